@@ -6,6 +6,8 @@ import (
 	"github.com/PabloPei/SmartSpend-backend/conf"
 	"github.com/PabloPei/SmartSpend-backend/db"
 	"github.com/PabloPei/SmartSpend-backend/internal/api"
+	"github.com/PabloPei/SmartSpend-backend/internal/currencies"
+	"github.com/PabloPei/SmartSpend-backend/internal/storage"
 )
 
 func main() {
@@ -21,11 +23,34 @@ func main() {
 
 	log.Println("Successfully connected to the database")
 
+	// File Storage //
+
+	log.Println("Opening", conf.ServerConfig.StorageBackend, "file storage...")
+
+	fileStorage, err := storage.New(conf.ServerConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Reference Data //
+
+	if conf.ServerConfig.ExchangeRatesFile != "" {
+		log.Println("Loading exchange rates from", conf.ServerConfig.ExchangeRatesFile)
+
+		rateService := currencies.NewService(currencies.NewSQLRepository(db))
+		loaded, err := rateService.LoadFile(conf.ServerConfig.ExchangeRatesFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("Loaded %d exchange rates", loaded)
+	}
+
 	// API Server //
 
 	log.Println("Starting Api Server...")
 
-	server := api.NewAPIServer(conf.ServerConfig, db)
+	server := api.NewAPIServer(conf.ServerConfig, db, fileStorage)
 	err = server.Run()
 
 	log.Fatal("Server Crash:", err)
@@ -1,6 +1,9 @@
 package conf
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"os"
 	"strconv"
 
@@ -28,6 +31,22 @@ type ApiServerConfig struct {
 	JWTExpirationInSeconds        int64
 	RefreshTokenSecret            string
 	RefreshTokenExpirationInHours int64
+	ExchangeRatesFile             string
+	RecurringIntervalInSeconds    int64
+	StatementFontFile             string
+	StorageBackend                string
+	StorageLocalDir               string
+	StoragePublicURL              string
+	StorageSigningSecret          string
+	StorageURLExpirationInSeconds int64
+	S3Endpoint                    string
+	S3PublicEndpoint              string
+	S3PublicUseSSL                bool
+	S3AccessKey                   string
+	S3SecretKey                   string
+	S3Bucket                      string
+	S3Region                      string
+	S3UseSSL                      bool
 }
 
 // Configs Functions //
@@ -47,13 +66,31 @@ func InitPostgresSqlConfig() PostgreSqlConfig {
 func InitApiServerConfig() ApiServerConfig {
 	godotenv.Load()
 
+	jwtSecret := getEnv("JWT_SECRET", "not-so-secret-now-is-it?")
+
 	return ApiServerConfig{
 		PublicHost:                    getEnv("PUBLIC_HOST", "0.0.0.0"),
 		Port:                          getEnv("PORT", "8080"),
-		JWTSecret:                     getEnv("JWT_SECRET", "not-so-secret-now-is-it?"),
+		JWTSecret:                     jwtSecret,
 		JWTExpirationInSeconds:        getEnvAsInt("JWT_EXPIRATION_IN_SECONDS", 3600*1),
 		RefreshTokenSecret:            getEnv("REFRESH_TOKEN_SECRET", "not-so-secret-now-is-it?"),
 		RefreshTokenExpirationInHours: getEnvAsInt("REFRESH_TOKEN_EXPIRATION_IN_HOURS", 30*24),
+		ExchangeRatesFile:             getEnv("EXCHANGE_RATES_FILE", ""),
+		RecurringIntervalInSeconds:    getEnvAsPositiveInt("RECURRING_INTERVAL_IN_SECONDS", 15*60),
+		StatementFontFile:             getEnv("STATEMENT_FONT_FILE", ""),
+		StorageBackend:                getEnv("STORAGE_BACKEND", "local"),
+		StorageLocalDir:               getEnv("STORAGE_LOCAL_DIR", "uploads"),
+		StoragePublicURL:              getEnv("STORAGE_PUBLIC_URL", "http://localhost:8080/api/v1/files"),
+		StorageSigningSecret:          getEnv("STORAGE_SIGNING_SECRET", deriveSecret(jwtSecret, "storage-signing")),
+		StorageURLExpirationInSeconds: getEnvAsInt("STORAGE_URL_EXPIRATION_IN_SECONDS", 15*60),
+		S3Endpoint:                    getEnv("S3_ENDPOINT", "localhost:9000"),
+		S3PublicEndpoint:              getEnv("S3_PUBLIC_ENDPOINT", getEnv("S3_ENDPOINT", "localhost:9000")),
+		S3PublicUseSSL:                getEnv("S3_PUBLIC_USE_SSL", getEnv("S3_USE_SSL", "false")) == "true",
+		S3AccessKey:                   getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:                   getEnv("S3_SECRET_KEY", ""),
+		S3Bucket:                      getEnv("S3_BUCKET", "smartspend"),
+		S3Region:                      getEnv("S3_REGION", ""),
+		S3UseSSL:                      getEnv("S3_USE_SSL", "false") == "true",
 	}
 }
 
@@ -65,6 +102,15 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+// deriveSecret gives a secret of its own for purpose, so a value that is not
+// set is never a public constant and does not reuse secret itself
+func deriveSecret(secret string, purpose string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 func getEnvAsInt(key string, fallback int64) int64 {
 	if value, ok := os.LookupEnv(key); ok {
 		i, err := strconv.ParseInt(value, 10, 64)
@@ -77,3 +123,13 @@ func getEnvAsInt(key string, fallback int64) int64 {
 
 	return fallback
 }
+
+// getEnvAsPositiveInt is getEnvAsInt for values that can not be zero or
+// negative, like the intervals of the background jobs
+func getEnvAsPositiveInt(key string, fallback int64) int64 {
+	if i := getEnvAsInt(key, fallback); i > 0 {
+		return i
+	}
+
+	return fallback
+}
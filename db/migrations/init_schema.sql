@@ -17,6 +17,87 @@ INSERT INTO conf.language (code, name) VALUES
     ('en', 'English'),
     ('es', 'Español'),
     ('zh', '中文 (Chinese)');
+
+CREATE TABLE conf.exchange_rate (
+    rate_date DATE NOT NULL,
+    base_currency CHAR(3) NOT NULL,
+    quote_currency CHAR(3) NOT NULL,
+    rate DECIMAL(20, 10) NOT NULL CHECK (rate > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (rate_date, base_currency, quote_currency)
+);
+
+-- Comments for conf.exchange_rate
+COMMENT ON TABLE conf.exchange_rate IS 'Table of daily exchange rates loaded from CSV or ECB XML files';
+COMMENT ON COLUMN conf.exchange_rate.rate_date IS 'Date in which the rate was published';
+COMMENT ON COLUMN conf.exchange_rate.base_currency IS 'ISO-4217 code of the base currency';
+COMMENT ON COLUMN conf.exchange_rate.quote_currency IS 'ISO-4217 code of the quote currency';
+COMMENT ON COLUMN conf.exchange_rate.rate IS 'Units of the quote currency for one unit of the base currency';
+
+CREATE INDEX idx_exchange_rate_pair ON conf.exchange_rate (base_currency, quote_currency, rate_date);
+
+CREATE TABLE conf.default_category (
+    code VARCHAR(50) PRIMARY KEY
+);
+
+-- Comments for conf.default_category
+COMMENT ON TABLE conf.default_category IS 'Table of categories created for every new group';
+COMMENT ON COLUMN conf.default_category.code IS 'Code of the default category (e.g., food, rent)';
+
+CREATE TABLE conf.default_category_name (
+    code VARCHAR(50),
+    language_code VARCHAR(10),
+    name VARCHAR(50) NOT NULL,
+    PRIMARY KEY (code, language_code),
+    CONSTRAINT fk_default_category_name_category FOREIGN KEY (code) REFERENCES conf.default_category(code) ON DELETE CASCADE,
+    CONSTRAINT fk_default_category_name_language FOREIGN KEY (language_code) REFERENCES conf.language(code)
+);
+
+-- Comments for conf.default_category_name
+COMMENT ON TABLE conf.default_category_name IS 'Table of translated names of the default categories';
+COMMENT ON COLUMN conf.default_category_name.code IS 'Code of the default category';
+COMMENT ON COLUMN conf.default_category_name.language_code IS 'Language of the name';
+COMMENT ON COLUMN conf.default_category_name.name IS 'Name of the category in the language';
+
+INSERT INTO conf.default_category (code) VALUES
+    ('food'),
+    ('transport'),
+    ('rent'),
+    ('utilities'),
+    ('entertainment'),
+    ('health'),
+    ('shopping'),
+    ('travel'),
+    ('other');
+
+INSERT INTO conf.default_category_name (code, language_code, name) VALUES
+    ('food', 'en', 'Food'),
+    ('food', 'es', 'Comida'),
+    ('food', 'zh', '餐饮'),
+    ('transport', 'en', 'Transport'),
+    ('transport', 'es', 'Transporte'),
+    ('transport', 'zh', '交通'),
+    ('rent', 'en', 'Rent'),
+    ('rent', 'es', 'Alquiler'),
+    ('rent', 'zh', '房租'),
+    ('utilities', 'en', 'Utilities'),
+    ('utilities', 'es', 'Servicios'),
+    ('utilities', 'zh', '水电费'),
+    ('entertainment', 'en', 'Entertainment'),
+    ('entertainment', 'es', 'Entretenimiento'),
+    ('entertainment', 'zh', '娱乐'),
+    ('health', 'en', 'Health'),
+    ('health', 'es', 'Salud'),
+    ('health', 'zh', '医疗'),
+    ('shopping', 'en', 'Shopping'),
+    ('shopping', 'es', 'Compras'),
+    ('shopping', 'zh', '购物'),
+    ('travel', 'en', 'Travel'),
+    ('travel', 'es', 'Viajes'),
+    ('travel', 'zh', '旅行'),
+    ('other', 'en', 'Other'),
+    ('other', 'es', 'Otros'),
+    ('other', 'zh', '其他');
     
 -- ===============================================
 -- Main Schema: core application data
@@ -27,7 +108,10 @@ CREATE TABLE public."group" (
     group_id UUID PRIMARY KEY DEFAULT gen_random_uuid() UNIQUE NOT NULL,
     group_name VARCHAR(50),
     description TEXT,
-    photo_url TEXT CHECK (photo_url ~* '^https?://.+') DEFAULT 'https://groupphoto.png',
+    photo_url TEXT CHECK (photo_url ~* '^https?://.+'),
+    photo_key TEXT,
+    simplify_debts BOOLEAN DEFAULT TRUE,
+    base_currency CHAR(3) NOT NULL DEFAULT 'USD',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ,
     created_by UUID,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -40,14 +124,64 @@ COMMENT ON COLUMN public."group".group_id IS 'Unique identifier for the group';
 COMMENT ON COLUMN public."group".group_name IS 'Name of the group';
 COMMENT ON COLUMN public."group".description IS 'Description of the group';
 COMMENT ON COLUMN public."group".photo_url IS 'URL of the representative photo for the group';
+COMMENT ON COLUMN public."group".photo_key IS 'Storage key of the uploaded or generated photo of the group, used instead of photo_url when set. Its thumbnails are stored next to it';
+COMMENT ON COLUMN public."group".simplify_debts IS 'Indicates if settle-up suggestions use the minimum number of transfers';
+COMMENT ON COLUMN public."group".base_currency IS 'ISO-4217 code of the currency in which balances are shown';
+
+-- Full-text search indexes, one per text search configuration used by the search
+CREATE INDEX idx_group_name_search_en ON public."group" USING GIN (to_tsvector('english', COALESCE(group_name, '')));
+CREATE INDEX idx_group_name_search_es ON public."group" USING GIN (to_tsvector('spanish', COALESCE(group_name, '')));
+
+CREATE TABLE public.category (
+    category_id UUID PRIMARY KEY DEFAULT gen_random_uuid() UNIQUE NOT NULL,
+    group_id UUID NOT NULL,
+    code VARCHAR(50),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by UUID,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_by UUID,
+    CONSTRAINT fk_category_group FOREIGN KEY (group_id) REFERENCES public."group"(group_id)
+);
+
+-- Comments for public.category
+COMMENT ON TABLE public.category IS 'Table of spending categories of a group';
+COMMENT ON COLUMN public.category.category_id IS 'Unique identifier for the category';
+COMMENT ON COLUMN public.category.group_id IS 'Identifier of the group associated with the category';
+COMMENT ON COLUMN public.category.code IS 'Code of the default category it was created from, NULL for categories added by the group';
+
+CREATE TABLE public.category_name (
+    category_id UUID,
+    language_code VARCHAR(10),
+    name VARCHAR(50) NOT NULL,
+    PRIMARY KEY (category_id, language_code),
+    CONSTRAINT fk_category_name_category FOREIGN KEY (category_id) REFERENCES public.category(category_id) ON DELETE CASCADE,
+    CONSTRAINT fk_category_name_language FOREIGN KEY (language_code) REFERENCES conf.language(code)
+);
+
+-- Comments for public.category_name
+COMMENT ON TABLE public.category_name IS 'Table of names of a category in each language';
+COMMENT ON COLUMN public.category_name.category_id IS 'Identifier of the category';
+COMMENT ON COLUMN public.category_name.language_code IS 'Language of the name';
+COMMENT ON COLUMN public.category_name.name IS 'Name of the category in the language';
 
 CREATE TABLE public.movement (
-    movement_id INT PRIMARY KEY,
-    group_id UUID,
-    amount DECIMAL(10, 2),
-    created_at TIMESTAMP,
-    created_by INT,
-    CONSTRAINT fk_movement_group FOREIGN KEY (group_id) REFERENCES public."group"(group_id)
+    movement_id UUID PRIMARY KEY DEFAULT gen_random_uuid() UNIQUE NOT NULL,
+    group_id UUID NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    description TEXT,
+    movement_date DATE DEFAULT CURRENT_DATE,
+    split_mode VARCHAR(20) DEFAULT 'equal' CHECK (split_mode IN ('equal', 'exact', 'percentage', 'shares', 'items')),
+    tax_amount DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (tax_amount >= 0),
+    tip_amount DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (tip_amount >= 0),
+    category_id UUID,
+    bank_transaction_id TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by UUID,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_by UUID,
+    CONSTRAINT fk_movement_group FOREIGN KEY (group_id) REFERENCES public."group"(group_id),
+    CONSTRAINT fk_movement_category FOREIGN KEY (category_id) REFERENCES public.category(category_id) ON DELETE SET NULL
 );
 
 -- Comments for public.movement
@@ -55,42 +189,307 @@ COMMENT ON TABLE public.movement IS 'Table of movements associated with a group'
 COMMENT ON COLUMN public.movement.movement_id IS 'Unique identifier for the movement';
 COMMENT ON COLUMN public.movement.group_id IS 'Identifier of the group to which the movement belongs';
 COMMENT ON COLUMN public.movement.amount IS 'Amount of the movement';
+COMMENT ON COLUMN public.movement.currency IS 'ISO-4217 code of the currency of the amount';
+COMMENT ON COLUMN public.movement.description IS 'Description of the movement';
+COMMENT ON COLUMN public.movement.movement_date IS 'Date in which the movement took place';
+COMMENT ON COLUMN public.movement.split_mode IS 'How the amount is split between participants (equal, exact, percentage, shares, items)';
+COMMENT ON COLUMN public.movement.tax_amount IS 'Tax included in the amount of an itemized movement, spread over the items';
+COMMENT ON COLUMN public.movement.tip_amount IS 'Tip included in the amount of an itemized movement, spread over the items';
+COMMENT ON COLUMN public.movement.category_id IS 'Identifier of the category of the movement';
+COMMENT ON COLUMN public.movement.bank_transaction_id IS 'Identifier of the bank statement transaction the movement was imported from';
+COMMENT ON COLUMN public.movement.created_by IS 'Identifier of the user who registered the movement';
+
+CREATE INDEX idx_movement_group ON public.movement (group_id, movement_date);
+CREATE UNIQUE INDEX uq_movement_bank_transaction ON public.movement (group_id, bank_transaction_id) WHERE bank_transaction_id IS NOT NULL;
+CREATE INDEX idx_movement_description_search_en ON public.movement USING GIN (to_tsvector('english', COALESCE(description, '')));
+CREATE INDEX idx_movement_description_search_es ON public.movement USING GIN (to_tsvector('spanish', COALESCE(description, '')));
+
+CREATE TABLE public.movement_participant (
+    movement_id UUID,
+    user_id UUID,
+    paid_amount DECIMAL(10, 2) DEFAULT 0 CHECK (paid_amount >= 0),
+    owed_amount DECIMAL(10, 2) DEFAULT 0 CHECK (owed_amount >= 0),
+    split_value DECIMAL(12, 4),
+    PRIMARY KEY (movement_id, user_id),
+    CONSTRAINT fk_movement_participant_movement FOREIGN KEY (movement_id) REFERENCES public.movement(movement_id) ON DELETE CASCADE
+);
+
+-- Comments for public.movement_participant
+COMMENT ON TABLE public.movement_participant IS 'Table of users who paid or share a movement';
+COMMENT ON COLUMN public.movement_participant.movement_id IS 'Identifier of the movement';
+COMMENT ON COLUMN public.movement_participant.user_id IS 'Identifier of the participant user';
+COMMENT ON COLUMN public.movement_participant.paid_amount IS 'Part of the movement amount paid by the user';
+COMMENT ON COLUMN public.movement_participant.owed_amount IS 'Part of the movement amount the user has to pay';
+COMMENT ON COLUMN public.movement_participant.split_value IS 'Exact amount, percentage or share given for the user, NULL on equal splits';
+
+CREATE INDEX idx_movement_participant_user ON public.movement_participant (user_id);
+
+CREATE TABLE public.movement_item (
+    movement_item_id UUID PRIMARY KEY DEFAULT gen_random_uuid() UNIQUE NOT NULL,
+    movement_id UUID NOT NULL,
+    position INT NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    extra_amount DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (extra_amount >= 0),
+    CONSTRAINT uq_movement_item_position UNIQUE (movement_id, position),
+    CONSTRAINT fk_movement_item_movement FOREIGN KEY (movement_id) REFERENCES public.movement(movement_id) ON DELETE CASCADE
+);
+
+-- Comments for public.movement_item
+COMMENT ON TABLE public.movement_item IS 'Table of the line items of an itemized movement';
+COMMENT ON COLUMN public.movement_item.movement_item_id IS 'Unique identifier for the item';
+COMMENT ON COLUMN public.movement_item.movement_id IS 'Identifier of the movement of the item';
+COMMENT ON COLUMN public.movement_item.position IS 'Order of the item in the receipt';
+COMMENT ON COLUMN public.movement_item.description IS 'Description of the item';
+COMMENT ON COLUMN public.movement_item.amount IS 'Price of the item before tax and tip';
+COMMENT ON COLUMN public.movement_item.extra_amount IS 'Part of the tax and tip of the movement that belongs to the item';
+
+CREATE TABLE public.movement_item_participant (
+    movement_item_id UUID,
+    user_id UUID,
+    owed_amount DECIMAL(10, 2) DEFAULT 0 CHECK (owed_amount >= 0),
+    PRIMARY KEY (movement_item_id, user_id),
+    CONSTRAINT fk_movement_item_participant_item FOREIGN KEY (movement_item_id) REFERENCES public.movement_item(movement_item_id) ON DELETE CASCADE
+);
+
+-- Comments for public.movement_item_participant
+COMMENT ON TABLE public.movement_item_participant IS 'Table of users who share an item of a movement';
+COMMENT ON COLUMN public.movement_item_participant.movement_item_id IS 'Identifier of the item';
+COMMENT ON COLUMN public.movement_item_participant.user_id IS 'Identifier of the participant user';
+COMMENT ON COLUMN public.movement_item_participant.owed_amount IS 'Part of the item, with its tax and tip, the user has to pay';
+
+CREATE TABLE public.movement_attachment (
+    attachment_id UUID PRIMARY KEY DEFAULT gen_random_uuid() UNIQUE NOT NULL,
+    movement_id UUID NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL CHECK (size > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by UUID,
+    CONSTRAINT fk_movement_attachment_movement FOREIGN KEY (movement_id) REFERENCES public.movement(movement_id) ON DELETE CASCADE
+);
+
+-- Comments for public.movement_attachment
+COMMENT ON TABLE public.movement_attachment IS 'Table of the files, such as receipts, uploaded for a movement';
+COMMENT ON COLUMN public.movement_attachment.attachment_id IS 'Unique identifier for the attachment';
+COMMENT ON COLUMN public.movement_attachment.movement_id IS 'Identifier of the movement of the attachment';
+COMMENT ON COLUMN public.movement_attachment.storage_key IS 'Key of the file in the storage';
+COMMENT ON COLUMN public.movement_attachment.file_name IS 'Name of the file given by the user';
+COMMENT ON COLUMN public.movement_attachment.content_type IS 'Media type detected from the content of the file';
+COMMENT ON COLUMN public.movement_attachment.size IS 'Size of the file in bytes';
+COMMENT ON COLUMN public.movement_attachment.created_by IS 'Identifier of the user who uploaded the file';
+
+CREATE INDEX idx_movement_attachment_movement ON public.movement_attachment (movement_id);
+
+CREATE TABLE public.movement_comment (
+    comment_id UUID PRIMARY KEY DEFAULT gen_random_uuid() UNIQUE NOT NULL,
+    movement_id UUID NOT NULL,
+    text VARCHAR(2000) NOT NULL CHECK (text <> ''),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by UUID,
+    CONSTRAINT fk_movement_comment_movement FOREIGN KEY (movement_id) REFERENCES public.movement(movement_id) ON DELETE CASCADE
+);
+
+-- Comments for public.movement_comment
+COMMENT ON TABLE public.movement_comment IS 'Table of the messages members write on a movement';
+COMMENT ON COLUMN public.movement_comment.comment_id IS 'Unique identifier for the comment';
+COMMENT ON COLUMN public.movement_comment.movement_id IS 'Identifier of the movement of the comment';
+COMMENT ON COLUMN public.movement_comment.text IS 'Text of the comment';
+COMMENT ON COLUMN public.movement_comment.created_by IS 'Identifier of the user who wrote the comment';
+
+CREATE INDEX idx_movement_comment_movement ON public.movement_comment (movement_id);
+CREATE INDEX idx_movement_comment_search_en ON public.movement_comment USING GIN (to_tsvector('english', text));
+CREATE INDEX idx_movement_comment_search_es ON public.movement_comment USING GIN (to_tsvector('spanish', text));
 
 CREATE TABLE public.movement_field (
-    movement_field_id INT PRIMARY KEY,
-    group_id UUID,
-    movement_id INT,
-    name VARCHAR(50),
-    type VARCHAR(50),
-    required BOOLEAN,
-    value VARCHAR(255),
-    CONSTRAINT fk_movement_field_group FOREIGN KEY (group_id) REFERENCES public."group"(group_id),
-    CONSTRAINT fk_movement_field_movement FOREIGN KEY (movement_id) REFERENCES public.movement(movement_id)
+    movement_field_id UUID PRIMARY KEY DEFAULT gen_random_uuid() UNIQUE NOT NULL,
+    group_id UUID NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    type VARCHAR(50) NOT NULL CHECK (type IN ('text', 'number', 'date', 'boolean', 'select')),
+    required BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by UUID,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_by UUID,
+    CONSTRAINT uq_movement_field_name UNIQUE (group_id, name),
+    CONSTRAINT fk_movement_field_group FOREIGN KEY (group_id) REFERENCES public."group"(group_id)
 );
 
 -- Comments for public.movement_field
-COMMENT ON TABLE public.movement_field IS 'Table of fields associated with a movement';
+COMMENT ON TABLE public.movement_field IS 'Table of custom fields defined by a group for its movements';
 COMMENT ON COLUMN public.movement_field.movement_field_id IS 'Unique identifier for the movement field';
 COMMENT ON COLUMN public.movement_field.group_id IS 'Identifier of the group associated with the field';
-COMMENT ON COLUMN public.movement_field.movement_id IS 'Identifier of the movement associated with the field';
 COMMENT ON COLUMN public.movement_field.name IS 'Name of the field';
-COMMENT ON COLUMN public.movement_field.type IS 'Data type or input type of the field';
+COMMENT ON COLUMN public.movement_field.type IS 'Data type of the field (text, number, date, boolean, select)';
 COMMENT ON COLUMN public.movement_field.required IS 'Indicates if the field is mandatory';
-COMMENT ON COLUMN public.movement_field.value IS 'Value assigned to the field';
 
 CREATE TABLE public.movement_field_options (
-    movement_field_options_id INT PRIMARY KEY,
-    movement_field_id INT,
-    value VARCHAR(255),
-    CONSTRAINT fk_movement_field_options FOREIGN KEY (movement_field_id) REFERENCES public.movement_field(movement_field_id)
+    movement_field_options_id UUID PRIMARY KEY DEFAULT gen_random_uuid() UNIQUE NOT NULL,
+    movement_field_id UUID NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    CONSTRAINT uq_movement_field_options_value UNIQUE (movement_field_id, value),
+    CONSTRAINT fk_movement_field_options FOREIGN KEY (movement_field_id) REFERENCES public.movement_field(movement_field_id) ON DELETE CASCADE
 );
 
 -- Comments for public.movement_field_options
-COMMENT ON TABLE public.movement_field_options IS 'Table of options available for a movement field';
+COMMENT ON TABLE public.movement_field_options IS 'Table of options available for a select movement field';
 COMMENT ON COLUMN public.movement_field_options.movement_field_options_id IS 'Unique identifier for the option';
 COMMENT ON COLUMN public.movement_field_options.movement_field_id IS 'Identifier of the associated movement field';
 COMMENT ON COLUMN public.movement_field_options.value IS 'Value of the option';
 
+CREATE TABLE public.movement_field_value (
+    movement_id UUID,
+    movement_field_id UUID,
+    value VARCHAR(255) NOT NULL,
+    PRIMARY KEY (movement_id, movement_field_id),
+    CONSTRAINT fk_movement_field_value_movement FOREIGN KEY (movement_id) REFERENCES public.movement(movement_id) ON DELETE CASCADE,
+    CONSTRAINT fk_movement_field_value_field FOREIGN KEY (movement_field_id) REFERENCES public.movement_field(movement_field_id) ON DELETE CASCADE
+);
+
+-- Comments for public.movement_field_value
+COMMENT ON TABLE public.movement_field_value IS 'Table of custom field values assigned to a movement';
+COMMENT ON COLUMN public.movement_field_value.movement_id IS 'Identifier of the movement';
+COMMENT ON COLUMN public.movement_field_value.movement_field_id IS 'Identifier of the movement field';
+COMMENT ON COLUMN public.movement_field_value.value IS 'Value assigned to the field';
+
+CREATE INDEX idx_movement_field_value_search_en ON public.movement_field_value USING GIN (to_tsvector('english', value));
+CREATE INDEX idx_movement_field_value_search_es ON public.movement_field_value USING GIN (to_tsvector('spanish', value));
+
+CREATE TABLE public.settlement (
+    settlement_id UUID PRIMARY KEY DEFAULT gen_random_uuid() UNIQUE NOT NULL,
+    group_id UUID NOT NULL,
+    from_user_id UUID NOT NULL,
+    to_user_id UUID NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    settlement_date DATE DEFAULT CURRENT_DATE,
+    note TEXT,
+    status VARCHAR(10) DEFAULT 'pending' CHECK (status IN ('pending', 'confirmed', 'disputed')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by UUID,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_by UUID,
+    CONSTRAINT chk_settlement_users CHECK (from_user_id <> to_user_id),
+    CONSTRAINT fk_settlement_group FOREIGN KEY (group_id) REFERENCES public."group"(group_id)
+);
+
+-- Comments for public.settlement
+COMMENT ON TABLE public.settlement IS 'Table of payments between members of a group to settle their debts';
+COMMENT ON COLUMN public.settlement.settlement_id IS 'Unique identifier for the settlement';
+COMMENT ON COLUMN public.settlement.group_id IS 'Identifier of the group to which the settlement belongs';
+COMMENT ON COLUMN public.settlement.from_user_id IS 'Identifier of the user who pays';
+COMMENT ON COLUMN public.settlement.to_user_id IS 'Identifier of the user who receives the payment';
+COMMENT ON COLUMN public.settlement.amount IS 'Amount paid, in the base currency of the group';
+COMMENT ON COLUMN public.settlement.settlement_date IS 'Date in which the payment took place';
+COMMENT ON COLUMN public.settlement.note IS 'Optional note of the payment';
+COMMENT ON COLUMN public.settlement.status IS 'pending until the receiver confirms or disputes it; disputed settlements do not count in the balances';
+COMMENT ON COLUMN public.settlement.created_by IS 'Identifier of the user who registered the settlement';
+
+CREATE INDEX idx_settlement_group ON public.settlement (group_id, settlement_date);
+CREATE INDEX idx_settlement_note_search_en ON public.settlement USING GIN (to_tsvector('english', COALESCE(note, '')));
+CREATE INDEX idx_settlement_note_search_es ON public.settlement USING GIN (to_tsvector('spanish', COALESCE(note, '')));
+
+CREATE TABLE public.budget (
+    budget_id UUID PRIMARY KEY DEFAULT gen_random_uuid() UNIQUE NOT NULL,
+    group_id UUID NOT NULL,
+    category_id UUID,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    period VARCHAR(10) NOT NULL CHECK (period IN ('monthly', 'custom')),
+    start_date DATE,
+    end_date DATE,
+    thresholds INTEGER[] NOT NULL DEFAULT '{80, 100}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by UUID,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_by UUID,
+    CONSTRAINT chk_budget_custom_period CHECK (period <> 'custom' OR (start_date IS NOT NULL AND end_date IS NOT NULL AND start_date <= end_date)),
+    CONSTRAINT fk_budget_group FOREIGN KEY (group_id) REFERENCES public."group"(group_id),
+    CONSTRAINT fk_budget_category FOREIGN KEY (category_id) REFERENCES public.category(category_id) ON DELETE CASCADE
+);
+
+-- Comments for public.budget
+COMMENT ON TABLE public.budget IS 'Table of spending budgets of a group or of one of its categories';
+COMMENT ON COLUMN public.budget.budget_id IS 'Unique identifier for the budget';
+COMMENT ON COLUMN public.budget.group_id IS 'Identifier of the group associated with the budget';
+COMMENT ON COLUMN public.budget.category_id IS 'Identifier of the category the budget applies to, NULL for the whole group';
+COMMENT ON COLUMN public.budget.amount IS 'Amount available in each period, in the base currency of the group';
+COMMENT ON COLUMN public.budget.period IS 'monthly budgets restart every calendar month, custom budgets cover start_date to end_date';
+COMMENT ON COLUMN public.budget.thresholds IS 'Percentages of the amount that produce an alert when spending reaches them';
+
+CREATE TABLE public.budget_alert (
+    budget_alert_id UUID PRIMARY KEY DEFAULT gen_random_uuid() UNIQUE NOT NULL,
+    budget_id UUID NOT NULL,
+    threshold INTEGER NOT NULL,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL,
+    spent DECIMAL(12, 2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP DEFAULT NULL,
+    CONSTRAINT uq_budget_alert UNIQUE (budget_id, threshold, period_start),
+    CONSTRAINT fk_budget_alert_budget FOREIGN KEY (budget_id) REFERENCES public.budget(budget_id) ON DELETE CASCADE
+);
+
+-- Comments for public.budget_alert
+COMMENT ON TABLE public.budget_alert IS 'Table of budget threshold crossings waiting to be delivered by the notification layer';
+COMMENT ON COLUMN public.budget_alert.budget_alert_id IS 'Unique identifier for the alert';
+COMMENT ON COLUMN public.budget_alert.budget_id IS 'Identifier of the budget';
+COMMENT ON COLUMN public.budget_alert.threshold IS 'Percentage of the budget that was reached';
+COMMENT ON COLUMN public.budget_alert.period_start IS 'First day of the budget period in which the threshold was reached';
+COMMENT ON COLUMN public.budget_alert.spent IS 'Amount spent when the threshold was reached';
+COMMENT ON COLUMN public.budget_alert.delivered_at IS 'When the notification layer delivered the alert, NULL while pending';
+
+CREATE TABLE public.recurring_movement (
+    recurring_movement_id UUID PRIMARY KEY DEFAULT gen_random_uuid() UNIQUE NOT NULL,
+    group_id UUID NOT NULL,
+    schedule TEXT NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    max_count INTEGER CHECK (max_count > 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    resumed_at DATE NOT NULL,
+    template JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by UUID,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_by UUID,
+    CONSTRAINT chk_recurring_movement_dates CHECK (end_date IS NULL OR start_date <= end_date),
+    CONSTRAINT fk_recurring_movement_group FOREIGN KEY (group_id) REFERENCES public."group"(group_id)
+);
+
+-- Comments for public.recurring_movement
+COMMENT ON TABLE public.recurring_movement IS 'Table of movement templates that are created on every date of a schedule';
+COMMENT ON COLUMN public.recurring_movement.recurring_movement_id IS 'Unique identifier for the recurring movement';
+COMMENT ON COLUMN public.recurring_movement.group_id IS 'Identifier of the group associated with the recurring movement';
+COMMENT ON COLUMN public.recurring_movement.schedule IS 'Cron expression with 5 fields or RRULE, evaluated on dates only';
+COMMENT ON COLUMN public.recurring_movement.end_date IS 'Last date on which a movement can be created, NULL for no end';
+COMMENT ON COLUMN public.recurring_movement.max_count IS 'Maximum number of occurrences counted from start_date, NULL for no limit';
+COMMENT ON COLUMN public.recurring_movement.active IS 'Inactive templates are not processed by the scheduler';
+COMMENT ON COLUMN public.recurring_movement.resumed_at IS 'First date the scheduler creates movements for, moved to the current date when the template is reactivated or its schedule changes';
+COMMENT ON COLUMN public.recurring_movement.template IS 'Movement payload used to create every occurrence';
+
+CREATE TABLE public.recurring_occurrence (
+    recurring_movement_id UUID NOT NULL,
+    occurrence_date DATE NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'processing', 'created', 'skipped', 'failed')),
+    movement_id UUID,
+    override JSONB,
+    error TEXT,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_by UUID,
+    PRIMARY KEY (recurring_movement_id, occurrence_date),
+    CONSTRAINT fk_recurring_occurrence_recurring FOREIGN KEY (recurring_movement_id) REFERENCES public.recurring_movement(recurring_movement_id) ON DELETE CASCADE,
+    CONSTRAINT fk_recurring_occurrence_movement FOREIGN KEY (movement_id) REFERENCES public.movement(movement_id) ON DELETE SET NULL
+);
+
+-- Comments for public.recurring_occurrence
+COMMENT ON TABLE public.recurring_occurrence IS 'Table of occurrences of a recurring movement that were processed, skipped or edited';
+COMMENT ON COLUMN public.recurring_occurrence.occurrence_date IS 'Date of the schedule the occurrence belongs to';
+COMMENT ON COLUMN public.recurring_occurrence.status IS 'scheduled occurrences were edited and wait for their date, processing ones are being created by the scheduler';
+COMMENT ON COLUMN public.recurring_occurrence.movement_id IS 'Identifier of the movement created for the occurrence';
+COMMENT ON COLUMN public.recurring_occurrence.override IS 'Movement payload that replaces the template for this occurrence only';
+COMMENT ON COLUMN public.recurring_occurrence.error IS 'Reason why the movement could not be created';
+
 -- ===============================================
 -- Authorization Schema: users, roles
 -- ===============================================
@@ -101,7 +500,8 @@ CREATE TABLE auth."user" (
     user_name VARCHAR(50) NOT NULL,
     email VARCHAR(255) UNIQUE NOT NULL,
     password TEXT NOT NULL,
-    photo_url TEXT CHECK (photo_url ~* '^https?://.+') DEFAULT 'https://userphoto.png',
+    photo_url TEXT CHECK (photo_url ~* '^https?://.+'),
+    photo_key TEXT,
     language_code VARCHAR(10) DEFAULT 'es',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -113,6 +513,7 @@ COMMENT ON TABLE auth."user" IS 'Table of application users';
 COMMENT ON COLUMN auth."user".user_id IS 'Unique identifier for the user';
 COMMENT ON COLUMN auth."user".user_name IS 'Name of the user';
 COMMENT ON COLUMN auth."user".photo_url IS 'URL of the user''s photo';
+COMMENT ON COLUMN auth."user".photo_key IS 'Storage key of the uploaded or generated photo of the user, used instead of photo_url when set. Its thumbnails are stored next to it';
 COMMENT ON COLUMN auth."user".language_code IS 'Identifier of the user''s preferred language';
 
 CREATE TABLE auth.role (
@@ -136,19 +537,58 @@ VALUES
     ('A', 'ADMIN', 'User with full administrative privileges');
 
 
-CREATE TABLE auth.user_role (
-    user_id UUID,
+CREATE TABLE auth.permission (
+    permission_id INT PRIMARY KEY,
+    permission_name VARCHAR(50) UNIQUE NOT NULL,
+    description TEXT
+);
+
+-- Comments for auth.permission
+COMMENT ON TABLE auth.permission IS 'Table of permissions that can be granted to roles';
+COMMENT ON COLUMN auth.permission.permission_id IS 'Unique identifier for the permission';
+COMMENT ON COLUMN auth.permission.permission_name IS 'Name used by the application to check the permission';
+COMMENT ON COLUMN auth.permission.description IS 'Description of the permission';
+
+INSERT INTO auth.permission (permission_id, permission_name, description)
+VALUES
+    (1, 'VIEW_GROUP', 'View the group, its members, fields and movements'),
+    (2, 'EDIT_MOVEMENTS', 'Create, edit and delete movements of the group'),
+    (3, 'MANAGE_FIELDS', 'Define the custom movement fields of the group'),
+    (4, 'MANAGE_MEMBERS', 'Add and remove members and change their roles'),
+    (5, 'EDIT_GROUP', 'Edit the group profile and settings');
+
+CREATE TABLE auth.role_permission (
     role_id VARCHAR(1),
-    group_id UUID,
+    permission_id INT,
+    PRIMARY KEY (role_id, permission_id),
+    CONSTRAINT fk_role_permission_role FOREIGN KEY (role_id) REFERENCES auth.role(role_id),
+    CONSTRAINT fk_role_permission_permission FOREIGN KEY (permission_id) REFERENCES auth.permission(permission_id)
+);
+
+-- Comments for auth.role_permission
+COMMENT ON TABLE auth.role_permission IS 'Table of permissions granted to each role';
+COMMENT ON COLUMN auth.role_permission.role_id IS 'Identifier of the role';
+COMMENT ON COLUMN auth.role_permission.permission_id IS 'Identifier of the granted permission';
+
+INSERT INTO auth.role_permission (role_id, permission_id)
+VALUES
+    ('V', 1),
+    ('E', 1), ('E', 2),
+    ('A', 1), ('A', 2), ('A', 3), ('A', 4), ('A', 5);
+
+CREATE TABLE auth.user_role (
+    user_id UUID NOT NULL,
+    role_id VARCHAR(1) NOT NULL,
+    group_id UUID NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     created_by UUID,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_by UUID,
-    valid_until TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    PRIMARY KEY (user_id, role_id),
+    valid_until TIMESTAMP DEFAULT NULL,
+    PRIMARY KEY (user_id, group_id),
     CONSTRAINT fk_user_role_user FOREIGN KEY (user_id) REFERENCES auth."user"(user_id),
     CONSTRAINT fk_user_role_role FOREIGN KEY (role_id) REFERENCES auth.role(role_id),
-    CONSTRAINT fk_user_role_group FOREIGN KEY (group_id) REFERENCES public."group"(group_id)
+    CONSTRAINT fk_user_role_group FOREIGN KEY (group_id) REFERENCES public."group"(group_id) ON DELETE CASCADE
 );
 
 -- Comments for auth.user_role
@@ -156,4 +596,4 @@ COMMENT ON TABLE auth.user_role IS 'Table for assigning roles to users for speci
 COMMENT ON COLUMN auth.user_role.user_id IS 'Identifier of the user';
 COMMENT ON COLUMN auth.user_role.role_id IS 'Identifier of the assigned role';
 COMMENT ON COLUMN auth.user_role.group_id IS 'Identifier of the group in which the role is assigned';
-COMMENT ON COLUMN auth.user_role.valid_until IS 'Date until the assignment is valid';
\ No newline at end of file
+COMMENT ON COLUMN auth.user_role.valid_until IS 'Date until the assignment is valid, NULL for permanent access';
\ No newline at end of file
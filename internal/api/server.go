@@ -5,26 +5,48 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/PabloPei/SmartSpend-backend/conf"
+	"github.com/PabloPei/SmartSpend-backend/internal/auth"
+	"github.com/PabloPei/SmartSpend-backend/internal/backups"
+	"github.com/PabloPei/SmartSpend-backend/internal/balances"
+	"github.com/PabloPei/SmartSpend-backend/internal/budgets"
+	"github.com/PabloPei/SmartSpend-backend/internal/categories"
+	"github.com/PabloPei/SmartSpend-backend/internal/currencies"
+	"github.com/PabloPei/SmartSpend-backend/internal/exports"
+	"github.com/PabloPei/SmartSpend-backend/internal/fields"
 	"github.com/PabloPei/SmartSpend-backend/internal/groups"
 	"github.com/PabloPei/SmartSpend-backend/internal/middlewares"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/movements"
+	"github.com/PabloPei/SmartSpend-backend/internal/recurring"
+	"github.com/PabloPei/SmartSpend-backend/internal/reports"
+	"github.com/PabloPei/SmartSpend-backend/internal/search"
+	"github.com/PabloPei/SmartSpend-backend/internal/settlements"
+	"github.com/PabloPei/SmartSpend-backend/internal/storage"
 	"github.com/PabloPei/SmartSpend-backend/internal/users"
 	"github.com/gorilla/mux"
 )
 
 type APIServer struct {
-	addr string
-	db   *sql.DB
+	addr              string
+	db                *sql.DB
+	storage           models.Storage
+	recurringInterval time.Duration
 }
 
-func NewAPIServer(cfg conf.ApiServerConfig, db *sql.DB) *APIServer {
+func NewAPIServer(cfg conf.ApiServerConfig, db *sql.DB, storage models.Storage) *APIServer {
 	return &APIServer{
-		addr: fmt.Sprintf("%s:%s", cfg.PublicHost, cfg.Port),
-		db:   db,
+		addr:              fmt.Sprintf("%s:%s", cfg.PublicHost, cfg.Port),
+		db:                db,
+		storage:           storage,
+		recurringInterval: time.Duration(cfg.RecurringIntervalInSeconds) * time.Second,
 	}
 }
 
+// Run also starts the recurring movements scheduler, so it uses the same
+// services as the API
 func (s *APIServer) Run() error {
 
 	router := mux.NewRouter()
@@ -35,18 +57,89 @@ func (s *APIServer) Run() error {
 
 	subrouter := router.PathPrefix("/api/v1").Subrouter()
 
+	authRepository := auth.NewSQLRepository(s.db)
+	rateService := currencies.NewService(currencies.NewSQLRepository(s.db))
+
+	// file routes, only used by the local storage
+	fileHandler := storage.NewHandler(s.storage)
+	fileHandler.RegisterRoutes(subrouter)
+
 	// user routes
 	userRepository := users.NewSQLRepository(s.db)
-	userService := users.NewService(userRepository)
+	userService := users.NewService(userRepository, s.storage)
 	userHandler := users.NewHandler(userService)
 	userHandler.RegisterRoutes(subrouter)
 
 	// group routes
+	categoryRepository := categories.NewSQLRepository(s.db)
 	groupRepository := groups.NewSQLRepository(s.db)
-	groupService := groups.NewService(groupRepository)
-	groupHandler := groups.NewHandler(groupService)
+	groupService := groups.NewService(groupRepository, authRepository, userRepository, s.storage)
+	groupHandler := groups.NewHandler(groupService, authRepository)
 	groupHandler.RegisterRoutes(subrouter)
 
+	// movement field routes
+	fieldRepository := fields.NewSQLRepository(s.db)
+	fieldService := fields.NewService(fieldRepository)
+	fieldHandler := fields.NewHandler(fieldService, authRepository)
+	fieldHandler.RegisterRoutes(subrouter)
+
+	// category routes
+	categoryService := categories.NewService(categoryRepository, userRepository)
+	categoryHandler := categories.NewHandler(categoryService, authRepository)
+	categoryHandler.RegisterRoutes(subrouter)
+
+	// budget routes
+	budgetRepository := budgets.NewSQLRepository(s.db)
+	budgetService := budgets.NewService(budgetRepository, categoryRepository, rateService)
+	budgetHandler := budgets.NewHandler(budgetService, authRepository)
+	budgetHandler.RegisterRoutes(subrouter)
+
+	// movement routes
+	movementRepository := movements.NewSQLRepository(s.db)
+	movementService := movements.NewService(movementRepository, fieldRepository, rateService, categoryRepository, budgetService, s.storage)
+	movementHandler := movements.NewHandler(movementService, authRepository)
+	movementHandler.RegisterRoutes(subrouter)
+
+	// recurring movement routes
+	recurringService := recurring.NewService(recurring.NewSQLRepository(s.db), movementService)
+	recurringHandler := recurring.NewHandler(recurringService, authRepository)
+	recurringHandler.RegisterRoutes(subrouter)
+
+	log.Println("Starting recurring movements scheduler...")
+	go recurring.RunScheduler(recurringService, s.recurringInterval)
+
+	// settlement routes
+	settlementRepository := settlements.NewSQLRepository(s.db)
+	settlementService := settlements.NewService(settlementRepository, authRepository)
+	settlementHandler := settlements.NewHandler(settlementService, authRepository)
+	settlementHandler.RegisterRoutes(subrouter)
+
+	// balance routes
+	balanceRepository := balances.NewSQLRepository(s.db)
+	balanceService := balances.NewService(balanceRepository, rateService)
+	balanceHandler := balances.NewHandler(balanceService, authRepository)
+	balanceHandler.RegisterRoutes(subrouter)
+
+	// export routes
+	exportService := exports.NewService(exports.NewSQLRepository(s.db), categoryRepository, fieldRepository, userRepository)
+	exportHandler := exports.NewHandler(exportService, authRepository)
+	exportHandler.RegisterRoutes(subrouter)
+
+	// backup routes
+	backupService := backups.NewService(backups.NewSQLRepository(s.db), userRepository, groupRepository, categoryRepository, s.storage)
+	backupHandler := backups.NewHandler(backupService, authRepository)
+	backupHandler.RegisterRoutes(subrouter)
+
+	// search routes
+	searchService := search.NewService(search.NewSQLRepository(s.db), userRepository)
+	searchHandler := search.NewHandler(searchService)
+	searchHandler.RegisterRoutes(subrouter)
+
+	// report routes
+	reportService := reports.NewService(reports.NewSQLRepository(s.db), groupRepository, userRepository, movementRepository, categoryRepository, balanceService, s.storage)
+	reportHandler := reports.NewHandler(reportService, authRepository)
+	reportHandler.RegisterRoutes(subrouter)
+
 	log.Println("Server running on", s.addr)
 	return http.ListenAndServe(s.addr, router)
 
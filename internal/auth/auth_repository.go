@@ -1,9 +1,12 @@
 package auth
 
-//TODO agregar interfaces de auth y middleware de permisos
-
 import (
 	"database/sql"
+	"fmt"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/pagination"
 )
 
 // Postgres SQL Repository
@@ -15,33 +18,231 @@ func NewSQLRepository(db *sql.DB) *SQLRepository {
 	return &SQLRepository{db: db}
 }
 
-/* Estafuncion va a ser la que cree una entrada en user role, donde se asigna un rol al usuario sobre un grupo
-func (s *SQLRepository) CreateRoleAssigment(user models.User) error {
+// Crea una entrada en user role, donde se asigna un rol al usuario sobre un grupo
+func (s *SQLRepository) CreateRoleAssigment(assigment models.RoleAssigment) error {
+
 	_, err := s.db.Exec(
-		"INSERT INTO auth.\"user\" (user_name, email, password) VALUES ($1, $2, $3)",
-		user.UserName, user.Email, user.Password,
+		"INSERT INTO auth.user_role (user_id, role_id, group_id, created_by, updated_by, valid_until) VALUES ($1, $2, $3, $4, $5, $6)",
+		assigment.UserId, assigment.RoleId, assigment.GroupId, assigment.CreatedBy, assigment.UpdatedBy, assigment.ValidUntil,
 	)
 	if err != nil {
-		return fmt.Errorf("error al crear usuario: %w", err)
+		return fmt.Errorf("error al asignar el rol: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLRepository) GetRoleAssigment(userId []uint8, groupId []uint8) (*models.RoleAssigment, error) {
+
+	assigment := new(models.RoleAssigment)
+	var validUntil sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT user_id, role_id, group_id, created_at, created_by, updated_at, updated_by, valid_until
+		FROM auth.user_role
+		WHERE user_id = $1 AND group_id = $2`, userId, groupId,
+	).Scan(
+		&assigment.UserId,
+		&assigment.RoleId,
+		&assigment.GroupId,
+		&assigment.CreatedAt,
+		&assigment.CreatedBy,
+		&assigment.UpdatedAt,
+		&assigment.UpdatedBy,
+		&validUntil,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrMemberNotFound
+		}
+		return nil, errors.ErrMemberScan(err.Error())
+	}
+
+	if validUntil.Valid {
+		assigment.ValidUntil = &validUntil.Time
+	}
+
+	return assigment, nil
+}
+
+// Sortable fields of the members of a group
+var memberSorts = pagination.Fields{
+	"memberSince": {{Expr: "ur.created_at", Type: "timestamp"}, {Expr: "u.user_id", Type: "uuid"}},
+	"name":        {{Expr: "u.user_name", Type: "text"}, {Expr: "u.user_id", Type: "uuid"}},
+	"email":       {{Expr: "u.email", Type: "text"}, {Expr: "u.user_id", Type: "uuid"}},
+}
+
+func (s *SQLRepository) GetGroupMembers(groupId []uint8, page models.PageRequest) (*models.Page[*models.GroupMember], error) {
+
+	query, err := pagination.Build(memberSorts, page, "memberSince", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	condition := ""
+	if query.Condition != "" {
+		condition = "AND " + query.Condition
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT u.user_id, u.user_name, u.email, COALESCE(u.photo_url, ''), COALESCE(u.photo_key, ''), r.role_id, r.role_name, ur.created_at, ur.valid_until,
+			(ur.valid_until IS NOT NULL AND ur.valid_until <= CURRENT_TIMESTAMP) AS expired, %s
+		FROM auth.user_role ur
+		INNER JOIN auth."user" u ON u.user_id = ur.user_id
+		INNER JOIN auth.role r ON r.role_id = ur.role_id
+		WHERE ur.group_id = $1
+		%s
+		ORDER BY %s
+		LIMIT %d`, query.Keys, condition, query.Order, query.Limit+1), append([]any{groupId}, query.Args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los miembros del grupo: %w", err)
 	}
+	defer rows.Close()
+
+	var members []*models.GroupMember
+	var keys [][]string
+
+	scanner := query.Scanner(rows)
+	for rows.Next() {
+		member := new(models.GroupMember)
+		var validUntil sql.NullTime
+		err := scanner.Scan(
+			&member.UserId,
+			&member.UserName,
+			&member.Email,
+			&member.PhotoUrl,
+			&member.PhotoKey,
+			&member.RoleId,
+			&member.RoleName,
+			&member.MemberSince,
+			&validUntil,
+			&member.Expired,
+		)
+		if err != nil {
+			return nil, errors.ErrMemberScan(err.Error())
+		}
+		if validUntil.Valid {
+			member.ValidUntil = &validUntil.Time
+		}
+		members = append(members, member)
+		keys = append(keys, scanner.Values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return pagination.Page(query, members, keys), nil
+}
+
+func (s *SQLRepository) UpdateRoleAssigment(assigment models.RoleAssigment) error {
+
+	return s.keepingAdmin(assigment.GroupId, "error al actualizar el rol",
+		`UPDATE auth.user_role
+		SET role_id = $1, updated_by = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $3 AND group_id = $4`,
+		assigment.RoleId, assigment.UpdatedBy, assigment.UserId, assigment.GroupId,
+	)
+}
+
+func (s *SQLRepository) DeleteRoleAssigment(userId []uint8, groupId []uint8) error {
 
+	return s.keepingAdmin(groupId, "error al eliminar el rol",
+		"DELETE FROM auth.user_role WHERE user_id = $1 AND group_id = $2", userId, groupId,
+	)
+}
+
+func (s *SQLRepository) UpdateRoleValidity(assigment models.RoleAssigment) error {
+
+	return s.keepingAdmin(assigment.GroupId, "error al actualizar el vencimiento del rol",
+		`UPDATE auth.user_role
+		SET valid_until = $1, updated_by = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $3 AND group_id = $4`,
+		assigment.ValidUntil, assigment.UpdatedBy, assigment.UserId, assigment.GroupId,
+	)
+}
+
+// Ejecuta el cambio de una asignacion del grupo en una transaccion y la
+// deshace si deja al grupo sin admins permanentes. Los admins permanentes se
+// bloquean antes del cambio, asi dos cambios simultaneos no pueden quitar
+// cada uno a un admin distinto creyendo que queda el otro
+func (s *SQLRepository) keepingAdmin(groupId []uint8, message string, query string, args ...any) error {
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := countPermanentAdmins(tx, groupId, " FOR UPDATE")
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+	if err := checkAffected(res); err != nil {
+		return err
+	}
+
+	after, err := countPermanentAdmins(tx, groupId, "")
+	if err != nil {
+		return err
+	}
+	if before > 0 && after == 0 {
+		return errors.ErrLastGroupAdmin
+	}
+
+	return tx.Commit()
+}
+
+// Cuenta los admins permanentes (sin fecha de vencimiento) del grupo. lock
+// agrega el bloqueo de las filas
+func countPermanentAdmins(tx *sql.Tx, groupId []uint8, lock string) (int, error) {
+
+	rows, err := tx.Query(
+		"SELECT user_id FROM auth.user_role WHERE group_id = $1 AND role_id = $2 AND valid_until IS NULL"+lock,
+		groupId, models.RoleAdmin,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error al contar los admins del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		count++
+	}
+
+	return count, rows.Err()
+}
+
+func checkAffected(res sql.Result) error {
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrMemberNotFound
+	}
 	return nil
 }
-*/
 
-/* funcion que va a verificar los permisos de un usuuario sobre un grupo
-func (s *SQLRepository) userHasPermission(userID []uint8, groupID []uint8, permission string) (bool, error) {
+// Verifica los permisos de un usuario sobre un grupo segun el rol asignado
+func (s *SQLRepository) UserHasPermission(userId []uint8, groupId []uint8, permission string) (bool, error) {
 
 	query := `
 	SELECT COUNT(*)
-	FROM user u
-	JOIN user_role ur ON u.user_id = ur.user_id
-	JOIN role_permission rp ON ur.role_id = rp.role_id
-	JOIN permission p ON rp.permission_id = p.permission_id
-	WHERE u.user_id = $1 AND ur.group_id = $2 AND p.description = $3;`
+	FROM auth.user_role ur
+	JOIN auth.role_permission rp ON ur.role_id = rp.role_id
+	JOIN auth.permission p ON rp.permission_id = p.permission_id
+	WHERE ur.user_id = $1 AND ur.group_id = $2 AND p.permission_name = $3
+	AND (ur.valid_until IS NULL OR ur.valid_until > CURRENT_TIMESTAMP);`
 
 	var count int
-	err := s.db.QueryRow(query, userID, groupID, permission).Scan(&count)
+	err := s.db.QueryRow(query, userId, groupId, permission).Scan(&count)
 
 	if err != nil {
 		return false, err
@@ -49,4 +250,3 @@ func (s *SQLRepository) userHasPermission(userID []uint8, groupID []uint8, permi
 
 	return count > 0, nil
 }
-*/
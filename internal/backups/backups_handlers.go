@@ -0,0 +1,87 @@
+package backups
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/auth"
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/middlewares"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+// Archives are read whole, so their size is bounded
+const maxBackupSize = 50 << 20
+
+type Handler struct {
+	service        models.BackupService
+	authRepository models.AuthRepository
+}
+
+func NewHandler(service models.BackupService, authRepository models.AuthRepository) *Handler {
+	return &Handler{service: service, authRepository: authRepository}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+
+	// User routes
+	router.HandleFunc("/group/restore", middlewares.WithJWTAuth(h.handleRestore)).Methods("POST")
+
+	// Admin routes
+	router.HandleFunc("/group/{groupId}/backup", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleBackup, models.PermissionEditGroup, h.authRepository))).Methods("GET")
+}
+
+func (h *Handler) handleBackup(w http.ResponseWriter, r *http.Request) {
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	backup, err := h.service.BackupGroup(groupId)
+	if err == errors.ErrGroupNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	filename := fmt.Sprintf("group-%s-%s.json", groupId, backup.CreatedAt.Format(time.DateOnly))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	utils.WriteJSON(w, http.StatusOK, backup)
+}
+
+// handleRestore restores the archive in the body as a new group of which the
+// user is admin. With ?dryRun=true it only reports the conflicts.
+func (h *Handler) handleRestore(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBackupSize)
+
+	var backup models.GroupBackup
+	if err := utils.ParseJSON(r, &backup); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(err.Error()))
+		return
+	}
+
+	result, err := h.service.RestoreGroup(&backup, r.URL.Query().Get("dryRun") == "true", userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if len(result.Conflicts) > 0 {
+		status = http.StatusConflict
+	} else if result.DryRun {
+		status = http.StatusOK
+	}
+
+	utils.WriteJSON(w, status, result)
+}
@@ -0,0 +1,504 @@
+package backups
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// Postgres SQL Repository
+type SQLRepository struct {
+	db *sql.DB
+}
+
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+// Lee el grupo completo en una transaccion de solo lectura, para que el
+// archivo sea consistente aunque el grupo cambie mientras se lee
+func (s *SQLRepository) GetGroupBackup(groupId []uint8) (*models.GroupBackup, error) {
+
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	backup := &models.GroupBackup{Version: models.BackupVersion}
+
+	err = tx.QueryRow(`
+		SELECT group_id, COALESCE(group_name, ''), COALESCE(description, ''), COALESCE(photo_url, ''), COALESCE(photo_key, ''), COALESCE(simplify_debts, TRUE), base_currency, created_at
+		FROM public."group"
+		WHERE group_id = $1`, groupId,
+	).Scan(
+		&backup.Group.Id,
+		&backup.Group.Name,
+		&backup.Group.Description,
+		&backup.Group.PhotoUrl,
+		&backup.Group.PhotoKey,
+		&backup.Group.SimplifyDebts,
+		&backup.Group.BaseCurrency,
+		&backup.Group.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrGroupNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("error al leer el grupo: %w", err)
+	}
+
+	if backup.Users, err = getBackupUsers(tx, groupId); err != nil {
+		return nil, err
+	}
+	if backup.Members, err = getBackupMembers(tx, groupId); err != nil {
+		return nil, err
+	}
+	if backup.Categories, err = getBackupCategories(tx, groupId); err != nil {
+		return nil, err
+	}
+	if backup.Fields, err = getBackupFields(tx, groupId); err != nil {
+		return nil, err
+	}
+	if backup.Movements, err = getBackupMovements(tx, groupId); err != nil {
+		return nil, err
+	}
+	if backup.Settlements, err = getBackupSettlements(tx, groupId); err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow("SELECT CURRENT_TIMESTAMP").Scan(&backup.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return backup, tx.Commit()
+}
+
+// Crea un grupo nuevo con el contenido del archivo en una sola transaccion.
+// Todas las entidades reciben ids nuevos; los usuarios ya vienen resueltos.
+func (s *SQLRepository) RestoreGroup(backup *models.GroupBackup, users map[string][]uint8, userId []uint8) ([]uint8, error) {
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// created_by of users outside the archive falls back to the restoring user
+	author := func(id string) []uint8 {
+		if user, ok := users[id]; ok {
+			return user
+		}
+		return userId
+	}
+
+	var groupId []uint8
+	err = tx.QueryRow(`
+		INSERT INTO public."group" (group_name, description, simplify_debts, base_currency, created_at, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		RETURNING group_id`,
+		backup.Group.Name, backup.Group.Description, backup.Group.SimplifyDebts, backup.Group.BaseCurrency, backup.Group.CreatedAt, userId,
+	).Scan(&groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al crear el grupo: %w", err)
+	}
+
+	if backup.Group.PhotoUrl != "" {
+		_, err = tx.Exec(`UPDATE public."group" SET photo_url = $1 WHERE group_id = $2`, backup.Group.PhotoUrl, groupId)
+		if err != nil {
+			return nil, fmt.Errorf("error al restaurar la foto del grupo: %w", err)
+		}
+	}
+
+	for _, member := range backup.Members {
+		_, err = tx.Exec(`
+			INSERT INTO auth.user_role (user_id, role_id, group_id, created_at, created_by, updated_by, valid_until)
+			VALUES ($1, $2, $3, $4, $5, $5, $6)`,
+			users[member.UserId], member.RoleId, groupId, member.MemberSince, userId, member.ValidUntil,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error al restaurar los miembros: %w", err)
+		}
+	}
+
+	// Whoever restores the group is always a permanent admin of it
+	_, err = tx.Exec(`
+		INSERT INTO auth.user_role (user_id, role_id, group_id, created_by, updated_by)
+		VALUES ($1, $2, $3, $1, $1)
+		ON CONFLICT (user_id, group_id) DO UPDATE
+		SET role_id = EXCLUDED.role_id, valid_until = NULL, updated_at = CURRENT_TIMESTAMP, updated_by = EXCLUDED.updated_by`,
+		userId, models.RoleAdmin, groupId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error al asignar el admin del grupo: %w", err)
+	}
+
+	categoryIds := make(map[string][]uint8, len(backup.Categories))
+	for _, category := range backup.Categories {
+		var categoryId []uint8
+		err = tx.QueryRow(
+			"INSERT INTO public.category (group_id, code, created_by, updated_by) VALUES ($1, NULLIF($2, ''), $3, $3) RETURNING category_id",
+			groupId, category.Code, userId,
+		).Scan(&categoryId)
+		if err != nil {
+			return nil, fmt.Errorf("error al restaurar las categorias: %w", err)
+		}
+		categoryIds[category.Id] = categoryId
+
+		for language, name := range category.Names {
+			_, err = tx.Exec(
+				"INSERT INTO public.category_name (category_id, language_code, name) VALUES ($1, $2, $3)",
+				categoryId, language, name,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("error al restaurar los nombres de las categorias: %w", err)
+			}
+		}
+	}
+
+	fieldIds := make(map[string][]uint8, len(backup.Fields))
+	for _, field := range backup.Fields {
+		var fieldId []uint8
+		err = tx.QueryRow(
+			"INSERT INTO public.movement_field (group_id, name, type, required, created_by, updated_by) VALUES ($1, $2, $3, $4, $5, $5) RETURNING movement_field_id",
+			groupId, field.Name, field.Type, field.Required, userId,
+		).Scan(&fieldId)
+		if err != nil {
+			return nil, fmt.Errorf("error al restaurar los campos: %w", err)
+		}
+		fieldIds[field.Id] = fieldId
+
+		if len(field.Options) > 0 {
+			_, err = tx.Exec(
+				"INSERT INTO public.movement_field_options (movement_field_id, value) SELECT $1, unnest($2::text[])",
+				fieldId, pq.Array(field.Options),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("error al restaurar las opciones de los campos: %w", err)
+			}
+		}
+	}
+
+	for _, movement := range backup.Movements {
+		var categoryId any
+		if movement.CategoryId != "" {
+			categoryId = categoryIds[movement.CategoryId]
+		}
+		var bankTransactionId any
+		if movement.BankTransactionId != "" {
+			bankTransactionId = movement.BankTransactionId
+		}
+
+		var movementId []uint8
+		err = tx.QueryRow(`
+			INSERT INTO public.movement (group_id, amount, currency, description, movement_date, split_mode, tax_amount, tip_amount, category_id, bank_transaction_id, created_at, created_by, updated_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			RETURNING movement_id`,
+			groupId, movement.Amount, movement.Currency, movement.Description, movement.Date, movement.SplitMode, movement.Tax, movement.Tip, categoryId, bankTransactionId, movement.CreatedAt, author(movement.CreatedBy), userId,
+		).Scan(&movementId)
+		if err != nil {
+			return nil, fmt.Errorf("error al restaurar los movimientos: %w", err)
+		}
+
+		for _, participant := range movement.Participants {
+			_, err = tx.Exec(
+				"INSERT INTO public.movement_participant (movement_id, user_id, paid_amount, owed_amount, split_value) VALUES ($1, $2, $3, $4, $5)",
+				movementId, users[participant.UserId], participant.Paid, participant.Owed, participant.SplitValue,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("error al restaurar los participantes: %w", err)
+			}
+		}
+
+		for position, item := range movement.Items {
+			var itemId []uint8
+			err = tx.QueryRow(
+				"INSERT INTO public.movement_item (movement_id, position, description, amount, extra_amount) VALUES ($1, $2, $3, $4, $5) RETURNING movement_item_id",
+				movementId, position, item.Description, item.Amount, item.Extra,
+			).Scan(&itemId)
+			if err != nil {
+				return nil, fmt.Errorf("error al restaurar los items: %w", err)
+			}
+			for _, participant := range item.Participants {
+				_, err = tx.Exec(
+					"INSERT INTO public.movement_item_participant (movement_item_id, user_id, owed_amount) VALUES ($1, $2, $3)",
+					itemId, users[participant.UserId], participant.Owed,
+				)
+				if err != nil {
+					return nil, fmt.Errorf("error al restaurar los participantes de los items: %w", err)
+				}
+			}
+		}
+
+		for _, value := range movement.Fields {
+			_, err = tx.Exec(
+				"INSERT INTO public.movement_field_value (movement_id, movement_field_id, value) VALUES ($1, $2, $3)",
+				movementId, fieldIds[value.FieldId], value.Value,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("error al restaurar los valores de los campos: %w", err)
+			}
+		}
+	}
+
+	for _, settlement := range backup.Settlements {
+		_, err = tx.Exec(`
+			INSERT INTO public.settlement (group_id, from_user_id, to_user_id, amount, settlement_date, note, status, created_at, created_by, updated_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			groupId, users[settlement.FromUserId], users[settlement.ToUserId], settlement.Amount, settlement.Date, settlement.Note, settlement.Status, settlement.CreatedAt, author(settlement.CreatedBy), userId,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error al restaurar las liquidaciones: %w", err)
+		}
+	}
+
+	return groupId, tx.Commit()
+}
+
+// Devuelve cuales de los usuarios comparten algun grupo vigente con userId
+func (s *SQLRepository) GetUsersSharingGroup(userId []uint8, users []string) (map[string]bool, error) {
+
+	rows, err := s.db.Query(`
+		SELECT DISTINCT other.user_id
+		FROM auth.user_role mine
+		INNER JOIN auth.user_role other ON other.group_id = mine.group_id
+		WHERE mine.user_id = $1 AND other.user_id = ANY($2::uuid[])
+		AND (mine.valid_until IS NULL OR mine.valid_until > CURRENT_TIMESTAMP)
+		AND (other.valid_until IS NULL OR other.valid_until > CURRENT_TIMESTAMP)`,
+		userId, pq.Array(users))
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar los usuarios que comparten un grupo: %w", err)
+	}
+	defer rows.Close()
+
+	shared := make(map[string]bool)
+	for rows.Next() {
+		var user []uint8
+		if err := rows.Scan(&user); err != nil {
+			return nil, errors.ErrUserScan(err.Error())
+		}
+		shared[string(user)] = true
+	}
+
+	return shared, rows.Err()
+}
+
+// Aux Functions
+
+// Usuarios con rol en el grupo o que aparecen en movimientos o liquidaciones
+func getBackupUsers(tx *sql.Tx, groupId []uint8) ([]*models.BackupUser, error) {
+
+	rows, err := tx.Query(`
+		SELECT u.user_id, u.email, u.user_name, COALESCE(u.photo_url, ''), COALESCE(u.photo_key, '')
+		FROM auth."user" u
+		WHERE u.user_id IN (
+			SELECT ur.user_id FROM auth.user_role ur WHERE ur.group_id = $1
+			UNION
+			SELECT p.user_id FROM public.movement_participant p INNER JOIN public.movement m ON m.movement_id = p.movement_id WHERE m.group_id = $1
+			UNION
+			SELECT st.from_user_id FROM public.settlement st WHERE st.group_id = $1
+			UNION
+			SELECT st.to_user_id FROM public.settlement st WHERE st.group_id = $1
+		)
+		ORDER BY u.email`, groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer los usuarios del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*models.BackupUser
+	for rows.Next() {
+		user := new(models.BackupUser)
+		if err := rows.Scan(&user.Id, &user.Email, &user.UserName, &user.PhotoUrl, &user.PhotoKey); err != nil {
+			return nil, errors.ErrUserScan(err.Error())
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func getBackupMembers(tx *sql.Tx, groupId []uint8) ([]*models.BackupMember, error) {
+
+	rows, err := tx.Query(`
+		SELECT user_id, role_id, created_at, valid_until
+		FROM auth.user_role
+		WHERE group_id = $1
+		ORDER BY created_at`, groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer los miembros del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var members []*models.BackupMember
+	for rows.Next() {
+		member := new(models.BackupMember)
+		if err := rows.Scan(&member.UserId, &member.RoleId, &member.MemberSince, &member.ValidUntil); err != nil {
+			return nil, errors.ErrMemberScan(err.Error())
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+func getBackupCategories(tx *sql.Tx, groupId []uint8) ([]*models.BackupCategory, error) {
+
+	rows, err := tx.Query(`
+		SELECT c.category_id, COALESCE(c.code, ''), COALESCE(json_object_agg(n.language_code, n.name) FILTER (WHERE n.language_code IS NOT NULL), '{}')
+		FROM public.category c
+		LEFT JOIN public.category_name n ON n.category_id = c.category_id
+		WHERE c.group_id = $1
+		GROUP BY c.category_id
+		ORDER BY c.created_at`, groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer las categorias del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []*models.BackupCategory
+	for rows.Next() {
+		category := new(models.BackupCategory)
+		var names []byte
+		if err := rows.Scan(&category.Id, &category.Code, &names); err != nil {
+			return nil, errors.ErrCategoryScan(err.Error())
+		}
+		if err := json.Unmarshal(names, &category.Names); err != nil {
+			return nil, errors.ErrCategoryScan(err.Error())
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+func getBackupFields(tx *sql.Tx, groupId []uint8) ([]*models.BackupField, error) {
+
+	rows, err := tx.Query(`
+		SELECT f.movement_field_id, f.name, f.type, COALESCE(f.required, FALSE),
+			COALESCE(array_agg(o.value ORDER BY o.value) FILTER (WHERE o.value IS NOT NULL), '{}')
+		FROM public.movement_field f
+		LEFT JOIN public.movement_field_options o ON o.movement_field_id = f.movement_field_id
+		WHERE f.group_id = $1
+		GROUP BY f.movement_field_id
+		ORDER BY f.name`, groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer los campos del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var fields []*models.BackupField
+	for rows.Next() {
+		field := new(models.BackupField)
+		if err := rows.Scan(&field.Id, &field.Name, &field.Type, &field.Required, pq.Array(&field.Options)); err != nil {
+			return nil, errors.ErrFieldScan(err.Error())
+		}
+		fields = append(fields, field)
+	}
+
+	return fields, rows.Err()
+}
+
+func getBackupMovements(tx *sql.Tx, groupId []uint8) ([]*models.BackupMovement, error) {
+
+	rows, err := tx.Query(`
+		SELECT m.movement_id, m.movement_date, m.amount, m.currency, COALESCE(m.description, ''), m.split_mode, m.tax_amount, m.tip_amount,
+			COALESCE(m.category_id::text, ''), COALESCE(m.bank_transaction_id, ''), m.created_at, COALESCE(m.created_by::text, ''),
+			COALESCE((SELECT json_agg(json_build_object('userId', p.user_id, 'paid', p.paid_amount, 'owed', p.owed_amount, 'splitValue', p.split_value) ORDER BY p.user_id)
+				FROM public.movement_participant p
+				WHERE p.movement_id = m.movement_id), '[]'),
+			COALESCE((SELECT json_agg(json_build_object('description', i.description, 'amount', i.amount, 'extra', i.extra_amount, 'participants',
+					(SELECT json_agg(json_build_object('userId', ip.user_id, 'owed', ip.owed_amount) ORDER BY ip.user_id)
+					FROM public.movement_item_participant ip
+					WHERE ip.movement_item_id = i.movement_item_id)) ORDER BY i.position)
+				FROM public.movement_item i
+				WHERE i.movement_id = m.movement_id), '[]'),
+			COALESCE((SELECT json_agg(json_build_object('fieldId', v.movement_field_id, 'value', v.value) ORDER BY v.movement_field_id)
+				FROM public.movement_field_value v
+				WHERE v.movement_id = m.movement_id), '[]')
+		FROM public.movement m
+		WHERE m.group_id = $1
+		ORDER BY m.movement_date, m.created_at`, groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer los movimientos del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var movements []*models.BackupMovement
+	for rows.Next() {
+		movement := new(models.BackupMovement)
+		var participants, items, fields []byte
+		err := rows.Scan(
+			&movement.Id,
+			&movement.Date,
+			&movement.Amount,
+			&movement.Currency,
+			&movement.Description,
+			&movement.SplitMode,
+			&movement.Tax,
+			&movement.Tip,
+			&movement.CategoryId,
+			&movement.BankTransactionId,
+			&movement.CreatedAt,
+			&movement.CreatedBy,
+			&participants,
+			&items,
+			&fields,
+		)
+		if err != nil {
+			return nil, errors.ErrMovementScan(err.Error())
+		}
+		if err := json.Unmarshal(participants, &movement.Participants); err != nil {
+			return nil, errors.ErrMovementScan(err.Error())
+		}
+		if err := json.Unmarshal(items, &movement.Items); err != nil {
+			return nil, errors.ErrMovementScan(err.Error())
+		}
+		if err := json.Unmarshal(fields, &movement.Fields); err != nil {
+			return nil, errors.ErrMovementScan(err.Error())
+		}
+		movements = append(movements, movement)
+	}
+
+	return movements, rows.Err()
+}
+
+func getBackupSettlements(tx *sql.Tx, groupId []uint8) ([]*models.BackupSettlement, error) {
+
+	rows, err := tx.Query(`
+		SELECT settlement_id, from_user_id, to_user_id, amount, settlement_date, COALESCE(note, ''), status, created_at, COALESCE(created_by::text, '')
+		FROM public.settlement
+		WHERE group_id = $1
+		ORDER BY settlement_date, created_at`, groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer las liquidaciones del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var settlements []*models.BackupSettlement
+	for rows.Next() {
+		settlement := new(models.BackupSettlement)
+		err := rows.Scan(
+			&settlement.Id,
+			&settlement.FromUserId,
+			&settlement.ToUserId,
+			&settlement.Amount,
+			&settlement.Date,
+			&settlement.Note,
+			&settlement.Status,
+			&settlement.CreatedAt,
+			&settlement.CreatedBy,
+		)
+		if err != nil {
+			return nil, errors.ErrSettlementScan(err.Error())
+		}
+		settlements = append(settlements, settlement)
+	}
+
+	return settlements, rows.Err()
+}
@@ -0,0 +1,506 @@
+package backups
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/images"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/storage"
+	"github.com/shopspring/decimal"
+)
+
+// Same check as the photo_url columns
+var photoUrlPattern = regexp.MustCompile(`(?i)^https?://.+`)
+
+var (
+	roles       = map[string]bool{models.RoleViewer: true, models.RoleEditor: true, models.RoleAdmin: true}
+	splitModes  = map[string]bool{models.SplitModeEqual: true, models.SplitModeExact: true, models.SplitModePercentage: true, models.SplitModeShares: true, models.SplitModeItems: true}
+	fieldTypes  = map[string]bool{models.FieldTypeText: true, models.FieldTypeNumber: true, models.FieldTypeDate: true, models.FieldTypeBoolean: true, models.FieldTypeSelect: true}
+	settlements = map[string]bool{models.SettlementPending: true, models.SettlementConfirmed: true, models.SettlementDisputed: true}
+)
+
+type Service struct {
+	repository         models.BackupRepository
+	userRepository     models.UserRepository
+	groupRepository    models.GroupRepository
+	categoryRepository models.CategoryRepository
+	storage            models.Storage
+}
+
+func NewService(repository models.BackupRepository, userRepository models.UserRepository, groupRepository models.GroupRepository, categoryRepository models.CategoryRepository, fileStorage models.Storage) *Service {
+	return &Service{repository: repository, userRepository: userRepository, groupRepository: groupRepository, categoryRepository: categoryRepository, storage: fileStorage}
+}
+
+// BackupGroup copies the stored photos of the group and its users into the
+// archive, their storage keys are of no use to another server.
+func (s *Service) BackupGroup(groupId []uint8) (*models.GroupBackup, error) {
+
+	backup, err := s.repository.GetGroupBackup(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	if backup.Group.Photo, err = s.readPhoto(backup.Group.PhotoKey); err != nil {
+		return nil, err
+	}
+	for _, user := range backup.Users {
+		if user.Photo, err = s.readPhoto(user.PhotoKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return backup, nil
+}
+
+// RestoreGroup checks the whole archive before writing anything and restores
+// it as a new group only when there are no conflicts and dryRun is false.
+// Restoring adds people to a group without asking them, so every user of the
+// archive must already share a group with the caller.
+func (s *Service) RestoreGroup(backup *models.GroupBackup, dryRun bool, userId []uint8) (*models.RestoreResult, error) {
+
+	result := &models.RestoreResult{
+		DryRun:      dryRun,
+		Members:     len(backup.Members),
+		Categories:  len(backup.Categories),
+		Fields:      len(backup.Fields),
+		Movements:   len(backup.Movements),
+		Settlements: len(backup.Settlements),
+		Conflicts:   []models.RestoreConflict{},
+	}
+
+	if backup.Version != models.BackupVersion {
+		result.Conflicts = append(result.Conflicts, models.RestoreConflict{
+			Type:    models.RestoreConflictVersion,
+			Message: fmt.Sprintf("archive version %d is not supported, expected %d", backup.Version, models.BackupVersion),
+		})
+		return result, nil
+	}
+
+	languages, err := s.categoryRepository.GetLanguages()
+	if err != nil {
+		return nil, err
+	}
+
+	v := &restoreValidator{languages: make(map[string]bool, len(languages)), userPhotos: make(map[string]*images.Photo)}
+	for _, language := range languages {
+		v.languages[language] = true
+	}
+
+	users, err := s.matchUsers(v, backup.Users, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	v.validate(backup)
+	result.Conflicts = v.conflicts
+
+	if dryRun || len(result.Conflicts) > 0 {
+		return result, nil
+	}
+
+	result.GroupId, err = s.repository.RestoreGroup(backup, users, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	s.restorePhotos(v, backup, users, result.GroupId, userId)
+
+	return result, nil
+}
+
+// Aux Functions
+
+func (s *Service) readPhoto(key string) (*models.BackupPhoto, error) {
+
+	if key == "" {
+		return nil, nil
+	}
+
+	file, err := s.storage.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType, _, err := storage.Detect(data, storage.PhotoTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BackupPhoto{ContentType: contentType, Data: data}, nil
+}
+
+// restorePhotos stores the photos checked by the validator. The group is
+// already restored, so a failure only leaves it or the user without photo.
+func (s *Service) restorePhotos(v *restoreValidator, backup *models.GroupBackup, users map[string][]uint8, groupId []uint8, userId []uint8) {
+
+	if v.groupPhoto != nil {
+		err := s.storePhoto("groups/"+string(groupId), v.groupPhoto, func(key string) error {
+			return s.groupRepository.UpdatePhotoKey(key, groupId, userId)
+		})
+		if err != nil {
+			log.Printf("restoring photo of group %s: %v", groupId, err)
+		}
+	}
+
+	for _, backupUser := range backup.Users {
+		photo, ok := v.userPhotos[backupUser.Id]
+		if !ok {
+			continue
+		}
+		err := s.storePhoto("users/"+string(users[backupUser.Id]), photo, func(key string) error {
+			return s.userRepository.UpdatePhotoKey(key, backupUser.Email)
+		})
+		if err != nil {
+			log.Printf("restoring photo of user %s: %v", backupUser.Email, err)
+		}
+	}
+}
+
+// storePhoto saves the photo and its key, deleting the files if the key can
+// not be saved
+func (s *Service) storePhoto(prefix string, photo *images.Photo, save func(key string) error) error {
+
+	key, err := images.Store(s.storage, prefix, photo)
+	if err != nil {
+		return err
+	}
+
+	if err := save(key); err != nil {
+		images.Delete(s.storage, key)
+		return err
+	}
+
+	return nil
+}
+
+// matchUsers finds every user of the archive in this server by email. Users
+// other than userId that do not share a group with it are conflicts.
+func (s *Service) matchUsers(v *restoreValidator, backupUsers []*models.BackupUser, userId []uint8) (map[string][]uint8, error) {
+
+	users := make(map[string][]uint8, len(backupUsers))
+	emails := make(map[string]bool, len(backupUsers))
+	withoutPhoto := make(map[string]bool)
+	var matched []*models.BackupUser
+	v.users = make(map[string]bool, len(backupUsers))
+
+	for _, backupUser := range backupUsers {
+		if !v.unique(v.users, backupUser.Id, "user") {
+			continue
+		}
+
+		email := strings.ToLower(strings.TrimSpace(backupUser.Email))
+		if emails[email] {
+			v.conflict(models.RestoreConflictDuplicate, backupUser.Id, fmt.Sprintf("email %s is used by more than one user", backupUser.Email))
+			continue
+		}
+		emails[email] = true
+
+		user, err := s.userRepository.GetUserByEmail(backupUser.Email)
+		if err == errors.ErrUserNotFound {
+			v.conflict(models.RestoreConflictUser, backupUser.Id, fmt.Sprintf("there is no user with email %s, it must sign up before the restore", backupUser.Email))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		users[backupUser.Id] = user.UserId
+		matched = append(matched, backupUser)
+		if user.PhotoKey == "" && user.PhotoUrl == "" {
+			withoutPhoto[backupUser.Id] = true
+		}
+	}
+
+	ids := make([]string, 0, len(users))
+	for _, id := range users {
+		ids = append(ids, string(id))
+	}
+	shared, err := s.repository.GetUsersSharingGroup(userId, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, backupUser := range matched {
+		id := users[backupUser.Id]
+		if string(id) != string(userId) && !shared[string(id)] {
+			v.conflict(models.RestoreConflictUser, backupUser.Id, fmt.Sprintf("user with email %s does not share a group with you, it must be added to one of your groups before the restore", backupUser.Email))
+			continue
+		}
+
+		// Users keep the photo they have in this server
+		if withoutPhoto[backupUser.Id] {
+			if photo := v.photo(backupUser.Photo, backupUser.Id); photo != nil {
+				v.userPhotos[backupUser.Id] = photo
+			}
+		}
+	}
+
+	return users, nil
+}
+
+// restoreValidator collects the conflicts of an archive. The id sets hold the
+// archive ids already seen, to check references and duplicates. The photos
+// are the archived ones that will be stored, already decoded and checked.
+type restoreValidator struct {
+	languages  map[string]bool
+	users      map[string]bool
+	categories map[string]bool
+	fields     map[string]*models.BackupField
+	groupPhoto *images.Photo
+	userPhotos map[string]*images.Photo
+	conflicts  []models.RestoreConflict
+}
+
+func (v *restoreValidator) conflict(kind string, id string, message string) {
+
+	v.conflicts = append(v.conflicts, models.RestoreConflict{Type: kind, Id: id, Message: message})
+}
+
+// unique adds id to seen and reports empty and repeated ids
+func (v *restoreValidator) unique(seen map[string]bool, id string, entity string) bool {
+
+	if id == "" {
+		v.conflict(models.RestoreConflictInvalid, "", fmt.Sprintf("a %s has no id", entity))
+		return false
+	}
+	if seen[id] {
+		v.conflict(models.RestoreConflictDuplicate, id, fmt.Sprintf("%s id %s appears more than once", entity, id))
+		return false
+	}
+	seen[id] = true
+	return true
+}
+
+// photo processes an archived photo like an upload, so only real images
+// with their metadata stripped end up in storage
+func (v *restoreValidator) photo(photo *models.BackupPhoto, owner string) *images.Photo {
+
+	if photo == nil {
+		return nil
+	}
+
+	processed, err := images.Process(photo.Data)
+	if err != nil {
+		v.conflict(models.RestoreConflictInvalid, owner, fmt.Sprintf("the photo is not valid: %v", err))
+		return nil
+	}
+	return processed
+}
+
+func (v *restoreValidator) user(id string, owner string, role string) {
+
+	if !v.users[id] {
+		v.conflict(models.RestoreConflictReference, owner, fmt.Sprintf("%s %s is not in the users of the archive", role, id))
+	}
+}
+
+func (v *restoreValidator) validate(backup *models.GroupBackup) {
+
+	group := backup.Group
+	if group.Name == "" || utf8.RuneCountInString(group.Name) > 50 {
+		v.conflict(models.RestoreConflictInvalid, group.Id, "the group name must have between 1 and 50 characters")
+	}
+	if len(group.BaseCurrency) != 3 {
+		v.conflict(models.RestoreConflictInvalid, group.Id, fmt.Sprintf("base currency %q is not a currency code", group.BaseCurrency))
+	}
+	// Stored photos come in Photo, PhotoUrl is only for links
+	if group.PhotoUrl != "" && !photoUrlPattern.MatchString(group.PhotoUrl) {
+		v.conflict(models.RestoreConflictInvalid, group.Id, fmt.Sprintf("photo %q is not an http or https url", group.PhotoUrl))
+	}
+	v.groupPhoto = v.photo(group.Photo, group.Id)
+
+	members := make(map[string]bool, len(backup.Members))
+	for _, member := range backup.Members {
+		v.user(member.UserId, member.UserId, "member")
+		if members[member.UserId] {
+			v.conflict(models.RestoreConflictDuplicate, member.UserId, fmt.Sprintf("user %s is a member more than once", member.UserId))
+		}
+		members[member.UserId] = true
+		if !roles[member.RoleId] {
+			v.conflict(models.RestoreConflictInvalid, member.UserId, fmt.Sprintf("role %q does not exist", member.RoleId))
+		}
+	}
+
+	v.categories = make(map[string]bool, len(backup.Categories))
+	for _, category := range backup.Categories {
+		if !v.unique(v.categories, category.Id, "category") {
+			continue
+		}
+		if len(category.Names) == 0 {
+			v.conflict(models.RestoreConflictInvalid, category.Id, "the category has no names")
+		}
+		for language, name := range category.Names {
+			if !v.languages[language] {
+				v.conflict(models.RestoreConflictLanguage, category.Id, fmt.Sprintf("language %s does not exist in this server", language))
+			}
+			if name == "" || utf8.RuneCountInString(name) > 50 {
+				v.conflict(models.RestoreConflictInvalid, category.Id, fmt.Sprintf("the %s name must have between 1 and 50 characters", language))
+			}
+		}
+	}
+
+	v.fields = make(map[string]*models.BackupField, len(backup.Fields))
+	fieldIds := make(map[string]bool, len(backup.Fields))
+	fieldNames := make(map[string]bool, len(backup.Fields))
+	for _, field := range backup.Fields {
+		if !v.unique(fieldIds, field.Id, "field") {
+			continue
+		}
+		v.fields[field.Id] = field
+		if fieldNames[field.Name] {
+			v.conflict(models.RestoreConflictDuplicate, field.Id, fmt.Sprintf("field name %s appears more than once", field.Name))
+		}
+		fieldNames[field.Name] = true
+		if !fieldTypes[field.Type] {
+			v.conflict(models.RestoreConflictInvalid, field.Id, fmt.Sprintf("field type %q does not exist", field.Type))
+		}
+	}
+
+	movementIds := make(map[string]bool, len(backup.Movements))
+	bankIds := make(map[string]bool)
+	for _, movement := range backup.Movements {
+		if v.unique(movementIds, movement.Id, "movement") {
+			v.validateMovement(movement, bankIds)
+		}
+	}
+
+	settlementIds := make(map[string]bool, len(backup.Settlements))
+	for _, settlement := range backup.Settlements {
+		if !v.unique(settlementIds, settlement.Id, "settlement") {
+			continue
+		}
+		v.user(settlement.FromUserId, settlement.Id, "payer")
+		v.user(settlement.ToUserId, settlement.Id, "payee")
+		if settlement.FromUserId == settlement.ToUserId {
+			v.conflict(models.RestoreConflictInvalid, settlement.Id, "the payer and the payee are the same user")
+		}
+		if !settlement.Amount.IsPositive() {
+			v.conflict(models.RestoreConflictInvalid, settlement.Id, "the amount must be positive")
+		}
+		if !settlements[settlement.Status] {
+			v.conflict(models.RestoreConflictInvalid, settlement.Id, fmt.Sprintf("status %q does not exist", settlement.Status))
+		}
+		v.date(settlement.Date, settlement.Id)
+	}
+}
+
+func (v *restoreValidator) validateMovement(movement *models.BackupMovement, bankIds map[string]bool) {
+
+	if !movement.Amount.IsPositive() {
+		v.conflict(models.RestoreConflictInvalid, movement.Id, "the amount must be positive")
+	}
+	if len(movement.Currency) != 3 {
+		v.conflict(models.RestoreConflictInvalid, movement.Id, fmt.Sprintf("currency %q is not a currency code", movement.Currency))
+	}
+	if !splitModes[movement.SplitMode] {
+		v.conflict(models.RestoreConflictInvalid, movement.Id, fmt.Sprintf("split mode %q does not exist", movement.SplitMode))
+	}
+	if movement.CategoryId != "" && !v.categories[movement.CategoryId] {
+		v.conflict(models.RestoreConflictReference, movement.Id, fmt.Sprintf("category %s is not in the archive", movement.CategoryId))
+	}
+	if movement.BankTransactionId != "" {
+		if bankIds[movement.BankTransactionId] {
+			v.conflict(models.RestoreConflictDuplicate, movement.Id, fmt.Sprintf("bank transaction %s is imported more than once", movement.BankTransactionId))
+		}
+		bankIds[movement.BankTransactionId] = true
+	}
+	v.date(movement.Date, movement.Id)
+
+	// The participants must add up to the amount, like CreateMovement checks
+	paid, owed := decimal.Zero, decimal.Zero
+	participants := make(map[string]bool, len(movement.Participants))
+	for _, participant := range movement.Participants {
+		v.user(participant.UserId, movement.Id, "participant")
+		if participants[participant.UserId] {
+			v.conflict(models.RestoreConflictDuplicate, movement.Id, fmt.Sprintf("participant %s appears more than once", participant.UserId))
+		}
+		participants[participant.UserId] = true
+		if participant.Paid.IsNegative() || participant.Owed.IsNegative() {
+			v.conflict(models.RestoreConflictInvalid, movement.Id, fmt.Sprintf("participant %s has a negative amount", participant.UserId))
+		}
+		paid = paid.Add(participant.Paid)
+		owed = owed.Add(participant.Owed)
+	}
+	if len(movement.Participants) == 0 {
+		v.conflict(models.RestoreConflictInvalid, movement.Id, "the movement has no participants")
+	} else if !paid.Equal(movement.Amount) || !owed.Equal(movement.Amount) {
+		v.conflict(models.RestoreConflictInvalid, movement.Id, fmt.Sprintf("participants paid %s and owe %s of %s", paid, owed, movement.Amount))
+	}
+
+	v.validateItems(movement, participants)
+
+	values := make(map[string]bool, len(movement.Fields))
+	for _, value := range movement.Fields {
+		field, ok := v.fields[value.FieldId]
+		if !ok {
+			v.conflict(models.RestoreConflictReference, movement.Id, fmt.Sprintf("field %s is not in the archive", value.FieldId))
+			continue
+		}
+		if values[value.FieldId] {
+			v.conflict(models.RestoreConflictDuplicate, movement.Id, fmt.Sprintf("field %s has more than one value", field.Name))
+		}
+		values[value.FieldId] = true
+	}
+}
+
+// validateItems checks that the items of an itemized movement add up to its
+// amount and to what its participants owe
+func (v *restoreValidator) validateItems(movement *models.BackupMovement, participants map[string]bool) {
+
+	if movement.SplitMode != models.SplitModeItems {
+		if len(movement.Items) > 0 || !movement.Tax.IsZero() || !movement.Tip.IsZero() {
+			v.conflict(models.RestoreConflictInvalid, movement.Id, "only items splits have items, tax and tip")
+		}
+		return
+	}
+
+	if len(movement.Items) == 0 {
+		v.conflict(models.RestoreConflictInvalid, movement.Id, "the items split has no items")
+		return
+	}
+	if movement.Tax.IsNegative() || movement.Tip.IsNegative() {
+		v.conflict(models.RestoreConflictInvalid, movement.Id, "tax and tip can not be negative")
+	}
+
+	total, extra := decimal.Zero, decimal.Zero
+	for _, item := range movement.Items {
+		if !item.Amount.IsPositive() || item.Extra.IsNegative() {
+			v.conflict(models.RestoreConflictInvalid, movement.Id, fmt.Sprintf("item %q has an invalid amount", item.Description))
+		}
+		total = total.Add(item.Amount)
+		extra = extra.Add(item.Extra)
+
+		owed := decimal.Zero
+		for _, participant := range item.Participants {
+			if !participants[participant.UserId] {
+				v.conflict(models.RestoreConflictReference, movement.Id, fmt.Sprintf("item %q participant %s does not take part in the movement", item.Description, participant.UserId))
+			}
+			owed = owed.Add(participant.Owed)
+		}
+		if len(item.Participants) == 0 || !owed.Equal(item.Amount.Add(item.Extra)) {
+			v.conflict(models.RestoreConflictInvalid, movement.Id, fmt.Sprintf("item %q participants owe %s of %s", item.Description, owed, item.Amount.Add(item.Extra)))
+		}
+	}
+
+	if !extra.Equal(movement.Tax.Add(movement.Tip)) || !total.Add(extra).Equal(movement.Amount) {
+		v.conflict(models.RestoreConflictInvalid, movement.Id, fmt.Sprintf("items add up to %s plus %s of tax and tip but the amount is %s", total, extra, movement.Amount))
+	}
+}
+
+func (v *restoreValidator) date(date time.Time, id string) {
+
+	if date.IsZero() {
+		v.conflict(models.RestoreConflictInvalid, id, "the date is missing")
+	}
+}
@@ -0,0 +1,80 @@
+package backups
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+func TestMatchUsersNeedsASharedGroup(t *testing.T) {
+
+	s := &Service{
+		repository: &sharingRepository{shared: map[string]bool{"id-friend": true}},
+		userRepository: &emailRepository{users: map[string]string{
+			"me@example.com":       "id-me",
+			"friend@example.com":   "id-friend",
+			"stranger@example.com": "id-stranger",
+		}},
+	}
+
+	backupUsers := []*models.BackupUser{
+		{Id: "a", Email: "me@example.com"},
+		{Id: "b", Email: "Friend@example.com"},
+		{Id: "c", Email: "stranger@example.com"},
+		{Id: "d", Email: "nobody@example.com"},
+	}
+
+	v := &restoreValidator{}
+	users, err := s.matchUsers(v, backupUsers, []uint8("id-me"))
+	if err != nil {
+		t.Fatalf("matchUsers() error = %v", err)
+	}
+
+	if len(users) != 3 || string(users["b"]) != "id-friend" {
+		t.Errorf("matchUsers() = %v, want the users found by email", users)
+	}
+
+	var conflicts []string
+	for _, conflict := range v.conflicts {
+		conflicts = append(conflicts, conflict.Type+" "+conflict.Id)
+	}
+	sort.Strings(conflicts)
+	if want := "user c,user d"; strings.Join(conflicts, ",") != want {
+		t.Errorf("matchUsers() conflicts = %v, want %s", conflicts, want)
+	}
+}
+
+// Aux Functions
+
+type sharingRepository struct {
+	models.BackupRepository
+	shared map[string]bool
+}
+
+func (r *sharingRepository) GetUsersSharingGroup(userId []uint8, users []string) (map[string]bool, error) {
+
+	shared := make(map[string]bool)
+	for _, user := range users {
+		if r.shared[user] {
+			shared[user] = true
+		}
+	}
+	return shared, nil
+}
+
+type emailRepository struct {
+	models.UserRepository
+	users map[string]string
+}
+
+func (r *emailRepository) GetUserByEmail(email string) (*models.User, error) {
+
+	id, ok := r.users[strings.ToLower(email)]
+	if !ok {
+		return nil, errors.ErrUserNotFound
+	}
+	return &models.User{UserId: []uint8(id), Email: email}, nil
+}
@@ -0,0 +1,55 @@
+package balances
+
+import (
+	"net/http"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/middlewares"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service        models.BalanceService
+	authRepository models.AuthRepository
+}
+
+func NewHandler(service models.BalanceService, authRepository models.AuthRepository) *Handler {
+	return &Handler{service: service, authRepository: authRepository}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+
+	router.HandleFunc("/group/{groupId}/balances", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetBalances, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}/settle-up", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetSettleUp, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+}
+
+func (h *Handler) handleGetBalances(w http.ResponseWriter, r *http.Request) {
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	balances, err := h.service.GetGroupBalances(groupId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, balances)
+}
+
+func (h *Handler) handleGetSettleUp(w http.ResponseWriter, r *http.Request) {
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	settleUp, err := h.service.GetSettleUp(groupId)
+	if err == errors.ErrGroupNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, settleUp)
+}
@@ -0,0 +1,174 @@
+package balances
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Postgres SQL Repository
+type SQLRepository struct {
+	db *sql.DB
+}
+
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+// Devuelve los miembros del grupo y los usuarios que participaron de algun
+// movimiento o pago aunque ya no pertenezcan al grupo
+func (s *SQLRepository) GetBalanceMembers(groupId []uint8) ([]*models.BalanceMember, error) {
+
+	rows, err := s.db.Query(`
+		SELECT u.user_id, u.user_name
+		FROM auth."user" u
+		WHERE u.user_id IN (
+			SELECT user_id FROM auth.user_role WHERE group_id = $1
+			UNION
+			SELECT p.user_id FROM public.movement_participant p
+			INNER JOIN public.movement m ON m.movement_id = p.movement_id
+			WHERE m.group_id = $1
+			UNION
+			SELECT from_user_id FROM public.settlement WHERE group_id = $1
+			UNION
+			SELECT to_user_id FROM public.settlement WHERE group_id = $1
+		)
+		ORDER BY u.user_id`, groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los miembros del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var members []*models.BalanceMember
+	for rows.Next() {
+		member := new(models.BalanceMember)
+		if err := rows.Scan(&member.UserId, &member.UserName); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+func (s *SQLRepository) GetGroupLedger(groupId []uint8) ([]*models.LedgerEntry, error) {
+
+	rows, err := s.db.Query(`
+		SELECT p.movement_id, m.currency, m.movement_date, p.user_id, p.paid_amount, p.owed_amount
+		FROM public.movement_participant p
+		INNER JOIN public.movement m ON m.movement_id = p.movement_id
+		WHERE m.group_id = $1
+		ORDER BY p.movement_id`, groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los movimientos del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var ledger []*models.LedgerEntry
+	var entry *models.LedgerEntry
+
+	for rows.Next() {
+		var movementId, userId []uint8
+		var currency string
+		var movementDate time.Time
+		var paid, owed decimal.Decimal
+		if err := rows.Scan(&movementId, &currency, &movementDate, &userId, &paid, &owed); err != nil {
+			return nil, err
+		}
+
+		if entry == nil || string(entry.MovementId) != string(movementId) {
+			entry = &models.LedgerEntry{
+				MovementId: movementId,
+				Currency:   currency,
+				Date:       movementDate,
+				Paid:       make(map[string]decimal.Decimal),
+				Owed:       make(map[string]decimal.Decimal),
+			}
+			ledger = append(ledger, entry)
+		}
+
+		if paid.IsPositive() {
+			entry.Paid[string(userId)] = paid
+		}
+		if owed.IsPositive() {
+			entry.Owed[string(userId)] = owed
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	settlements, err := s.getSettlementLedger(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(ledger, settlements...), nil
+}
+
+func (s *SQLRepository) GetSimplifyDebts(groupId []uint8) (bool, error) {
+
+	var simplifyDebts bool
+	err := s.db.QueryRow("SELECT simplify_debts FROM public.\"group\" WHERE group_id = $1", groupId).Scan(&simplifyDebts)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, errors.ErrGroupNotFound
+		}
+		return false, err
+	}
+
+	return simplifyDebts, nil
+}
+
+func (s *SQLRepository) GetBaseCurrency(groupId []uint8) (string, error) {
+
+	var currency string
+	err := s.db.QueryRow("SELECT base_currency FROM public.\"group\" WHERE group_id = $1", groupId).Scan(&currency)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.ErrGroupNotFound
+		}
+		return "", err
+	}
+
+	return currency, nil
+}
+
+// Aux Functions
+
+// getSettlementLedger returns the settlements of the group that were not
+// disputed as ledger entries.
+func (s *SQLRepository) getSettlementLedger(groupId []uint8) ([]*models.LedgerEntry, error) {
+
+	rows, err := s.db.Query(`
+		SELECT settlement_id, from_user_id, to_user_id, amount
+		FROM public.settlement
+		WHERE group_id = $1 AND status <> $2
+		ORDER BY settlement_id`, groupId, models.SettlementDisputed)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los pagos del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var ledger []*models.LedgerEntry
+	for rows.Next() {
+		var settlementId, fromUserId, toUserId []uint8
+		var amount decimal.Decimal
+		if err := rows.Scan(&settlementId, &fromUserId, &toUserId, &amount); err != nil {
+			return nil, err
+		}
+		ledger = append(ledger, &models.LedgerEntry{
+			MovementId: settlementId,
+			Settlement: true,
+			Paid:       map[string]decimal.Decimal{string(fromUserId): amount},
+			Owed:       map[string]decimal.Decimal{string(toUserId): amount},
+		})
+	}
+
+	return ledger, rows.Err()
+}
@@ -0,0 +1,200 @@
+package balances
+
+import (
+	"sort"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/money"
+	"github.com/shopspring/decimal"
+)
+
+type Service struct {
+	repository  models.BalanceRepository
+	rateService models.ExchangeRateService
+}
+
+func NewService(repository models.BalanceRepository, rateService models.ExchangeRateService) *Service {
+	return &Service{repository: repository, rateService: rateService}
+}
+
+func (s *Service) GetGroupBalances(groupId []uint8) (*models.GroupBalances, error) {
+
+	members, err := s.repository.GetBalanceMembers(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger, err := s.repository.GetGroupLedger(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := s.repository.GetBaseCurrency(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.convertLedger(ledger, currency); err != nil {
+		return nil, err
+	}
+
+	balances := ComputeBalances(members, ledger)
+	balances.GroupId = groupId
+	balances.Currency = currency
+
+	return balances, nil
+}
+
+func (s *Service) GetSettleUp(groupId []uint8) (*models.SettleUp, error) {
+
+	simplify, err := s.repository.GetSimplifyDebts(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	balances, err := s.GetGroupBalances(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	settleUp := &models.SettleUp{GroupId: groupId, Currency: balances.Currency, Simplified: simplify, Transfers: balances.Debts}
+	if simplify {
+		settleUp.Transfers = SimplifyDebts(balances.Members)
+	}
+
+	return settleUp, nil
+}
+
+// ComputeBalances returns the net position of every member and the debts
+// between each pair of members. Settlements move money from the payer to the
+// receiver, so they cancel the debt in the opposite direction. Net positions
+// are exact, pairwise debts are accumulated without rounding and rounded to
+// cents once at the end.
+func ComputeBalances(members []*models.BalanceMember, ledger []*models.LedgerEntry) *models.GroupBalances {
+
+	byId := make(map[string]*models.MemberBalance, len(members))
+	result := &models.GroupBalances{Members: []*models.MemberBalance{}, Debts: []*models.Debt{}}
+
+	member := func(userId string) *models.MemberBalance {
+		if m, ok := byId[userId]; ok {
+			return m
+		}
+		m := &models.MemberBalance{UserId: []uint8(userId)}
+		byId[userId] = m
+		result.Members = append(result.Members, m)
+		return m
+	}
+
+	for _, m := range members {
+		member(string(m.UserId)).UserName = m.UserName
+	}
+
+	// owes[debtor][creditor]
+	owes := make(map[string]map[string]decimal.Decimal)
+
+	for _, entry := range ledger {
+
+		totalPaid := decimal.Zero
+		for userId, paid := range entry.Paid {
+			if entry.Settlement {
+				member(userId).Sent = member(userId).Sent.Add(paid)
+			} else {
+				member(userId).Paid = member(userId).Paid.Add(paid)
+			}
+			totalPaid = totalPaid.Add(paid)
+		}
+
+		for userId, owed := range entry.Owed {
+			if entry.Settlement {
+				member(userId).Received = member(userId).Received.Add(owed)
+			} else {
+				member(userId).Owed = member(userId).Owed.Add(owed)
+			}
+		}
+
+		if totalPaid.IsZero() {
+			continue
+		}
+
+		for debtor, owed := range entry.Owed {
+			for creditor, paid := range entry.Paid {
+				if debtor == creditor {
+					continue
+				}
+				if owes[debtor] == nil {
+					owes[debtor] = make(map[string]decimal.Decimal)
+				}
+				share := owed.Mul(paid).Div(totalPaid)
+				owes[debtor][creditor] = owes[debtor][creditor].Add(share)
+			}
+		}
+	}
+
+	for _, m := range result.Members {
+		m.Net = m.Paid.Sub(m.Owed).Add(m.Sent).Sub(m.Received)
+	}
+
+	sort.Slice(result.Members, func(i, j int) bool {
+		return string(result.Members[i].UserId) < string(result.Members[j].UserId)
+	})
+
+	for i, a := range result.Members {
+		for _, b := range result.Members[i+1:] {
+			idA, idB := string(a.UserId), string(b.UserId)
+			diff := owes[idA][idB].Sub(owes[idB][idA]).Round(2)
+
+			switch diff.Sign() {
+			case 1:
+				result.Debts = append(result.Debts, newDebt(a, b, diff))
+			case -1:
+				result.Debts = append(result.Debts, newDebt(b, a, diff.Neg()))
+			}
+		}
+	}
+
+	return result
+}
+
+// Aux Functions
+
+// convertLedger expresses every entry in the base currency using the rate of
+// the entry date. The converted total is rounded to cents and spread over the
+// payers and debtors with money.Allocate, so each entry stays balanced.
+func (s *Service) convertLedger(ledger []*models.LedgerEntry, currency string) error {
+
+	for _, entry := range ledger {
+		if entry.Currency == "" || entry.Currency == currency {
+			continue
+		}
+
+		total := decimal.Zero
+		for _, paid := range entry.Paid {
+			total = total.Add(paid)
+		}
+		if total.IsZero() {
+			continue
+		}
+
+		converted, err := s.rateService.Convert(total, entry.Currency, currency, entry.Date)
+		if err != nil {
+			return err
+		}
+
+		entry.Paid = money.Allocate(converted, entry.Paid)
+		entry.Owed = money.Allocate(converted, entry.Owed)
+		entry.Currency = currency
+	}
+
+	return nil
+}
+
+func newDebt(from *models.MemberBalance, to *models.MemberBalance, amount decimal.Decimal) *models.Debt {
+
+	return &models.Debt{
+		From:     from.UserId,
+		FromName: from.UserName,
+		To:       to.UserId,
+		ToName:   to.UserName,
+		Amount:   amount,
+	}
+}
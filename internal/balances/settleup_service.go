@@ -0,0 +1,168 @@
+package balances
+
+import (
+	"sort"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Above this number of members with a non zero balance the exact search is
+// too expensive (2^n states) and the greedy settlement is used directly.
+const maxExactMembers = 16
+
+type position struct {
+	member *models.MemberBalance
+	cents  int64
+}
+
+// SimplifyDebts returns the fewest transfers that leave every member with a
+// zero balance. The members are split into the largest possible number of
+// independent groups whose balances add up to zero; a group of k members is
+// always settled with k-1 transfers, so maximizing the groups minimizes the
+// transfers. The result only depends on the balances and the user ids.
+func SimplifyDebts(members []*models.MemberBalance) []*models.Debt {
+
+	var positions []position
+	for _, m := range members {
+		cents := m.Net.Shift(2).Round(0).IntPart()
+		if cents != 0 {
+			positions = append(positions, position{member: m, cents: cents})
+		}
+	}
+
+	sort.Slice(positions, func(i, j int) bool {
+		return string(positions[i].member.UserId) < string(positions[j].member.UserId)
+	})
+
+	transfers := []*models.Debt{}
+
+	if len(positions) > maxExactMembers {
+		return append(transfers, settleGreedy(positions)...)
+	}
+
+	for _, group := range zeroSumGroups(positions) {
+		transfers = append(transfers, settleGreedy(group)...)
+	}
+
+	return transfers
+}
+
+// zeroSumGroups partitions the positions into the maximum number of subsets
+// that add up to zero. best[mask] is the maximum number of nested zero sum
+// subsets found removing the members of mask one by one, and from[mask] the
+// mask left after the removal that achieves it.
+func zeroSumGroups(positions []position) [][]position {
+
+	n := len(positions)
+	if n == 0 {
+		return nil
+	}
+
+	full := 1<<n - 1
+	sum := make([]int64, full+1)
+	best := make([]int, full+1)
+	from := make([]int, full+1)
+
+	for mask := 1; mask <= full; mask++ {
+		low := lowestBit(mask)
+		sum[mask] = sum[mask&^(1<<low)] + positions[low].cents
+
+		best[mask] = -1
+		for i := low; i < n; i++ {
+			if mask&(1<<i) == 0 {
+				continue
+			}
+			prev := mask &^ (1 << i)
+			if best[prev] > best[mask] {
+				best[mask] = best[prev]
+				from[mask] = prev
+			}
+		}
+		if sum[mask] == 0 {
+			best[mask]++
+		}
+	}
+
+	var groups [][]position
+	boundary := full
+	for mask := from[full]; ; mask = from[mask] {
+		if sum[mask] == 0 {
+			groups = append(groups, pick(positions, boundary&^mask))
+			boundary = mask
+		}
+		if mask == 0 {
+			break
+		}
+	}
+
+	return groups
+}
+
+// settleGreedy matches the largest debtor with the largest creditor until
+// every balance is zero. Ties are broken by user id.
+func settleGreedy(group []position) []*models.Debt {
+
+	var debtors, creditors []position
+	for _, p := range group {
+		if p.cents < 0 {
+			debtors = append(debtors, position{member: p.member, cents: -p.cents})
+		} else {
+			creditors = append(creditors, p)
+		}
+	}
+
+	var transfers []*models.Debt
+
+	for len(debtors) > 0 && len(creditors) > 0 {
+		sortPositions(debtors)
+		sortPositions(creditors)
+
+		amount := min(debtors[0].cents, creditors[0].cents)
+		transfers = append(transfers, newDebt(debtors[0].member, creditors[0].member, decimal.New(amount, -2)))
+
+		debtors[0].cents -= amount
+		creditors[0].cents -= amount
+		if debtors[0].cents == 0 {
+			debtors = debtors[1:]
+		}
+		if creditors[0].cents == 0 {
+			creditors = creditors[1:]
+		}
+	}
+
+	return transfers
+}
+
+// Aux Functions
+
+func sortPositions(positions []position) {
+
+	sort.SliceStable(positions, func(i, j int) bool {
+		if positions[i].cents != positions[j].cents {
+			return positions[i].cents > positions[j].cents
+		}
+		return string(positions[i].member.UserId) < string(positions[j].member.UserId)
+	})
+}
+
+func lowestBit(mask int) int {
+
+	i := 0
+	for mask&1 == 0 {
+		mask >>= 1
+		i++
+	}
+	return i
+}
+
+func pick(positions []position, mask int) []position {
+
+	var result []position
+	for i := range positions {
+		if mask&(1<<i) != 0 {
+			result = append(result, positions[i])
+		}
+	}
+	return result
+}
@@ -0,0 +1,322 @@
+package balances
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestSimplifyDebts(t *testing.T) {
+
+	tests := []struct {
+		name string
+		nets []string
+		want []string
+	}{
+		{
+			name: "no members",
+			nets: nil,
+			want: []string{},
+		},
+		{
+			name: "everyone settled",
+			nets: []string{"0", "0.00", "0"},
+			want: []string{},
+		},
+		{
+			name: "one debtor and one creditor",
+			nets: []string{"-25.50", "25.50"},
+			want: []string{"u00->u01 25.5"},
+		},
+		{
+			name: "one debtor pays two creditors",
+			nets: []string{"-30", "10", "20"},
+			want: []string{"u00->u02 20", "u00->u01 10"},
+		},
+		{
+			name: "independent pairs are settled apart",
+			nets: []string{"-8", "-2", "5", "3", "2"},
+			want: []string{"u00->u02 5", "u00->u03 3", "u01->u04 2"},
+		},
+		{
+			name: "equal amounts are broken by user id",
+			nets: []string{"10", "-10", "10", "-10"},
+			want: []string{"u01->u00 10", "u03->u02 10"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members := memberBalances(tt.nets)
+			got := formatDebts(SimplifyDebts(members))
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("SimplifyDebts() = %v, want %v", got, tt.want)
+			}
+			checkSettled(t, members, SimplifyDebts(members))
+		})
+	}
+}
+
+func TestSimplifyDebtsRoundsToCents(t *testing.T) {
+
+	tests := []struct {
+		name string
+		nets []string
+		want []string
+	}{
+		{
+			name: "less than half a cent is dropped",
+			nets: []string{"-0.004", "0.004"},
+			want: []string{},
+		},
+		{
+			name: "half a cent rounds away from zero",
+			nets: []string{"-10.005", "10.005"},
+			want: []string{"u00->u01 10.01"},
+		},
+		{
+			name: "shares of a division",
+			nets: []string{"-3.333333", "-3.333333", "6.666666"},
+			want: []string{"u00->u02 3.33", "u01->u02 3.33"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SimplifyDebts(memberBalances(tt.nets))
+			if fmt.Sprint(formatDebts(got)) != fmt.Sprint(tt.want) {
+				t.Errorf("SimplifyDebts() = %v, want %v", formatDebts(got), tt.want)
+			}
+			for _, debt := range got {
+				if debt.Amount.Exponent() < -2 {
+					t.Errorf("amount %s has more than two decimals", debt.Amount)
+				}
+			}
+		})
+	}
+}
+
+func TestSimplifyDebtsIsDeterministic(t *testing.T) {
+
+	random := rand.New(rand.NewSource(1))
+
+	for _, size := range []int{3, 6, 10, maxExactMembers + 4} {
+		members := memberBalances(randomNets(random, size))
+		want := fmt.Sprint(formatDebts(SimplifyDebts(members)))
+
+		for i := 0; i < 20; i++ {
+			shuffled := append([]*models.MemberBalance(nil), members...)
+			random.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+			if got := fmt.Sprint(formatDebts(SimplifyDebts(shuffled))); got != want {
+				t.Fatalf("%d members: order changed the transfers\n got: %s\nwant: %s", size, got, want)
+			}
+		}
+	}
+}
+
+func TestSimplifyDebtsIsMinimal(t *testing.T) {
+
+	random := rand.New(rand.NewSource(2))
+
+	for i := 0; i < 200; i++ {
+		size := 2 + random.Intn(7)
+		nets := randomNets(random, size)
+		// Small amounts so that zero sum subsets are common
+		if i%2 == 0 {
+			nets = smallNets(random, size)
+		}
+
+		members := memberBalances(nets)
+		transfers := SimplifyDebts(members)
+		checkSettled(t, members, transfers)
+
+		if want := bruteForceTransfers(members); len(transfers) != want {
+			t.Errorf("nets %v: %d transfers, the minimum is %d", nets, len(transfers), want)
+		}
+	}
+}
+
+func TestSimplifyDebtsFallsBackToGreedy(t *testing.T) {
+
+	random := rand.New(rand.NewSource(3))
+
+	for _, size := range []int{maxExactMembers + 1, 40} {
+		members := memberBalances(randomNets(random, size))
+
+		var positions []position
+		for _, m := range members {
+			positions = append(positions, position{member: m, cents: m.Net.Shift(2).IntPart()})
+		}
+
+		transfers := SimplifyDebts(members)
+		if got, want := fmt.Sprint(formatDebts(transfers)), fmt.Sprint(formatDebts(settleGreedy(positions))); got != want {
+			t.Errorf("%d members: got %s, want the greedy settlement %s", size, got, want)
+		}
+		if len(transfers) > size-1 {
+			t.Errorf("%d members: %d transfers, more than %d", size, len(transfers), size-1)
+		}
+		checkSettled(t, members, transfers)
+	}
+}
+
+func TestZeroSumGroups(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		cents []int64
+		want  int
+	}{
+		{name: "empty", cents: nil, want: 0},
+		{name: "one group", cents: []int64{-300, 100, 200}, want: 1},
+		{name: "two pairs", cents: []int64{-100, 100, -250, 250}, want: 2},
+		{name: "pair inside a larger group", cents: []int64{-800, -200, 500, 300, 200}, want: 2},
+		{name: "three groups", cents: []int64{-1, 1, -2, 2, -3, 1, 2}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var positions []position
+			for i, cents := range tt.cents {
+				positions = append(positions, position{member: &models.MemberBalance{UserId: userId(i)}, cents: cents})
+			}
+
+			groups := zeroSumGroups(positions)
+			if len(groups) != tt.want {
+				t.Fatalf("zeroSumGroups() returned %d groups, want %d", len(groups), tt.want)
+			}
+
+			seen := 0
+			for _, group := range groups {
+				var sum int64
+				for _, p := range group {
+					sum += p.cents
+				}
+				if sum != 0 {
+					t.Errorf("group %v adds up to %d", group, sum)
+				}
+				seen += len(group)
+			}
+			if seen != len(positions) {
+				t.Errorf("the groups have %d members, want %d", seen, len(positions))
+			}
+		})
+	}
+}
+
+// Aux Functions
+
+func userId(i int) []uint8 {
+
+	return []uint8(fmt.Sprintf("u%02d", i))
+}
+
+func memberBalances(nets []string) []*models.MemberBalance {
+
+	members := make([]*models.MemberBalance, len(nets))
+	for i, net := range nets {
+		members[i] = &models.MemberBalance{UserId: userId(i), Net: decimal.RequireFromString(net)}
+	}
+	return members
+}
+
+// randomNets returns balances in cents that add up to zero
+func randomNets(random *rand.Rand, size int) []string {
+
+	nets := make([]string, size)
+	var total int64
+	for i := 0; i < size-1; i++ {
+		cents := random.Int63n(20000) - 10000
+		total += cents
+		nets[i] = decimal.New(cents, -2).String()
+	}
+	nets[size-1] = decimal.New(-total, -2).String()
+	return nets
+}
+
+// smallNets returns whole balances between -5 and 5 that add up to zero
+func smallNets(random *rand.Rand, size int) []string {
+
+	nets := make([]string, size)
+	var total int64
+	for i := 0; i < size-1; i++ {
+		units := random.Int63n(11) - 5
+		total += units
+		nets[i] = decimal.NewFromInt(units).String()
+	}
+	nets[size-1] = decimal.NewFromInt(-total).String()
+	return nets
+}
+
+func formatDebts(debts []*models.Debt) []string {
+
+	result := []string{}
+	for _, debt := range debts {
+		result = append(result, fmt.Sprintf("%s->%s %s", debt.From, debt.To, debt.Amount))
+	}
+	return result
+}
+
+// checkSettled applies the transfers and checks that every balance ends at
+// zero, rounded to cents
+func checkSettled(t *testing.T, members []*models.MemberBalance, transfers []*models.Debt) {
+
+	t.Helper()
+
+	net := make(map[string]decimal.Decimal, len(members))
+	for _, m := range members {
+		net[string(m.UserId)] = m.Net.Round(2)
+	}
+
+	for _, transfer := range transfers {
+		if !transfer.Amount.IsPositive() {
+			t.Errorf("transfer %s->%s of %s is not positive", transfer.From, transfer.To, transfer.Amount)
+		}
+		net[string(transfer.From)] = net[string(transfer.From)].Add(transfer.Amount)
+		net[string(transfer.To)] = net[string(transfer.To)].Sub(transfer.Amount)
+	}
+
+	for user, balance := range net {
+		if !balance.IsZero() {
+			t.Errorf("%s ends with a balance of %s", user, balance)
+		}
+	}
+}
+
+// bruteForceTransfers tries every way of settling the first open balance
+// against a later one of the opposite sign and returns the fewest transfers
+func bruteForceTransfers(members []*models.MemberBalance) int {
+
+	var cents []int64
+	for _, m := range members {
+		if c := m.Net.Shift(2).Round(0).IntPart(); c != 0 {
+			cents = append(cents, c)
+		}
+	}
+
+	var search func(start int) int
+	search = func(start int) int {
+		for start < len(cents) && cents[start] == 0 {
+			start++
+		}
+		if start == len(cents) {
+			return 0
+		}
+
+		best := len(cents)
+		for i := start + 1; i < len(cents); i++ {
+			if cents[i]*cents[start] >= 0 {
+				continue
+			}
+			cents[i] += cents[start]
+			best = min(best, 1+search(start+1))
+			cents[i] -= cents[start]
+		}
+		return best
+	}
+
+	return search(0)
+}
@@ -0,0 +1,180 @@
+package budgets
+
+import (
+	"net/http"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/auth"
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/middlewares"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service        models.BudgetService
+	authRepository models.AuthRepository
+}
+
+func NewHandler(service models.BudgetService, authRepository models.AuthRepository) *Handler {
+	return &Handler{service: service, authRepository: authRepository}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+
+	router.HandleFunc("/group/{groupId}/budgets", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetBudgets, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}/budgets/alerts", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetAlerts, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}/budgets/alerts/{alertId}/delivered", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleAlertDelivered, models.PermissionViewGroup, h.authRepository))).Methods("POST")
+
+	// Admin routes
+	router.HandleFunc("/group/{groupId}/budgets", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleBudgetCreate, models.PermissionEditGroup, h.authRepository))).Methods("POST")
+	router.HandleFunc("/group/{groupId}/budgets/{budgetId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleBudgetUpdate, models.PermissionEditGroup, h.authRepository))).Methods("PUT")
+	router.HandleFunc("/group/{groupId}/budgets/{budgetId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleBudgetDelete, models.PermissionEditGroup, h.authRepository))).Methods("DELETE")
+}
+
+func (h *Handler) handleBudgetCreate(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	var payload models.CreateBudgetPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	budget, err := h.service.CreateBudget(payload, groupId, userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, budget)
+}
+
+func (h *Handler) handleGetBudgets(w http.ResponseWriter, r *http.Request) {
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	budgets, err := h.service.GetGroupBudgets(groupId)
+	if err == errors.ErrGroupNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, budgets)
+}
+
+func (h *Handler) handleGetAlerts(w http.ResponseWriter, r *http.Request) {
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	var alerts []*models.BudgetAlert
+	var err error
+	if r.URL.Query().Get("pending") == "true" {
+		alerts, err = h.service.GetPendingAlerts(groupId)
+	} else {
+		alerts, err = h.service.GetGroupAlerts(groupId)
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, alerts)
+}
+
+// handleAlertDelivered is called by the notification layer once it delivered
+// the alert, so it is not returned by ?pending=true again
+func (h *Handler) handleAlertDelivered(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	alertId := []uint8(vars["alertId"])
+
+	err := h.service.MarkAlertDelivered(groupId, alertId)
+	if err == errors.ErrBudgetAlertNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Budget alert marked as delivered",
+	})
+}
+
+func (h *Handler) handleBudgetUpdate(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	budgetId := []uint8(vars["budgetId"])
+
+	var payload models.UpdateBudgetPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	err = h.service.UpdateBudget(payload, groupId, budgetId, userId)
+	if err == errors.ErrBudgetNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Budget updated successfully",
+	})
+}
+
+func (h *Handler) handleBudgetDelete(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	budgetId := []uint8(vars["budgetId"])
+
+	err := h.service.DeleteBudget(groupId, budgetId)
+	if err == errors.ErrBudgetNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Budget deleted successfully",
+	})
+}
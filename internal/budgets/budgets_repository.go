@@ -0,0 +1,280 @@
+package budgets
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// Postgres SQL Repository
+type SQLRepository struct {
+	db *sql.DB
+}
+
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+const budgetColumns = `budget_id, group_id, category_id, amount, period, start_date, end_date, thresholds, created_at, created_by, updated_at, updated_by`
+
+const alertColumns = `a.budget_alert_id, a.budget_id, b.group_id, b.category_id, a.threshold, a.period_start, a.period_end, a.amount, a.spent, a.created_at, a.delivered_at`
+
+func (s *SQLRepository) CreateBudget(budget models.Budget) ([]uint8, error) {
+
+	var budgetId []uint8
+	err := s.db.QueryRow(
+		`INSERT INTO public.budget (group_id, category_id, amount, period, start_date, end_date, thresholds, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING budget_id`,
+		budget.GroupId, nullableId(budget.CategoryId), budget.Amount, budget.Period, budget.StartDate, budget.EndDate, pq.Array(budget.Thresholds), budget.CreatedBy, budget.UpdatedBy,
+	).Scan(&budgetId)
+
+	if err != nil {
+		return nil, fmt.Errorf("error al crear el presupuesto: %w", err)
+	}
+
+	return budgetId, nil
+}
+
+func (s *SQLRepository) GetBudgetById(groupId []uint8, budgetId []uint8) (*models.Budget, error) {
+
+	row := s.db.QueryRow("SELECT "+budgetColumns+" FROM public.budget WHERE group_id = $1 AND budget_id = $2", groupId, budgetId)
+	return scanRowIntoBudget(row)
+}
+
+func (s *SQLRepository) GetGroupBudgets(groupId []uint8) ([]*models.Budget, error) {
+
+	rows, err := s.db.Query("SELECT "+budgetColumns+" FROM public.budget WHERE group_id = $1 ORDER BY created_at", groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los presupuestos del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var budgets []*models.Budget
+	for rows.Next() {
+		budget, err := scanRowIntoBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+
+	return budgets, rows.Err()
+}
+
+func (s *SQLRepository) UpdateBudget(budget models.Budget) error {
+
+	res, err := s.db.Exec(
+		`UPDATE public.budget
+		SET category_id = $1, amount = $2, period = $3, start_date = $4, end_date = $5, thresholds = $6, updated_by = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE group_id = $8 AND budget_id = $9`,
+		nullableId(budget.CategoryId), budget.Amount, budget.Period, budget.StartDate, budget.EndDate, pq.Array(budget.Thresholds), budget.UpdatedBy, budget.GroupId, budget.BudgetId,
+	)
+	if err != nil {
+		return fmt.Errorf("error al actualizar el presupuesto: %w", err)
+	}
+
+	return checkAffected(res)
+}
+
+func (s *SQLRepository) DeleteBudget(groupId []uint8, budgetId []uint8) error {
+
+	res, err := s.db.Exec("DELETE FROM public.budget WHERE group_id = $1 AND budget_id = $2", groupId, budgetId)
+	if err != nil {
+		return fmt.Errorf("error al eliminar el presupuesto: %w", err)
+	}
+
+	return checkAffected(res)
+}
+
+// Devuelve los movimientos del periodo, de la categoria si se indica una
+func (s *SQLRepository) GetSpending(groupId []uint8, categoryId []uint8, from time.Time, to time.Time) ([]*models.SpendingEntry, error) {
+
+	rows, err := s.db.Query(`
+		SELECT amount, currency, movement_date
+		FROM public.movement
+		WHERE group_id = $1
+		AND movement_date BETWEEN $2 AND $3
+		AND ($4::uuid IS NULL OR category_id = $4::uuid)`,
+		groupId, from, to, nullableId(categoryId))
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los gastos del presupuesto: %w", err)
+	}
+	defer rows.Close()
+
+	var spending []*models.SpendingEntry
+	for rows.Next() {
+		entry := new(models.SpendingEntry)
+		if err := rows.Scan(&entry.Amount, &entry.Currency, &entry.Date); err != nil {
+			return nil, err
+		}
+		spending = append(spending, entry)
+	}
+
+	return spending, rows.Err()
+}
+
+func (s *SQLRepository) GetBaseCurrency(groupId []uint8) (string, error) {
+
+	var currency string
+	err := s.db.QueryRow("SELECT base_currency FROM public.\"group\" WHERE group_id = $1", groupId).Scan(&currency)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.ErrGroupNotFound
+		}
+		return "", err
+	}
+
+	return currency, nil
+}
+
+// Registra la alerta si todavia no existe para el umbral y periodo. Devuelve
+// false cuando ya habia sido generada
+func (s *SQLRepository) CreateAlert(alert models.BudgetAlert) (bool, error) {
+
+	res, err := s.db.Exec(
+		`INSERT INTO public.budget_alert (budget_id, threshold, period_start, period_end, amount, spent)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (budget_id, threshold, period_start) DO NOTHING`,
+		alert.BudgetId, alert.Threshold, alert.PeriodStart, alert.PeriodEnd, alert.Amount, alert.Spent,
+	)
+	if err != nil {
+		return false, fmt.Errorf("error al crear la alerta del presupuesto: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (s *SQLRepository) GetGroupAlerts(groupId []uint8) ([]*models.BudgetAlert, error) {
+
+	return s.queryAlerts("b.group_id = $1", groupId)
+}
+
+func (s *SQLRepository) GetPendingAlerts(groupId []uint8) ([]*models.BudgetAlert, error) {
+
+	return s.queryAlerts("b.group_id = $1 AND a.delivered_at IS NULL", groupId)
+}
+
+// Marca la alerta del grupo como entregada. Si ya lo estaba conserva la fecha
+// de la primera entrega
+func (s *SQLRepository) MarkAlertDelivered(groupId []uint8, alertId []uint8) error {
+
+	res, err := s.db.Exec(
+		`UPDATE public.budget_alert a
+		SET delivered_at = COALESCE(a.delivered_at, CURRENT_TIMESTAMP)
+		FROM public.budget b
+		WHERE b.budget_id = a.budget_id AND b.group_id = $1 AND a.budget_alert_id = $2`,
+		groupId, alertId,
+	)
+	if err != nil {
+		return fmt.Errorf("error al marcar la alerta como entregada: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrBudgetAlertNotFound
+	}
+
+	return nil
+}
+
+func (s *SQLRepository) queryAlerts(condition string, args ...any) ([]*models.BudgetAlert, error) {
+
+	rows, err := s.db.Query(`
+		SELECT `+alertColumns+`
+		FROM public.budget_alert a
+		INNER JOIN public.budget b ON b.budget_id = a.budget_id
+		WHERE `+condition+`
+		ORDER BY a.created_at DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las alertas de presupuesto: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []*models.BudgetAlert
+	for rows.Next() {
+		alert := new(models.BudgetAlert)
+		err := rows.Scan(
+			&alert.BudgetAlertId,
+			&alert.BudgetId,
+			&alert.GroupId,
+			&alert.CategoryId,
+			&alert.Threshold,
+			&alert.PeriodStart,
+			&alert.PeriodEnd,
+			&alert.Amount,
+			&alert.Spent,
+			&alert.CreatedAt,
+			&alert.DeliveredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRowIntoBudget(row rowScanner) (*models.Budget, error) {
+
+	budget := new(models.Budget)
+	err := row.Scan(
+		&budget.BudgetId,
+		&budget.GroupId,
+		&budget.CategoryId,
+		&budget.Amount,
+		&budget.Period,
+		&budget.StartDate,
+		&budget.EndDate,
+		pq.Array(&budget.Thresholds),
+		&budget.CreatedAt,
+		&budget.CreatedBy,
+		&budget.UpdatedAt,
+		&budget.UpdatedBy,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrBudgetNotFound
+		}
+		return nil, errors.ErrBudgetScan(err.Error())
+	}
+	return budget, nil
+}
+
+// nullableId stores an empty id as NULL instead of an empty string
+func nullableId(id []uint8) any {
+
+	if len(id) == 0 {
+		return nil
+	}
+	return id
+}
+
+func checkAffected(res sql.Result) error {
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrBudgetNotFound
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package budgets
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+	"github.com/shopspring/decimal"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+type Service struct {
+	repository         models.BudgetRepository
+	categoryRepository models.CategoryRepository
+	rateService        models.ExchangeRateService
+}
+
+func NewService(repository models.BudgetRepository, categoryRepository models.CategoryRepository, rateService models.ExchangeRateService) *Service {
+	return &Service{repository: repository, categoryRepository: categoryRepository, rateService: rateService}
+}
+
+func (s *Service) CreateBudget(payload models.CreateBudgetPayload, groupId []uint8, userId []uint8) (*models.Budget, error) {
+
+	budget, err := s.buildBudget(groupId, payload.CategoryId, payload.Amount, payload.Period, payload.StartDate, payload.EndDate, payload.Thresholds)
+	if err != nil {
+		return nil, err
+	}
+	budget.CreatedBy = userId
+	budget.UpdatedBy = userId
+
+	budgetId, err := s.repository.CreateBudget(*budget)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.GetBudgetById(groupId, budgetId)
+}
+
+// GetGroupBudgets returns every budget with its spending in the current
+// period: this month for monthly budgets and the whole range for custom ones.
+func (s *Service) GetGroupBudgets(groupId []uint8) ([]*models.BudgetStatus, error) {
+
+	budgets, err := s.repository.GetGroupBudgets(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := s.repository.GetBaseCurrency(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	today := time.Now().UTC()
+	statuses := make([]*models.BudgetStatus, 0, len(budgets))
+
+	for _, budget := range budgets {
+		start, end := Period(budget, today)
+		status, err := s.getStatus(budget, currency, start, end)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func (s *Service) UpdateBudget(payload models.UpdateBudgetPayload, groupId []uint8, budgetId []uint8, userId []uint8) error {
+
+	budget, err := s.buildBudget(groupId, payload.CategoryId, payload.Amount, payload.Period, payload.StartDate, payload.EndDate, payload.Thresholds)
+	if err != nil {
+		return err
+	}
+	budget.BudgetId = budgetId
+	budget.UpdatedBy = userId
+
+	return s.repository.UpdateBudget(*budget)
+}
+
+func (s *Service) DeleteBudget(groupId []uint8, budgetId []uint8) error {
+
+	return s.repository.DeleteBudget(groupId, budgetId)
+}
+
+func (s *Service) GetGroupAlerts(groupId []uint8) ([]*models.BudgetAlert, error) {
+
+	return s.repository.GetGroupAlerts(groupId)
+}
+
+// GetPendingAlerts returns the alerts of the group that the notification layer
+// has not delivered yet
+func (s *Service) GetPendingAlerts(groupId []uint8) ([]*models.BudgetAlert, error) {
+
+	return s.repository.GetPendingAlerts(groupId)
+}
+
+func (s *Service) MarkAlertDelivered(groupId []uint8, alertId []uint8) error {
+
+	return s.repository.MarkAlertDelivered(groupId, alertId)
+}
+
+// CheckBudgets recomputes the budgets affected by a movement of the category
+// on date and records an alert for every threshold reached for the first time
+// in the period. It returns the new alerts.
+func (s *Service) CheckBudgets(groupId []uint8, categoryId []uint8, date time.Time) ([]*models.BudgetAlert, error) {
+
+	budgets, err := s.repository.GetGroupBudgets(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := s.repository.GetBaseCurrency(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []*models.BudgetAlert
+
+	for _, budget := range budgets {
+		if len(budget.CategoryId) > 0 && string(budget.CategoryId) != string(categoryId) {
+			continue
+		}
+
+		start, end := Period(budget, date)
+		if date.Before(start) || date.After(end) {
+			continue
+		}
+
+		status, err := s.getStatus(budget, currency, start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, threshold := range budget.Thresholds {
+			if status.PercentUsed.LessThan(decimal.NewFromInt(threshold)) {
+				continue
+			}
+
+			alert := models.BudgetAlert{
+				BudgetId:    budget.BudgetId,
+				GroupId:     budget.GroupId,
+				CategoryId:  budget.CategoryId,
+				Threshold:   threshold,
+				PeriodStart: start,
+				PeriodEnd:   end,
+				Amount:      budget.Amount,
+				Spent:       status.Spent,
+			}
+
+			created, err := s.repository.CreateAlert(alert)
+			if err != nil {
+				return nil, err
+			}
+			if created {
+				log.Printf("budget %s reached %d%% (%s of %s %s)", budget.BudgetId, threshold, status.Spent, budget.Amount, currency)
+				alerts = append(alerts, &alert)
+			}
+		}
+	}
+
+	return alerts, nil
+}
+
+// Period returns the first and last day of the period of the budget that
+// contains date. Custom budgets only have one period.
+func Period(budget *models.Budget, date time.Time) (time.Time, time.Time) {
+
+	if budget.Period == models.BudgetPeriodCustom && budget.StartDate != nil && budget.EndDate != nil {
+		return *budget.StartDate, *budget.EndDate
+	}
+
+	start := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, -1)
+}
+
+// Aux Functions
+
+func (s *Service) getStatus(budget *models.Budget, currency string, start time.Time, end time.Time) (*models.BudgetStatus, error) {
+
+	spending, err := s.repository.GetSpending(budget.GroupId, budget.CategoryId, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	spent := decimal.Zero
+	for _, entry := range spending {
+		amount := entry.Amount
+		if entry.Currency != currency {
+			amount, err = s.rateService.Convert(entry.Amount, entry.Currency, currency, entry.Date)
+			if err != nil {
+				return nil, err
+			}
+		}
+		spent = spent.Add(amount)
+	}
+
+	return &models.BudgetStatus{
+		Budget:      budget,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Currency:    currency,
+		Spent:       spent,
+		Remaining:   budget.Amount.Sub(spent),
+		PercentUsed: spent.Mul(hundred).Div(budget.Amount).Round(2),
+	}, nil
+}
+
+func (s *Service) buildBudget(groupId []uint8, categoryId string, amount decimal.Decimal, period string, startDate string, endDate string, thresholds []int64) (*models.Budget, error) {
+
+	if err := utils.ValidateAmount(amount); err != nil {
+		return nil, err
+	}
+
+	budget := &models.Budget{
+		GroupId:    groupId,
+		Amount:     amount,
+		Period:     period,
+		Thresholds: thresholds,
+	}
+
+	if categoryId != "" {
+		category, err := s.categoryRepository.GetCategoryById(groupId, []uint8(strings.ToLower(categoryId)))
+		if err == errors.ErrCategoryNotFound {
+			return nil, errors.ErrInvalidaPayload(fmt.Sprintf("category %s does not belong to the group", categoryId))
+		} else if err != nil {
+			return nil, err
+		}
+		budget.CategoryId = category.CategoryId
+	}
+
+	if period == models.BudgetPeriodCustom {
+		start, err := time.Parse(time.DateOnly, startDate)
+		if err != nil {
+			return nil, errors.ErrInvalidaPayload("startDate must have the format YYYY-MM-DD")
+		}
+		end, err := time.Parse(time.DateOnly, endDate)
+		if err != nil {
+			return nil, errors.ErrInvalidaPayload("endDate must have the format YYYY-MM-DD")
+		}
+		if end.Before(start) {
+			return nil, errors.ErrInvalidaPayload("endDate can not be before startDate")
+		}
+		budget.StartDate = &start
+		budget.EndDate = &end
+	}
+
+	if len(budget.Thresholds) == 0 {
+		budget.Thresholds = append([]int64(nil), models.DefaultBudgetThresholds...)
+	}
+	sort.Slice(budget.Thresholds, func(i, j int) bool { return budget.Thresholds[i] < budget.Thresholds[j] })
+
+	return budget, nil
+}
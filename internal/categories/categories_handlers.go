@@ -0,0 +1,165 @@
+package categories
+
+import (
+	"net/http"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/auth"
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/middlewares"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service        models.CategoryService
+	authRepository models.AuthRepository
+}
+
+func NewHandler(service models.CategoryService, authRepository models.AuthRepository) *Handler {
+	return &Handler{service: service, authRepository: authRepository}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+
+	router.HandleFunc("/group/{groupId}/category", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetCategories, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}/category/{categoryId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetCategory, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+
+	// Admin routes
+	router.HandleFunc("/group/{groupId}/category", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleCategoryCreate, models.PermissionManageFields, h.authRepository))).Methods("POST")
+	router.HandleFunc("/group/{groupId}/category/{categoryId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleCategoryUpdate, models.PermissionManageFields, h.authRepository))).Methods("PUT")
+	router.HandleFunc("/group/{groupId}/category/{categoryId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleCategoryDelete, models.PermissionManageFields, h.authRepository))).Methods("DELETE")
+}
+
+func (h *Handler) handleCategoryCreate(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	var payload models.CreateCategoryPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	category, err := h.service.CreateCategory(payload, groupId, userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, category)
+}
+
+func (h *Handler) handleGetCategories(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	categories, err := h.service.GetGroupCategories(groupId, userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, categories)
+}
+
+func (h *Handler) handleGetCategory(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	categoryId := []uint8(vars["categoryId"])
+
+	category, err := h.service.GetCategoryById(groupId, categoryId, userId)
+	if err == errors.ErrCategoryNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, category)
+}
+
+func (h *Handler) handleCategoryUpdate(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	categoryId := []uint8(vars["categoryId"])
+
+	var payload models.UpdateCategoryPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	err = h.service.UpdateCategory(payload, groupId, categoryId, userId)
+	if err == errors.ErrCategoryNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Category updated successfully",
+	})
+}
+
+func (h *Handler) handleCategoryDelete(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	categoryId := []uint8(vars["categoryId"])
+
+	err := h.service.DeleteCategory(groupId, categoryId)
+	if err == errors.ErrCategoryNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Category deleted successfully",
+	})
+}
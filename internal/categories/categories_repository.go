@@ -0,0 +1,240 @@
+package categories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+// Postgres SQL Repository
+type SQLRepository struct {
+	db *sql.DB
+}
+
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+const categoryColumns = `c.category_id, c.group_id, c.code, c.created_at, c.created_by, c.updated_at, c.updated_by`
+
+func (s *SQLRepository) CreateCategory(category models.Category) ([]uint8, error) {
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var categoryId []uint8
+	err = tx.QueryRow(
+		"INSERT INTO public.category (group_id, created_by, updated_by) VALUES ($1, $2, $3) RETURNING category_id",
+		category.GroupId, category.CreatedBy, category.UpdatedBy,
+	).Scan(&categoryId)
+	if err != nil {
+		return nil, fmt.Errorf("error al crear la categoria: %w", err)
+	}
+
+	if err := insertNames(tx, categoryId, category.Names); err != nil {
+		return nil, err
+	}
+
+	return categoryId, tx.Commit()
+}
+
+func (s *SQLRepository) GetCategoryById(groupId []uint8, categoryId []uint8) (*models.Category, error) {
+
+	row := s.db.QueryRow("SELECT "+categoryColumns+" FROM public.category c WHERE c.group_id = $1 AND c.category_id = $2", groupId, categoryId)
+	category, err := scanRowIntoCategory(row)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := s.getNames("n.category_id = $1", category.CategoryId)
+	if err != nil {
+		return nil, err
+	}
+	category.Names = names[string(category.CategoryId)]
+
+	return category, nil
+}
+
+func (s *SQLRepository) GetGroupCategories(groupId []uint8) ([]*models.Category, error) {
+
+	rows, err := s.db.Query("SELECT "+categoryColumns+" FROM public.category c WHERE c.group_id = $1 ORDER BY c.created_at, c.code", groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las categorias del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []*models.Category
+	for rows.Next() {
+		category, err := scanRowIntoCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	names, err := s.getNames("c.group_id = $1", groupId)
+	if err != nil {
+		return nil, err
+	}
+	for _, category := range categories {
+		category.Names = names[string(category.CategoryId)]
+	}
+
+	return categories, nil
+}
+
+func (s *SQLRepository) UpdateCategory(category models.Category) error {
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		`UPDATE public.category
+		SET updated_by = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE group_id = $2 AND category_id = $3`,
+		category.UpdatedBy, category.GroupId, category.CategoryId,
+	)
+	if err != nil {
+		return fmt.Errorf("error al actualizar la categoria: %w", err)
+	}
+	if err := checkAffected(res); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM public.category_name WHERE category_id = $1", category.CategoryId); err != nil {
+		return fmt.Errorf("error al actualizar los nombres de la categoria: %w", err)
+	}
+
+	if err := insertNames(tx, category.CategoryId, category.Names); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Los movimientos de la categoria quedan sin categoria
+func (s *SQLRepository) DeleteCategory(groupId []uint8, categoryId []uint8) error {
+
+	res, err := s.db.Exec("DELETE FROM public.category WHERE group_id = $1 AND category_id = $2", groupId, categoryId)
+	if err != nil {
+		return fmt.Errorf("error al eliminar la categoria: %w", err)
+	}
+
+	return checkAffected(res)
+}
+
+func (s *SQLRepository) GetLanguages() ([]string, error) {
+
+	rows, err := s.db.Query("SELECT code FROM conf.language ORDER BY code")
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los idiomas: %w", err)
+	}
+	defer rows.Close()
+
+	var languages []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		languages = append(languages, code)
+	}
+
+	return languages, rows.Err()
+}
+
+// getNames returns the translated names of the categories matching the
+// condition, grouped by category id and keyed by language.
+func (s *SQLRepository) getNames(condition string, arg any) (map[string]map[string]string, error) {
+
+	rows, err := s.db.Query(`
+		SELECT n.category_id, n.language_code, n.name
+		FROM public.category_name n
+		INNER JOIN public.category c ON c.category_id = n.category_id
+		WHERE `+condition, arg)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los nombres de las categorias: %w", err)
+	}
+	defer rows.Close()
+
+	names := make(map[string]map[string]string)
+	for rows.Next() {
+		var categoryId []uint8
+		var language, name string
+		if err := rows.Scan(&categoryId, &language, &name); err != nil {
+			return nil, err
+		}
+		if names[string(categoryId)] == nil {
+			names[string(categoryId)] = make(map[string]string)
+		}
+		names[string(categoryId)][language] = name
+	}
+
+	return names, rows.Err()
+}
+
+func insertNames(tx *sql.Tx, categoryId []uint8, names map[string]string) error {
+
+	for language, name := range names {
+		_, err := tx.Exec(
+			"INSERT INTO public.category_name (category_id, language_code, name) VALUES ($1, $2, $3)",
+			categoryId, language, name,
+		)
+		if err != nil {
+			return fmt.Errorf("error al guardar el nombre de la categoria en %s: %w", language, err)
+		}
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRowIntoCategory(row rowScanner) (*models.Category, error) {
+
+	category := new(models.Category)
+	var code sql.NullString
+	err := row.Scan(
+		&category.CategoryId,
+		&category.GroupId,
+		&code,
+		&category.CreatedAt,
+		&category.CreatedBy,
+		&category.UpdatedAt,
+		&category.UpdatedBy,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrCategoryNotFound
+		}
+		return nil, errors.ErrCategoryScan(err.Error())
+	}
+	category.Code = code.String
+	return category, nil
+}
+
+func checkAffected(res sql.Result) error {
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrCategoryNotFound
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package categories
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+type Service struct {
+	repository     models.CategoryRepository
+	userRepository models.UserRepository
+}
+
+func NewService(repository models.CategoryRepository, userRepository models.UserRepository) *Service {
+	return &Service{repository: repository, userRepository: userRepository}
+}
+
+func (s *Service) CreateCategory(payload models.CreateCategoryPayload, groupId []uint8, userId []uint8) (*models.Category, error) {
+
+	names, err := s.validateNames(groupId, nil, payload.Names)
+	if err != nil {
+		return nil, err
+	}
+
+	category := models.Category{
+		GroupId:   groupId,
+		Names:     names,
+		CreatedBy: userId,
+		UpdatedBy: userId,
+	}
+
+	categoryId, err := s.repository.CreateCategory(category)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCategoryById(groupId, categoryId, userId)
+}
+
+func (s *Service) GetCategoryById(groupId []uint8, categoryId []uint8, userId []uint8) (*models.Category, error) {
+
+	category, err := s.repository.GetCategoryById(groupId, categoryId)
+	if err != nil {
+		return nil, err
+	}
+
+	language, err := UserLanguage(s.userRepository, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	category.Name, category.Language = Localize(category.Names, language)
+	return category, nil
+}
+
+func (s *Service) GetGroupCategories(groupId []uint8, userId []uint8) ([]*models.Category, error) {
+
+	categories, err := s.repository.GetGroupCategories(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	language, err := UserLanguage(s.userRepository, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, category := range categories {
+		category.Name, category.Language = Localize(category.Names, language)
+	}
+
+	return categories, nil
+}
+
+func (s *Service) UpdateCategory(payload models.UpdateCategoryPayload, groupId []uint8, categoryId []uint8, userId []uint8) error {
+
+	names, err := s.validateNames(groupId, categoryId, payload.Names)
+	if err != nil {
+		return err
+	}
+
+	category := models.Category{
+		CategoryId: categoryId,
+		GroupId:    groupId,
+		Names:      names,
+		UpdatedBy:  userId,
+	}
+
+	return s.repository.UpdateCategory(category)
+}
+
+func (s *Service) DeleteCategory(groupId []uint8, categoryId []uint8) error {
+
+	return s.repository.DeleteCategory(groupId, categoryId)
+}
+
+// Localize picks the name of a category in language, then in the default
+// language and then in the first language available in alphabetical order.
+// It returns the name and the language it is written in.
+func Localize(names map[string]string, language string) (string, string) {
+
+	if name, ok := names[language]; ok {
+		return name, language
+	}
+	if name, ok := names[models.DefaultLanguage]; ok {
+		return name, models.DefaultLanguage
+	}
+
+	languages := make([]string, 0, len(names))
+	for code := range names {
+		languages = append(languages, code)
+	}
+	if len(languages) == 0 {
+		return "", ""
+	}
+	sort.Strings(languages)
+
+	return names[languages[0]], languages[0]
+}
+
+// UserLanguage returns the language of the user, or the default language
+// when the user has not chosen one
+func UserLanguage(userRepository models.UserRepository, userId []uint8) (string, error) {
+
+	user, err := userRepository.GetUserById(userId)
+	if err != nil {
+		return "", err
+	}
+	if user.LanguageCode == "" {
+		return models.DefaultLanguage, nil
+	}
+	return user.LanguageCode, nil
+}
+
+// Aux Functions
+
+// validateNames normalizes the translations and checks that every language
+// exists and that no other category of the group has the same name in it.
+func (s *Service) validateNames(groupId []uint8, categoryId []uint8, payload map[string]string) (map[string]string, error) {
+
+	languages, err := s.repository.GetLanguages()
+	if err != nil {
+		return nil, err
+	}
+	available := make(map[string]bool, len(languages))
+	for _, code := range languages {
+		available[code] = true
+	}
+
+	names := make(map[string]string, len(payload))
+	for language, name := range payload {
+		language = strings.ToLower(strings.TrimSpace(language))
+		name = strings.TrimSpace(name)
+		if !available[language] {
+			return nil, errors.ErrInvalidaPayload(fmt.Sprintf("language %s is not available", language))
+		}
+		if name == "" {
+			return nil, errors.ErrInvalidaPayload(fmt.Sprintf("the name in %s can not be empty", language))
+		}
+		names[language] = name
+	}
+
+	existing, err := s.repository.GetGroupCategories(groupId)
+	if err != nil {
+		return nil, err
+	}
+	for _, category := range existing {
+		if string(category.CategoryId) == string(categoryId) {
+			continue
+		}
+		for language, name := range names {
+			if strings.EqualFold(category.Names[language], name) {
+				return nil, errors.ErrCategoryAlreadyExist(name)
+			}
+		}
+	}
+
+	return names, nil
+}
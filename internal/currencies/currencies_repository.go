@@ -0,0 +1,71 @@
+package currencies
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+// Postgres SQL Repository
+type SQLRepository struct {
+	db *sql.DB
+}
+
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+// Guarda las cotizaciones en una transaccion, reemplazando las ya cargadas
+// para la misma fecha y par de monedas
+func (s *SQLRepository) SaveExchangeRates(rates []models.ExchangeRate) (int64, error) {
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO conf.exchange_rate (rate_date, base_currency, quote_currency, rate)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (rate_date, base_currency, quote_currency) DO UPDATE SET rate = EXCLUDED.rate`)
+	if err != nil {
+		return 0, fmt.Errorf("error al guardar las cotizaciones: %w", err)
+	}
+	defer stmt.Close()
+
+	var saved int64
+	for _, rate := range rates {
+		if _, err := stmt.Exec(rate.RateDate, rate.BaseCurrency, rate.QuoteCurrency, rate.Rate); err != nil {
+			return 0, fmt.Errorf("error al guardar la cotizacion %s/%s: %w", rate.BaseCurrency, rate.QuoteCurrency, err)
+		}
+		saved++
+	}
+
+	return saved, tx.Commit()
+}
+
+// Devuelve la ultima cotizacion publicada hasta la fecha indicada
+func (s *SQLRepository) GetExchangeRate(baseCurrency string, quoteCurrency string, date time.Time) (*models.ExchangeRate, error) {
+
+	rate := new(models.ExchangeRate)
+	err := s.db.QueryRow(`
+		SELECT rate_date, base_currency, quote_currency, rate
+		FROM conf.exchange_rate
+		WHERE base_currency = $1 AND quote_currency = $2 AND rate_date <= $3
+		ORDER BY rate_date DESC
+		LIMIT 1`, baseCurrency, quoteCurrency, date,
+	).Scan(&rate.RateDate, &rate.BaseCurrency, &rate.QuoteCurrency, &rate.Rate)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrExchangeRateNotFound
+		}
+		return nil, fmt.Errorf("error al obtener la cotizacion: %w", err)
+	}
+
+	return rate, nil
+}
@@ -0,0 +1,91 @@
+package currencies
+
+import (
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+type Service struct {
+	repository models.ExchangeRateRepository
+}
+
+func NewService(repository models.ExchangeRateRepository) *Service {
+	return &Service{repository: repository}
+}
+
+// LoadFile reads a CSV or ECB XML file of exchange rates and saves them
+func (s *Service) LoadFile(path string) (int64, error) {
+
+	rates, err := ParseFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.repository.SaveExchangeRates(rates)
+}
+
+// GetRate returns how many units of to are worth one unit of from, using the
+// last rate published up to date. When the pair was not loaded it tries the
+// inverse pair and then a cross rate through the pivot currency. Reports look
+// rates up in SQL with the same steps, see rateSQL in reports_repository.go.
+func (s *Service) GetRate(from string, to string, date time.Time) (decimal.Decimal, error) {
+
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+
+	rate, err := s.pairRate(from, to, date)
+	if err != errors.ErrExchangeRateNotFound {
+		return rate, err
+	}
+
+	if from != models.PivotCurrency && to != models.PivotCurrency {
+		toPivot, err := s.pairRate(from, models.PivotCurrency, date)
+		if err != nil && err != errors.ErrExchangeRateNotFound {
+			return decimal.Zero, err
+		}
+		fromPivot, err2 := s.pairRate(models.PivotCurrency, to, date)
+		if err2 != nil && err2 != errors.ErrExchangeRateNotFound {
+			return decimal.Zero, err2
+		}
+		if err == nil && err2 == nil {
+			return toPivot.Mul(fromPivot), nil
+		}
+	}
+
+	return decimal.Zero, errors.ErrMissingExchangeRate(from, to, date.Format(time.DateOnly))
+}
+
+// Convert returns amount expressed in to, rounded to cents
+func (s *Service) Convert(amount decimal.Decimal, from string, to string, date time.Time) (decimal.Decimal, error) {
+
+	rate, err := s.GetRate(from, to, date)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	return amount.Mul(rate).Round(2), nil
+}
+
+// Aux Functions
+
+func (s *Service) pairRate(from string, to string, date time.Time) (decimal.Decimal, error) {
+
+	rate, err := s.repository.GetExchangeRate(from, to, date)
+	if err == nil {
+		return rate.Rate, nil
+	}
+	if err != errors.ErrExchangeRateNotFound {
+		return decimal.Zero, err
+	}
+
+	inverse, err := s.repository.GetExchangeRate(to, from, date)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	return decimal.NewFromInt(1).Div(inverse.Rate), nil
+}
@@ -0,0 +1,205 @@
+package currencies
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Date layouts used by the ECB files and by our own CSV format
+var dateLayouts = []string{time.DateOnly, "02 January 2006"}
+
+// ParseFile reads exchange rates from a local file. XML files must follow the
+// ECB eurofxref format; CSV files can be either the ECB format (a Date column
+// followed by one column per currency, all against the euro) or one rate per
+// row with date, base, quote and rate columns.
+func ParseFile(path string) ([]models.ExchangeRate, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	head, err := reader.Peek(1)
+	if err != nil {
+		return nil, errors.ErrExchangeRateFile("the file is empty")
+	}
+
+	if head[0] == '<' || strings.EqualFold(filepath.Ext(path), ".xml") {
+		return ParseECBXML(reader)
+	}
+	return ParseCSV(reader)
+}
+
+// ParseECBXML reads the ECB eurofxref XML files (daily, 90 days or history)
+func ParseECBXML(r io.Reader) ([]models.ExchangeRate, error) {
+
+	var envelope struct {
+		Days []struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string `xml:"currency,attr"`
+				Rate     string `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube>Cube"`
+	}
+
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, errors.ErrExchangeRateFile(err.Error())
+	}
+
+	var rates []models.ExchangeRate
+	for _, day := range envelope.Days {
+		for _, r := range day.Rates {
+			rate, err := newRate(day.Time, models.PivotCurrency, r.Currency, r.Rate)
+			if err != nil {
+				return nil, errors.ErrExchangeRateFile(err.Error())
+			}
+			rates = append(rates, rate)
+		}
+	}
+
+	if len(rates) == 0 {
+		return nil, errors.ErrExchangeRateFile("no rates found")
+	}
+
+	return rates, nil
+}
+
+// ParseCSV reads rates in the ECB CSV format or in the date,base,quote,rate
+// format, detected from the header.
+func ParseCSV(r io.Reader) ([]models.ExchangeRate, error) {
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.ErrExchangeRateFile(err.Error())
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	long := true
+	for _, name := range []string{"date", "base", "quote", "rate"} {
+		if _, ok := columns[name]; !ok {
+			long = false
+		}
+	}
+	if _, ok := columns["date"]; !ok {
+		return nil, errors.ErrExchangeRateFile("the header must have a date column")
+	}
+
+	var rates []models.ExchangeRate
+	line := 1
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.ErrExchangeRateFile(err.Error())
+		}
+		line++
+
+		if long {
+			rate, err := newRate(field(record, columns["date"]), field(record, columns["base"]), field(record, columns["quote"]), field(record, columns["rate"]))
+			if err != nil {
+				return nil, errors.ErrExchangeRateFile(fmt.Sprintf("line %d: %v", line, err))
+			}
+			rates = append(rates, rate)
+			continue
+		}
+
+		date := field(record, columns["date"])
+		for i, currency := range header {
+			currency = strings.TrimSpace(currency)
+			value := field(record, i)
+			if i == columns["date"] || currency == "" || value == "" || value == "N/A" {
+				continue
+			}
+			rate, err := newRate(date, models.PivotCurrency, currency, value)
+			if err != nil {
+				return nil, errors.ErrExchangeRateFile(fmt.Sprintf("line %d: %v", line, err))
+			}
+			rates = append(rates, rate)
+		}
+	}
+
+	if len(rates) == 0 {
+		return nil, errors.ErrExchangeRateFile("no rates found")
+	}
+
+	return rates, nil
+}
+
+// Aux Functions
+
+func newRate(date string, base string, quote string, value string) (models.ExchangeRate, error) {
+
+	rateDate, err := parseDate(date)
+	if err != nil {
+		return models.ExchangeRate{}, err
+	}
+
+	base = strings.ToUpper(strings.TrimSpace(base))
+	quote = strings.ToUpper(strings.TrimSpace(quote))
+	if !isCurrencyCode(base) || !isCurrencyCode(quote) {
+		return models.ExchangeRate{}, fmt.Errorf("invalid currency pair %s/%s", base, quote)
+	}
+
+	rate, err := decimal.NewFromString(strings.TrimSpace(value))
+	if err != nil || !rate.IsPositive() {
+		return models.ExchangeRate{}, fmt.Errorf("invalid rate %q for %s/%s", value, base, quote)
+	}
+
+	return models.ExchangeRate{RateDate: rateDate, BaseCurrency: base, QuoteCurrency: quote, Rate: rate}, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+func isCurrencyCode(code string) bool {
+
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func field(record []string, i int) string {
+
+	if i < len(record) {
+		return strings.TrimSpace(record[i])
+	}
+	return ""
+}
@@ -6,14 +6,38 @@ import (
 )
 
 var (
-	ErrInvalidCredentials = errors.New("invalid email or password")
-	ErrJWTCreation        = errors.New("unable to create JWT token")
-	ErrJWTInvalidToken    = errors.New("error authenticating user: Token not valid")
-	ErrJWTTokenExpired    = errors.New("error authenticating user: JWT token expired")
-	ErrUploadPhoto        = errors.New("unable to upload photo")
-	ErrUserNotFound       = errors.New("user not found")
-	ErrGroupNotFound      = errors.New("group not found")
-	ErrPermissionDenied   = func(permission string) error {
+	ErrInvalidCredentials   = errors.New("invalid email or password")
+	ErrJWTCreation          = errors.New("unable to create JWT token")
+	ErrJWTInvalidToken      = errors.New("error authenticating user: Token not valid")
+	ErrJWTTokenExpired      = errors.New("error authenticating user: JWT token expired")
+	ErrUploadPhoto          = errors.New("unable to upload photo")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrGroupNotFound        = errors.New("group not found")
+	ErrMovementNotFound     = errors.New("movement not found")
+	ErrFieldNotFound        = errors.New("movement field not found")
+	ErrMemberNotFound       = errors.New("user is not a member of the group")
+	ErrLastGroupAdmin       = errors.New("the group must keep at least one admin")
+	ErrSettlementNotFound   = errors.New("settlement not found")
+	ErrNotSettlementPayee   = errors.New("only the user who receives the payment can confirm or dispute it")
+	ErrNotSettlementPayer   = errors.New("only admins can register payments made by other members")
+	ErrSettlementConfirmed  = errors.New("only admins can delete a confirmed settlement")
+	ErrExchangeRateNotFound = errors.New("exchange rate not found")
+	ErrCategoryNotFound     = errors.New("category not found")
+	ErrBudgetNotFound       = errors.New("budget not found")
+	ErrBudgetAlertNotFound  = errors.New("budget alert not found")
+	ErrRecurringNotFound    = errors.New("recurring movement not found")
+	ErrOccurrenceProcessed  = errors.New("the occurrence was already processed")
+	ErrInvalidCursor        = errors.New("invalid or expired page cursor")
+	ErrInvalidSort          = errors.New("the list cannot be sorted by that field")
+	ErrFileNotFound         = errors.New("file not found")
+	ErrInvalidFileSignature = errors.New("the file link is invalid or expired")
+	ErrAttachmentNotFound   = errors.New("attachment not found")
+	ErrCommentNotFound      = errors.New("comment not found")
+	ErrNotCommentAuthor     = errors.New("users can only delete their own comments")
+	ErrNotPhotoOwner        = errors.New("users can only change their own photo")
+	ErrGroupModified        = errors.New("the group was changed by someone else, reload it and try again")
+	ErrBaseCurrencyInUse    = errors.New("the base currency can not change while the group has settlements or budgets")
+	ErrPermissionDenied     = func(permission string) error {
 		return fmt.Errorf("user do not have %v permissions", permission)
 	}
 	ErrInvalidaPayload = func(err string) error {
@@ -34,4 +58,76 @@ var (
 	ErrCreateGroup = func(err string) error {
 		return fmt.Errorf("group can´t be created: %v", err)
 	}
+	ErrCreateMovement = func(err string) error {
+		return fmt.Errorf("movement can´t be created: %v", err)
+	}
+	ErrMovementScan = func(err string) error {
+		return fmt.Errorf("error scaning movement: %v", err)
+	}
+	ErrFieldAlreadyExist = func(name string) error {
+		return fmt.Errorf("movement field %s already exists in the group", name)
+	}
+	ErrMemberAlreadyExist = func(email string) error {
+		return fmt.Errorf("user with email %s is already a member of the group", email)
+	}
+	ErrMemberScan = func(err string) error {
+		return fmt.Errorf("error scaning group member: %v", err)
+	}
+	ErrSettlementScan = func(err string) error {
+		return fmt.Errorf("error scaning settlement: %v", err)
+	}
+	ErrMissingExchangeRate = func(from string, to string, date string) error {
+		return fmt.Errorf("there is no exchange rate from %s to %s on %s", from, to, date)
+	}
+	ErrExchangeRateFile = func(err string) error {
+		return fmt.Errorf("invalid exchange rate file: %v", err)
+	}
+	ErrCategoryAlreadyExist = func(name string) error {
+		return fmt.Errorf("category %s already exists in the group", name)
+	}
+	ErrCategoryScan = func(err string) error {
+		return fmt.Errorf("error scaning category: %v", err)
+	}
+	ErrBudgetScan = func(err string) error {
+		return fmt.Errorf("error scaning budget: %v", err)
+	}
+	ErrRecurringScan = func(err string) error {
+		return fmt.Errorf("error scaning recurring movement: %v", err)
+	}
+	ErrInvalidOccurrence = func(date string) error {
+		return fmt.Errorf("%s is not a date of the schedule", date)
+	}
+	ErrStatementFile = func(err string) error {
+		return fmt.Errorf("invalid bank statement: %v", err)
+	}
+	ErrFieldScan = func(err string) error {
+		return fmt.Errorf("error scaning movement field: %v", err)
+	}
+	ErrInvalidExportFormat = func(format string) error {
+		return fmt.Errorf("export format %q is not supported, use csv, xlsx, json, ledger or beancount", format)
+	}
+	ErrSearchScan = func(err string) error {
+		return fmt.Errorf("error scaning search result: %v", err)
+	}
+	ErrReportScan = func(err string) error {
+		return fmt.Errorf("error scaning report: %v", err)
+	}
+	ErrInvalidReportFormat = func(format string) error {
+		return fmt.Errorf("invalid report format %s, expected json or csv", format)
+	}
+	ErrUnsupportedFileType = func(contentType string) error {
+		return fmt.Errorf("files of type %s are not accepted", contentType)
+	}
+	ErrStorage = func(err string) error {
+		return fmt.Errorf("error storing file: %v", err)
+	}
+	ErrAttachmentScan = func(err string) error {
+		return fmt.Errorf("error scaning attachment: %v", err)
+	}
+	ErrCommentScan = func(err string) error {
+		return fmt.Errorf("error scaning comment: %v", err)
+	}
+	ErrInvalidImage = func(err string) error {
+		return fmt.Errorf("the file is not a valid image: %v", err)
+	}
 )
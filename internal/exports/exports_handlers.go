@@ -0,0 +1,87 @@
+package exports
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/auth"
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/middlewares"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service        models.ExportService
+	authRepository models.AuthRepository
+}
+
+func NewHandler(service models.ExportService, authRepository models.AuthRepository) *Handler {
+	return &Handler{service: service, authRepository: authRepository}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+
+	router.HandleFunc("/group/{groupId}/export", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleExport, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+}
+
+func (h *Handler) handleExport(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = models.ExportFormatCSV
+	}
+
+	contentType, extension, err := ContentType(format)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	response := &attachmentWriter{
+		w:           w,
+		contentType: contentType,
+		filename:    fmt.Sprintf("group-%s.%s", groupId, extension),
+	}
+
+	if err := h.service.Export(groupId, userId, format, response); err != nil {
+		if !response.started {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		// The status is already sent, the client gets a truncated file
+		log.Printf("export of group %s interrupted: %v", groupId, err)
+	}
+}
+
+// Aux Functions
+
+// attachmentWriter sends the headers of the file with the first write, so
+// errors before any row is written can still be answered with JSON.
+type attachmentWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (a *attachmentWriter) Write(data []byte) (int, error) {
+
+	if !a.started {
+		a.started = true
+		a.w.Header().Set("Content-Type", a.contentType)
+		a.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", a.filename))
+		a.w.WriteHeader(http.StatusOK)
+	}
+	return a.w.Write(data)
+}
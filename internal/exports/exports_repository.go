@@ -0,0 +1,149 @@
+package exports
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Postgres SQL Repository
+type SQLRepository struct {
+	db *sql.DB
+}
+
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+// Recorre los movimientos del grupo con sus participantes y campos, fila por
+// fila
+func (s *SQLRepository) StreamMovements(groupId []uint8, fn func(*models.ExportEntry) error) error {
+
+	rows, err := s.db.Query(`
+		SELECT m.movement_id, m.movement_date, m.amount, m.currency, COALESCE(m.description, ''), m.split_mode, m.category_id,
+			(SELECT json_agg(json_build_object('userId', p.user_id, 'userName', u.user_name, 'paid', p.paid_amount, 'owed', p.owed_amount) ORDER BY u.user_name)
+				FROM public.movement_participant p
+				INNER JOIN auth."user" u ON u.user_id = p.user_id
+				WHERE p.movement_id = m.movement_id),
+			(SELECT json_agg(json_build_object('name', f.name, 'value', v.value) ORDER BY f.name)
+				FROM public.movement_field_value v
+				INNER JOIN public.movement_field f ON f.movement_field_id = v.movement_field_id
+				WHERE v.movement_id = m.movement_id)
+		FROM public.movement m
+		WHERE m.group_id = $1
+		ORDER BY m.movement_date, m.created_at`, groupId)
+	if err != nil {
+		return fmt.Errorf("error al exportar los movimientos del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry := &models.ExportEntry{Type: models.HistoryMovement}
+		var participants, fields []byte
+		err := rows.Scan(
+			&entry.Id,
+			&entry.Date,
+			&entry.Amount,
+			&entry.Currency,
+			&entry.Description,
+			&entry.SplitMode,
+			&entry.CategoryId,
+			&participants,
+			&fields,
+		)
+		if err != nil {
+			return errors.ErrMovementScan(err.Error())
+		}
+
+		if entry.Participants, err = decodeParticipants(participants); err != nil {
+			return errors.ErrMovementScan(err.Error())
+		}
+		if fields != nil {
+			if err := json.Unmarshal(fields, &entry.Fields); err != nil {
+				return errors.ErrMovementScan(err.Error())
+			}
+		}
+
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (s *SQLRepository) StreamSettlements(groupId []uint8, fn func(*models.ExportEntry) error) error {
+
+	rows, err := s.db.Query(`
+		SELECT st.settlement_id, st.settlement_date, st.amount, g.base_currency, COALESCE(st.note, ''), st.status, fu.user_name, tu.user_name
+		FROM public.settlement st
+		INNER JOIN public."group" g ON g.group_id = st.group_id
+		INNER JOIN auth."user" fu ON fu.user_id = st.from_user_id
+		INNER JOIN auth."user" tu ON tu.user_id = st.to_user_id
+		WHERE st.group_id = $1
+		ORDER BY st.settlement_date, st.created_at`, groupId)
+	if err != nil {
+		return fmt.Errorf("error al exportar las liquidaciones del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry := &models.ExportEntry{Type: models.HistorySettlement}
+		err := rows.Scan(
+			&entry.Id,
+			&entry.Date,
+			&entry.Amount,
+			&entry.Currency,
+			&entry.Description,
+			&entry.Status,
+			&entry.From,
+			&entry.To,
+		)
+		if err != nil {
+			return errors.ErrSettlementScan(err.Error())
+		}
+
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Aux Functions
+
+// decodeParticipants reads the json_agg of participants. Ids come as text,
+// which encoding/json would otherwise take as base64 for a []uint8.
+func decodeParticipants(data []byte) ([]*models.ExportParticipant, error) {
+
+	if data == nil {
+		return nil, nil
+	}
+
+	var rows []struct {
+		UserId   string          `json:"userId"`
+		UserName string          `json:"userName"`
+		Paid     decimal.Decimal `json:"paid"`
+		Owed     decimal.Decimal `json:"owed"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+
+	participants := make([]*models.ExportParticipant, len(rows))
+	for i, row := range rows {
+		participants[i] = &models.ExportParticipant{
+			UserId:   []uint8(row.UserId),
+			UserName: row.UserName,
+			Paid:     row.Paid,
+			Owed:     row.Owed,
+		}
+	}
+
+	return participants, nil
+}
@@ -0,0 +1,122 @@
+package exports
+
+import (
+	"io"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/categories"
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+var contentTypes = map[string]string{
+	models.ExportFormatCSV:       "text/csv; charset=utf-8",
+	models.ExportFormatXLSX:      "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	models.ExportFormatJSON:      "application/json",
+	models.ExportFormatLedger:    "text/plain; charset=utf-8",
+	models.ExportFormatBeancount: "text/plain; charset=utf-8",
+}
+
+var extensions = map[string]string{
+	models.ExportFormatCSV:       "csv",
+	models.ExportFormatXLSX:      "xlsx",
+	models.ExportFormatJSON:      "json",
+	models.ExportFormatLedger:    "journal",
+	models.ExportFormatBeancount: "beancount",
+}
+
+type Service struct {
+	repository         models.ExportRepository
+	categoryRepository models.CategoryRepository
+	fieldRepository    models.FieldRepository
+	userRepository     models.UserRepository
+}
+
+func NewService(repository models.ExportRepository, categoryRepository models.CategoryRepository, fieldRepository models.FieldRepository, userRepository models.UserRepository) *Service {
+	return &Service{repository: repository, categoryRepository: categoryRepository, fieldRepository: fieldRepository, userRepository: userRepository}
+}
+
+// ContentType returns the media type and the file extension of a format
+func ContentType(format string) (string, string, error) {
+
+	contentType, ok := contentTypes[format]
+	if !ok {
+		return "", "", errors.ErrInvalidExportFormat(format)
+	}
+	return contentType, extensions[format], nil
+}
+
+// Export writes the movements of the group and then its settlements to w as
+// they are read. Category names are in the language of the user.
+func (s *Service) Export(groupId []uint8, userId []uint8, format string, w io.Writer) error {
+
+	if _, _, err := ContentType(format); err != nil {
+		return err
+	}
+
+	names, err := s.categoryNames(groupId, userId)
+	if err != nil {
+		return err
+	}
+
+	fields, err := s.fieldRepository.GetGroupFields(groupId)
+	if err != nil {
+		return err
+	}
+	fieldNames := make([]string, len(fields))
+	for i, field := range fields {
+		fieldNames[i] = field.Name
+	}
+
+	var writer entryWriter
+	switch format {
+	case models.ExportFormatCSV:
+		writer, err = newCSVWriter(w, fieldNames)
+	case models.ExportFormatXLSX:
+		writer, err = newTableXLSXWriter(w, fieldNames)
+	case models.ExportFormatJSON:
+		writer, err = newJSONWriter(w)
+	case models.ExportFormatLedger:
+		writer = newLedgerWriter(w, false)
+	case models.ExportFormatBeancount:
+		writer = newLedgerWriter(w, true)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.repository.StreamMovements(groupId, func(entry *models.ExportEntry) error {
+		entry.Category = names[string(entry.CategoryId)]
+		return writer.Write(entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.repository.StreamSettlements(groupId, writer.Write); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// Aux Functions
+
+func (s *Service) categoryNames(groupId []uint8, userId []uint8) (map[string]string, error) {
+
+	language, err := categories.UserLanguage(s.userRepository, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	groupCategories, err := s.categoryRepository.GetGroupCategories(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(groupCategories))
+	for _, category := range groupCategories {
+		names[string(category.CategoryId)], _ = categories.Localize(category.Names, language)
+	}
+
+	return names, nil
+}
@@ -0,0 +1,191 @@
+package exports
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Accounts of the plain text ledgers, seen from the group:
+// Expenses:<category> gets the amount of each movement, Assets:Cash:<member>
+// what each member paid and Assets:Receivable:<member> what the member owes
+// the rest of the group, positive while the member is in debt.
+const (
+	expensesAccount      = "Expenses"
+	cashAccount          = "Assets:Cash"
+	receivableAccount    = "Assets:Receivable"
+	uncategorizedAccount = "Uncategorized"
+)
+
+type posting struct {
+	account string
+	amount  decimal.Decimal
+}
+
+// ledgerWriter writes hledger/ledger-cli journals or, with beancount, the
+// beancount syntax. Disputed settlements are left out like in the balances.
+type ledgerWriter struct {
+	w         *bufio.Writer
+	beancount bool
+	accounts  map[string]bool
+	first     time.Time
+}
+
+func newLedgerWriter(w io.Writer, beancount bool) *ledgerWriter {
+
+	return &ledgerWriter{w: bufio.NewWriter(w), beancount: beancount, accounts: make(map[string]bool)}
+}
+
+func (l *ledgerWriter) Write(entry *models.ExportEntry) error {
+
+	var postings []posting
+
+	switch entry.Type {
+	case models.HistoryMovement:
+		category := uncategorizedAccount
+		if entry.Category != "" {
+			category = entry.Category
+		}
+		postings = append(postings, posting{account: expensesAccount + ":" + accountName(category), amount: entry.Amount})
+		for _, participant := range entry.Participants {
+			member := accountName(participant.UserName)
+			postings = append(postings,
+				posting{account: cashAccount + ":" + member, amount: participant.Paid.Neg()},
+				posting{account: receivableAccount + ":" + member, amount: participant.Owed.Sub(participant.Paid)},
+			)
+		}
+	case models.HistorySettlement:
+		if entry.Status == models.SettlementDisputed {
+			return nil
+		}
+		from, to := accountName(entry.From), accountName(entry.To)
+		postings = []posting{
+			{account: cashAccount + ":" + from, amount: entry.Amount.Neg()},
+			{account: cashAccount + ":" + to, amount: entry.Amount},
+			{account: receivableAccount + ":" + from, amount: entry.Amount.Neg()},
+			{account: receivableAccount + ":" + to, amount: entry.Amount},
+		}
+	}
+
+	if l.first.IsZero() || entry.Date.Before(l.first) {
+		l.first = entry.Date
+	}
+
+	date := entry.Date.Format(time.DateOnly)
+	description := entry.Description
+	if description == "" && entry.Type == models.HistorySettlement {
+		description = fmt.Sprintf("%s pays %s", entry.From, entry.To)
+	}
+
+	if l.beancount {
+		flag := "*"
+		if entry.Status == models.SettlementPending {
+			flag = "!"
+		}
+		fmt.Fprintf(l.w, "%s %s %s\n", date, flag, quote(description))
+		fmt.Fprintf(l.w, "  %s-id: %s\n", entry.Type, quote(string(entry.Id)))
+		for _, field := range entry.Fields {
+			if key := metadataKey(field.Name); key != "" {
+				fmt.Fprintf(l.w, "  %s: %s\n", key, quote(field.Value))
+			}
+		}
+	} else {
+		flag := ""
+		if entry.Status == models.SettlementPending {
+			flag = "! "
+		}
+		fmt.Fprintf(l.w, "%s %s%s\n", date, flag, strings.ReplaceAll(description, "\n", " "))
+		fmt.Fprintf(l.w, "    ; %s-id: %s\n", entry.Type, entry.Id)
+		for _, field := range entry.Fields {
+			fmt.Fprintf(l.w, "    ; %s: %s\n", strings.ReplaceAll(field.Name, ":", ""), strings.ReplaceAll(field.Value, "\n", " "))
+		}
+	}
+
+	for _, p := range postings {
+		if p.amount.IsZero() {
+			continue
+		}
+		l.accounts[p.account] = true
+		fmt.Fprintf(l.w, "    %-50s %12s %s\n", p.account, p.amount.StringFixed(2), entry.Currency)
+	}
+
+	_, err := l.w.WriteString("\n")
+	return err
+}
+
+// Close writes the open directives beancount needs. Beancount does not care
+// about the order of the file, so they can go after the transactions.
+func (l *ledgerWriter) Close() error {
+
+	if l.beancount && len(l.accounts) > 0 {
+		accounts := make([]string, 0, len(l.accounts))
+		for account := range l.accounts {
+			accounts = append(accounts, account)
+		}
+		sort.Strings(accounts)
+
+		for _, account := range accounts {
+			fmt.Fprintf(l.w, "%s open %s\n", l.first.Format(time.DateOnly), account)
+		}
+	}
+
+	return l.w.Flush()
+}
+
+// Aux Functions
+
+// accountName turns a name into an account component valid for both ledger
+// and beancount: it starts with an upper case letter or digit and only has
+// letters, digits and dashes.
+func accountName(name string) string {
+
+	var b strings.Builder
+	upper := true
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			b.WriteRune(r)
+		case b.Len() > 0:
+			upper = true
+		}
+	}
+
+	if b.Len() == 0 {
+		return "Unknown"
+	}
+	return b.String()
+}
+
+// metadataKey turns a field name into a beancount metadata key: lower case
+// letter first, then letters, digits, dashes and underscores.
+func metadataKey(name string) string {
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', b.Len() > 0 && (r >= '0' && r <= '9' || r == '_' || r == '-'):
+			b.WriteRune(r)
+		case b.Len() > 0:
+			b.WriteRune('-')
+		}
+	}
+	return strings.TrimRight(b.String(), "-")
+}
+
+func quote(text string) string {
+
+	text = strings.ReplaceAll(text, `\`, `\\`)
+	text = strings.ReplaceAll(text, `"`, `\"`)
+	return `"` + strings.ReplaceAll(text, "\n", " ") + `"`
+}
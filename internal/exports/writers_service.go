@@ -0,0 +1,145 @@
+package exports
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+// entryWriter writes the entries of an export one by one
+type entryWriter interface {
+	Write(*models.ExportEntry) error
+	Close() error
+}
+
+var tableColumns = []string{"type", "id", "date", "description", "category", "amount", "currency", "split_mode", "status", "from", "to", "participant", "paid", "owed"}
+
+// Columns of tableColumns holding numbers
+var tableNumeric = map[int]bool{5: true, 12: true, 13: true}
+
+// tableWriter writes one row per participant of each movement and one per
+// settlement, followed by a column per movement field. Used by CSV and XLSX.
+type tableWriter struct {
+	fields   []string
+	writeRow func([]string) error
+	close    func() error
+}
+
+func newCSVWriter(w io.Writer, fields []string) (*tableWriter, error) {
+
+	writer := csv.NewWriter(w)
+	table := &tableWriter{
+		fields: fields,
+		writeRow: func(row []string) error {
+			return writer.Write(row)
+		},
+		close: func() error {
+			writer.Flush()
+			return writer.Error()
+		},
+	}
+
+	return table, table.writeRow(append(append([]string{}, tableColumns...), fields...))
+}
+
+func newTableXLSXWriter(w io.Writer, fields []string) (*tableWriter, error) {
+
+	writer, err := newXLSXWriter(w)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &tableWriter{
+		fields: fields,
+		writeRow: func(row []string) error {
+			return writer.WriteRow(row, tableNumeric)
+		},
+		close: writer.Close,
+	}
+
+	return table, writer.WriteRow(append(append([]string{}, tableColumns...), fields...), nil)
+}
+
+func (t *tableWriter) Write(entry *models.ExportEntry) error {
+
+	base := []string{
+		entry.Type,
+		string(entry.Id),
+		entry.Date.Format(time.DateOnly),
+		entry.Description,
+		entry.Category,
+		entry.Amount.StringFixed(2),
+		entry.Currency,
+		entry.SplitMode,
+		entry.Status,
+		entry.From,
+		entry.To,
+	}
+
+	values := make(map[string]string, len(entry.Fields))
+	for _, field := range entry.Fields {
+		values[field.Name] = field.Value
+	}
+	fieldValues := make([]string, len(t.fields))
+	for i, name := range t.fields {
+		fieldValues[i] = values[name]
+	}
+
+	if len(entry.Participants) == 0 {
+		row := append(append(base, "", "", ""), fieldValues...)
+		return t.writeRow(row)
+	}
+
+	for _, participant := range entry.Participants {
+		row := append(append([]string{}, base...), participant.UserName, participant.Paid.StringFixed(2), participant.Owed.StringFixed(2))
+		if err := t.writeRow(append(row, fieldValues...)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *tableWriter) Close() error {
+
+	return t.close()
+}
+
+// jsonWriter writes {"entries": [...]} encoding one entry at a time
+type jsonWriter struct {
+	w     io.Writer
+	first bool
+}
+
+func newJSONWriter(w io.Writer) (*jsonWriter, error) {
+
+	_, err := io.WriteString(w, `{"entries":[`)
+	return &jsonWriter{w: w, first: true}, err
+}
+
+func (j *jsonWriter) Write(entry *models.ExportEntry) error {
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if !j.first {
+		if _, err := io.WriteString(j.w, ","); err != nil {
+			return err
+		}
+	}
+	j.first = false
+
+	_, err = j.w.Write(append(data, '\n'))
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+
+	_, err := io.WriteString(j.w, "]}\n")
+	return err
+}
@@ -0,0 +1,153 @@
+package fields
+
+import (
+	"net/http"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/auth"
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/middlewares"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service        models.FieldService
+	authRepository models.AuthRepository
+}
+
+func NewHandler(service models.FieldService, authRepository models.AuthRepository) *Handler {
+	return &Handler{service: service, authRepository: authRepository}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+
+	router.HandleFunc("/group/{groupId}/field", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetFields, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}/field/{fieldId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetField, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+
+	// Admin routes
+	router.HandleFunc("/group/{groupId}/field", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleFieldCreate, models.PermissionManageFields, h.authRepository))).Methods("POST")
+	router.HandleFunc("/group/{groupId}/field/{fieldId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleFieldUpdate, models.PermissionManageFields, h.authRepository))).Methods("PUT")
+	router.HandleFunc("/group/{groupId}/field/{fieldId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleFieldDelete, models.PermissionManageFields, h.authRepository))).Methods("DELETE")
+}
+
+func (h *Handler) handleFieldCreate(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	var payload models.CreateFieldPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	field, err := h.service.CreateField(payload, groupId, userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, field)
+}
+
+func (h *Handler) handleGetFields(w http.ResponseWriter, r *http.Request) {
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	fields, err := h.service.GetGroupFields(groupId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, fields)
+}
+
+func (h *Handler) handleGetField(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	fieldId := []uint8(vars["fieldId"])
+
+	field, err := h.service.GetFieldById(groupId, fieldId)
+	if err == errors.ErrFieldNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, field)
+}
+
+func (h *Handler) handleFieldUpdate(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	fieldId := []uint8(vars["fieldId"])
+
+	var payload models.UpdateFieldPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	err = h.service.UpdateField(payload, groupId, fieldId, userId)
+	if err == errors.ErrFieldNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Movement field updated successfully",
+	})
+}
+
+func (h *Handler) handleFieldDelete(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	fieldId := []uint8(vars["fieldId"])
+
+	err := h.service.DeleteField(groupId, fieldId)
+	if err == errors.ErrFieldNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Movement field deleted successfully",
+	})
+}
@@ -0,0 +1,240 @@
+package fields
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+// Postgres SQL Repository
+type SQLRepository struct {
+	db *sql.DB
+}
+
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+const fieldColumns = `movement_field_id, group_id, name, type, required, created_at, created_by, updated_at, updated_by`
+
+func (s *SQLRepository) CreateField(field models.MovementField) ([]uint8, error) {
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var fieldId []uint8
+	err = tx.QueryRow(
+		`INSERT INTO public.movement_field (group_id, name, type, required, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING movement_field_id`,
+		field.GroupId, field.Name, field.Type, field.Required, field.CreatedBy, field.UpdatedBy,
+	).Scan(&fieldId)
+	if err != nil {
+		return nil, fmt.Errorf("error al crear el campo: %w", err)
+	}
+
+	if err := insertOptions(tx, fieldId, field.Options); err != nil {
+		return nil, err
+	}
+
+	return fieldId, tx.Commit()
+}
+
+func (s *SQLRepository) GetFieldById(groupId []uint8, fieldId []uint8) (*models.MovementField, error) {
+
+	row := s.db.QueryRow("SELECT "+fieldColumns+" FROM public.movement_field WHERE group_id = $1 AND movement_field_id = $2", groupId, fieldId)
+	field, err := scanRowIntoField(row)
+	if err != nil {
+		return nil, err
+	}
+
+	field.Options, err = s.getFieldOptions(field.MovementFieldId)
+	if err != nil {
+		return nil, err
+	}
+
+	return field, nil
+}
+
+func (s *SQLRepository) GetFieldByName(groupId []uint8, name string) (*models.MovementField, error) {
+
+	row := s.db.QueryRow("SELECT "+fieldColumns+" FROM public.movement_field WHERE group_id = $1 AND name = $2", groupId, name)
+	field, err := scanRowIntoField(row)
+	if err != nil {
+		return nil, err
+	}
+
+	field.Options, err = s.getFieldOptions(field.MovementFieldId)
+	if err != nil {
+		return nil, err
+	}
+
+	return field, nil
+}
+
+func (s *SQLRepository) GetGroupFields(groupId []uint8) ([]*models.MovementField, error) {
+
+	rows, err := s.db.Query("SELECT "+fieldColumns+" FROM public.movement_field WHERE group_id = $1 ORDER BY name", groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los campos del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var fields []*models.MovementField
+	byId := make(map[string]*models.MovementField)
+
+	for rows.Next() {
+		field, err := scanRowIntoField(rows)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+		byId[string(field.MovementFieldId)] = field
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	optionRows, err := s.db.Query(`
+		SELECT o.movement_field_id, o.value
+		FROM public.movement_field_options o
+		INNER JOIN public.movement_field f ON f.movement_field_id = o.movement_field_id
+		WHERE f.group_id = $1
+		ORDER BY o.value`, groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las opciones de los campos: %w", err)
+	}
+	defer optionRows.Close()
+
+	for optionRows.Next() {
+		var fieldId []uint8
+		var value string
+		if err := optionRows.Scan(&fieldId, &value); err != nil {
+			return nil, err
+		}
+		if field, ok := byId[string(fieldId)]; ok {
+			field.Options = append(field.Options, value)
+		}
+	}
+
+	return fields, optionRows.Err()
+}
+
+func (s *SQLRepository) UpdateField(field models.MovementField) error {
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		`UPDATE public.movement_field
+		SET name = $1, type = $2, required = $3, updated_by = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE group_id = $5 AND movement_field_id = $6`,
+		field.Name, field.Type, field.Required, field.UpdatedBy, field.GroupId, field.MovementFieldId,
+	)
+	if err != nil {
+		return fmt.Errorf("error al actualizar el campo: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.ErrFieldNotFound
+	}
+
+	if _, err := tx.Exec("DELETE FROM public.movement_field_options WHERE movement_field_id = $1", field.MovementFieldId); err != nil {
+		return fmt.Errorf("error al actualizar las opciones del campo: %w", err)
+	}
+
+	if err := insertOptions(tx, field.MovementFieldId, field.Options); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLRepository) DeleteField(groupId []uint8, fieldId []uint8) error {
+
+	res, err := s.db.Exec("DELETE FROM public.movement_field WHERE group_id = $1 AND movement_field_id = $2", groupId, fieldId)
+	if err != nil {
+		return fmt.Errorf("error al eliminar el campo: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrFieldNotFound
+	}
+
+	return nil
+}
+
+func (s *SQLRepository) getFieldOptions(fieldId []uint8) ([]string, error) {
+
+	rows, err := s.db.Query("SELECT value FROM public.movement_field_options WHERE movement_field_id = $1 ORDER BY value", fieldId)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las opciones del campo: %w", err)
+	}
+	defer rows.Close()
+
+	var options []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		options = append(options, value)
+	}
+
+	return options, rows.Err()
+}
+
+func insertOptions(tx *sql.Tx, fieldId []uint8, options []string) error {
+
+	for _, option := range options {
+		_, err := tx.Exec(
+			"INSERT INTO public.movement_field_options (movement_field_id, value) VALUES ($1, $2)",
+			fieldId, option,
+		)
+		if err != nil {
+			return fmt.Errorf("error al crear la opción del campo: %w", err)
+		}
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRowIntoField(row rowScanner) (*models.MovementField, error) {
+
+	field := new(models.MovementField)
+	err := row.Scan(
+		&field.MovementFieldId,
+		&field.GroupId,
+		&field.Name,
+		&field.Type,
+		&field.Required,
+		&field.CreatedAt,
+		&field.CreatedBy,
+		&field.UpdatedAt,
+		&field.UpdatedBy,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrFieldNotFound
+		}
+		return nil, errors.ErrFieldScan(err.Error())
+	}
+	return field, nil
+}
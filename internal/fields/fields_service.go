@@ -0,0 +1,203 @@
+package fields
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// movement_field_value.value is VARCHAR(255)
+const maxValueLength = 255
+
+type Service struct {
+	repository models.FieldRepository
+}
+
+func NewService(repository models.FieldRepository) *Service {
+	return &Service{repository: repository}
+}
+
+func (s *Service) CreateField(payload models.CreateFieldPayload, groupId []uint8, userId []uint8) (*models.MovementField, error) {
+
+	if err := validateOptions(payload.Type, payload.Options); err != nil {
+		return nil, err
+	}
+
+	_, err := s.repository.GetFieldByName(groupId, payload.Name)
+	if err == nil {
+		return nil, errors.ErrFieldAlreadyExist(payload.Name)
+	}
+
+	field := models.MovementField{
+		GroupId:   groupId,
+		Name:      payload.Name,
+		Type:      payload.Type,
+		Required:  payload.Required,
+		Options:   payload.Options,
+		CreatedBy: userId,
+		UpdatedBy: userId,
+	}
+
+	fieldId, err := s.repository.CreateField(field)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.GetFieldById(groupId, fieldId)
+}
+
+func (s *Service) GetFieldById(groupId []uint8, fieldId []uint8) (*models.MovementField, error) {
+
+	return s.repository.GetFieldById(groupId, fieldId)
+}
+
+func (s *Service) GetGroupFields(groupId []uint8) ([]*models.MovementField, error) {
+
+	return s.repository.GetGroupFields(groupId)
+}
+
+func (s *Service) UpdateField(payload models.UpdateFieldPayload, groupId []uint8, fieldId []uint8, userId []uint8) error {
+
+	if err := validateOptions(payload.Type, payload.Options); err != nil {
+		return err
+	}
+
+	existing, err := s.repository.GetFieldByName(groupId, payload.Name)
+	if err == nil && string(existing.MovementFieldId) != string(fieldId) {
+		return errors.ErrFieldAlreadyExist(payload.Name)
+	}
+
+	field := models.MovementField{
+		MovementFieldId: fieldId,
+		GroupId:         groupId,
+		Name:            payload.Name,
+		Type:            payload.Type,
+		Required:        payload.Required,
+		Options:         payload.Options,
+		UpdatedBy:       userId,
+	}
+
+	return s.repository.UpdateField(field)
+}
+
+func (s *Service) DeleteField(groupId []uint8, fieldId []uint8) error {
+
+	return s.repository.DeleteField(groupId, fieldId)
+}
+
+// ValidateValues checks the submitted values against the group field schema
+// and returns them normalized and linked to their field.
+func ValidateValues(schema []*models.MovementField, values map[string]string) ([]models.MovementFieldValue, error) {
+
+	var problems []string
+	var result []models.MovementFieldValue
+
+	byName := make(map[string]*models.MovementField, len(schema))
+	for _, field := range schema {
+		byName[field.Name] = field
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := byName[name]; !ok {
+			problems = append(problems, fmt.Sprintf("field %s is not defined in the group", name))
+		}
+	}
+
+	for _, field := range schema {
+
+		value := strings.TrimSpace(values[field.Name])
+		if value == "" {
+			if field.Required {
+				problems = append(problems, fmt.Sprintf("field %s is required", field.Name))
+			}
+			continue
+		}
+
+		normalized, err := normalizeValue(field, value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("field %s %v", field.Name, err))
+			continue
+		}
+
+		result = append(result, models.MovementFieldValue{
+			MovementFieldId: field.MovementFieldId,
+			Name:            field.Name,
+			Value:           normalized,
+		})
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.ErrInvalidaPayload(strings.Join(problems, "; "))
+	}
+
+	return result, nil
+}
+
+// Aux Functions
+
+func normalizeValue(field *models.MovementField, value string) (string, error) {
+
+	switch field.Type {
+	case models.FieldTypeText:
+		if utf8.RuneCountInString(value) > maxValueLength {
+			return "", fmt.Errorf("can not be longer than %d characters", maxValueLength)
+		}
+		return value, nil
+
+	case models.FieldTypeNumber:
+		number, err := decimal.NewFromString(value)
+		if err != nil {
+			return "", fmt.Errorf("must be a number")
+		}
+		return number.String(), nil
+
+	case models.FieldTypeDate:
+		date, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return "", fmt.Errorf("must be a date with the format YYYY-MM-DD")
+		}
+		return date.Format(time.DateOnly), nil
+
+	case models.FieldTypeBoolean:
+		boolean, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("must be true or false")
+		}
+		return strconv.FormatBool(boolean), nil
+
+	case models.FieldTypeSelect:
+		for _, option := range field.Options {
+			if option == value {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("must be one of [%s]", strings.Join(field.Options, ", "))
+	}
+
+	return "", fmt.Errorf("has an unknown type %s", field.Type)
+}
+
+func validateOptions(fieldType string, options []string) error {
+
+	if fieldType == models.FieldTypeSelect && len(options) == 0 {
+		return errors.ErrInvalidaPayload("select fields need at least one option")
+	}
+	if fieldType != models.FieldTypeSelect && len(options) > 0 {
+		return errors.ErrInvalidaPayload("only select fields can have options")
+	}
+
+	return nil
+}
@@ -7,17 +7,22 @@ import (
 	"github.com/PabloPei/SmartSpend-backend/internal/errors"
 	"github.com/PabloPei/SmartSpend-backend/internal/middlewares"
 	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/pagination"
 	"github.com/PabloPei/SmartSpend-backend/utils"
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 )
 
+// Largest photo that can be uploaded
+const maxPhotoSize = 5 << 20
+
 type Handler struct {
-	service models.GroupService
+	service        models.GroupService
+	authRepository models.AuthRepository
 }
 
-func NewHandler(service models.GroupService) *Handler {
-	return &Handler{service: service}
+func NewHandler(service models.GroupService, authRepository models.AuthRepository) *Handler {
+	return &Handler{service: service, authRepository: authRepository}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
@@ -26,8 +31,18 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/group/create", middlewares.WithJWTAuth(h.handleGroupCreate)).Methods("POST")
 	router.HandleFunc("/group/all", middlewares.WithJWTAuth(h.handleGetGroups)).Methods("GET")
 
-	// Admin routes
-	router.HandleFunc("/group/{groupId}", middlewares.WithJWTAuth(h.handleGetGroup)).Methods("GET")
+	// Group routes
+	router.HandleFunc("/group/{groupId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetGroup, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGroupUpdate, models.PermissionEditGroup, h.authRepository))).Methods("PUT")
+	router.HandleFunc("/group/{groupId}/photo", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGroupPhoto, models.PermissionEditGroup, h.authRepository))).Methods("POST", "PUT")
+	router.HandleFunc("/group/{groupId}/simplify-debts", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleSimplifyDebts, models.PermissionEditGroup, h.authRepository))).Methods("PUT")
+
+	// Member routes
+	router.HandleFunc("/group/{groupId}/member", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetMembers, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}/member", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleMemberAdd, models.PermissionManageMembers, h.authRepository))).Methods("POST")
+	router.HandleFunc("/group/{groupId}/member/{userId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleMemberUpdate, models.PermissionManageMembers, h.authRepository))).Methods("PUT")
+	router.HandleFunc("/group/{groupId}/member/{userId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleMemberRemove, models.PermissionManageMembers, h.authRepository))).Methods("DELETE")
+	router.HandleFunc("/group/{groupId}/member/{userId}/validity", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleMemberValidity, models.PermissionManageMembers, h.authRepository))).Methods("PUT")
 }
 
 func (h *Handler) handleGroupCreate(w http.ResponseWriter, r *http.Request) {
@@ -70,9 +85,18 @@ func (h *Handler) handleGetGroups(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userPublic, err := h.service.GetUserGroups(userId)
-
+	page, err := pagination.ParseRequest(r)
 	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	userPublic, err := h.service.GetUserGroups(userId, page)
+
+	if err == errors.ErrInvalidCursor || err == errors.ErrInvalidSort {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
@@ -97,3 +121,295 @@ func (h *Handler) handleGetGroup(w http.ResponseWriter, r *http.Request) {
 
 	utils.WriteJSON(w, http.StatusOK, userPublic)
 }
+
+func (h *Handler) handleGetMembers(w http.ResponseWriter, r *http.Request) {
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	page, err := pagination.ParseRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	members, err := h.service.GetGroupMembers(groupId, page)
+	if err == errors.ErrInvalidCursor || err == errors.ErrInvalidSort {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, members)
+}
+
+func (h *Handler) handleMemberAdd(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	var payload models.AddMemberPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	err = h.service.AddMember(payload, groupId, userId)
+	if err == errors.ErrUserNotFound || err == errors.ErrGroupNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, map[string]string{
+		"message": "Member added successfully",
+	})
+}
+
+func (h *Handler) handleMemberUpdate(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	memberId := []uint8(vars["userId"])
+
+	var payload models.UpdateMemberPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	err = h.service.UpdateMemberRole(payload, groupId, memberId, userId)
+	if err == errors.ErrMemberNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err == errors.ErrLastGroupAdmin {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Member role updated successfully",
+	})
+}
+
+func (h *Handler) handleMemberRemove(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	memberId := []uint8(vars["userId"])
+
+	err := h.service.RemoveMember(groupId, memberId)
+	if err == errors.ErrMemberNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err == errors.ErrLastGroupAdmin {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Member removed successfully",
+	})
+}
+
+func (h *Handler) handleMemberValidity(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	memberId := []uint8(vars["userId"])
+
+	var payload models.UpdateMemberValidityPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = h.service.UpdateMemberValidity(payload, groupId, memberId, userId)
+	if err == errors.ErrMemberNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err == errors.ErrLastGroupAdmin {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Member access updated successfully",
+	})
+}
+
+func (h *Handler) handleSimplifyDebts(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	var payload models.UpdateSimplifyDebtsPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = h.service.UpdateSimplifyDebts(payload, groupId, userId)
+	if err == errors.ErrGroupNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Group updated successfully",
+	})
+}
+
+// handleGroupUpdate answers 409 when the updatedAt sent is not the one of the
+// group anymore, somebody else edited it after the client read it
+func (h *Handler) handleGroupUpdate(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	var payload models.UpdateGroupPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	group, err := h.service.UpdateGroup(payload, groupId, userId)
+	if err == errors.ErrGroupNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err == errors.ErrGroupModified || err == errors.ErrBaseCurrencyInUse {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, group)
+}
+
+// handleGroupPhoto takes a JSON body with a link to the photo or a multipart
+// form with the photo itself in "file"
+func (h *Handler) handleGroupPhoto(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	if !utils.IsMultipart(r) {
+		h.handleGroupPhotoUrl(w, r, groupId, userId)
+		return
+	}
+
+	data, _, err := utils.ParseFile(w, r, "file", maxPhotoSize)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(err.Error()))
+		return
+	}
+
+	photoUrl, err := h.service.StorePhoto(data, groupId, userId)
+	if err == errors.ErrGroupNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message":  "Photo uploaded successfully",
+		"photoUrl": photoUrl,
+	})
+}
+
+func (h *Handler) handleGroupPhotoUrl(w http.ResponseWriter, r *http.Request, groupId []uint8, userId []uint8) {
+
+	var payload models.UploadPhotoPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	err := h.service.UploadPhoto(payload, groupId, userId)
+	if err == errors.ErrGroupNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Photo uploaded successfully",
+	})
+}
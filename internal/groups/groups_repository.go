@@ -4,9 +4,11 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/PabloPei/SmartSpend-backend/internal/errors"
 	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/pagination"
 )
 
 // Postgres SQL Repository
@@ -18,24 +20,62 @@ func NewSQLRepository(db *sql.DB) *SQLRepository {
 	return &SQLRepository{db: db}
 }
 
-func (s *SQLRepository) CreateGroup(group models.Group) error {
+const groupColumns = `g.group_id, g.group_name, g.description, COALESCE(g.photo_url, ''), COALESCE(g.photo_key, ''), g.created_at, g.created_by, g.updated_at, g.updated_by, g.simplify_debts, g.base_currency`
 
-	_, err := s.db.Exec(
-		"INSERT INTO public.\"group\" (group_name, description, created_by, updated_by) VALUES ($1, $2, $3, $4)",
-		group.GroupName, group.Description, group.CreatedBy, group.UpdatedBy,
+// Crea el grupo con sus categorias por defecto y asigna al creador el rol de
+// admin en la misma transaccion
+func (s *SQLRepository) CreateGroup(group models.Group) ([]uint8, error) {
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var groupId []uint8
+	err = tx.QueryRow(
+		"INSERT INTO public.\"group\" (group_name, description, base_currency, created_by, updated_by) VALUES ($1, $2, $3, $4, $5) RETURNING group_id",
+		group.GroupName, group.Description, group.BaseCurrency, group.CreatedBy, group.UpdatedBy,
+	).Scan(&groupId)
+
+	if err != nil {
+		return nil, fmt.Errorf("error al crear el grupo: %w", err)
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO auth.user_role (user_id, role_id, group_id, created_by, updated_by) VALUES ($1, $2, $3, $4, $5)",
+		group.CreatedBy, models.RoleAdmin, groupId, group.CreatedBy, group.UpdatedBy,
 	)
 
 	if err != nil {
-		return fmt.Errorf("error al crear el grupo: %w", err)
+		return nil, fmt.Errorf("error al asignar el admin del grupo: %w", err)
 	}
 
-	return nil
+	// Copia las categorias por defecto de conf con todas sus traducciones
+	_, err = tx.Exec(`
+		INSERT INTO public.category (group_id, code, created_by, updated_by)
+		SELECT $1, code, $2, $2 FROM conf.default_category`, groupId, group.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("error al crear las categorias por defecto: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO public.category_name (category_id, language_code, name)
+		SELECT c.category_id, d.language_code, d.name
+		FROM public.category c
+		INNER JOIN conf.default_category_name d ON d.code = c.code
+		WHERE c.group_id = $1`, groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al crear los nombres de las categorias por defecto: %w", err)
+	}
+
+	return groupId, tx.Commit()
 }
 
 func (s *SQLRepository) GetGroupById(groupId []uint8) (*models.Group, error) {
 
 	groupIdstr := string(groupId)
-	row := s.db.QueryRow("SELECT * FROM public.\"group\" WHERE group_id = $1", groupIdstr)
+	row := s.db.QueryRow("SELECT "+groupColumns+" FROM public.\"group\" g WHERE g.group_id = $1", groupIdstr)
 	log.Printf("borrar %v", row)
 	return scanRowIntoUser(row)
 
@@ -43,71 +83,195 @@ func (s *SQLRepository) GetGroupById(groupId []uint8) (*models.Group, error) {
 
 func (s *SQLRepository) GetGroupByName(name string) (*models.Group, error) {
 
-	row := s.db.QueryRow("SELECT * FROM public.\"group\" WHERE group_name = $1", name)
+	row := s.db.QueryRow("SELECT "+groupColumns+" FROM public.\"group\" g WHERE g.group_name = $1", name)
 	return scanRowIntoUser(row)
 
 }
 
 func (s *SQLRepository) GetUserGroupByName(user []uint8, name string) (*models.Group, error) {
 
-	row := s.db.QueryRow("SELECT g.* FROM public.\"group\" g INNER JOIN auth.\"user_role\" ur ON g.group_id = ur.group_id WHERE g.group_name = $1 AND ur.user_id = $2", name, user)
+	row := s.db.QueryRow("SELECT "+groupColumns+" FROM public.\"group\" g INNER JOIN auth.\"user_role\" ur ON g.group_id = ur.group_id WHERE g.group_name = $1 AND ur.user_id = $2 AND (ur.valid_until IS NULL OR ur.valid_until > CURRENT_TIMESTAMP)", name, user)
 	return scanRowIntoUser(row)
 
 }
 
-func (s *SQLRepository) GetUserGroups(user []uint8) ([]*models.Group, error) {
+// Sortable fields of the groups of a user
+var groupSorts = pagination.Fields{
+	"name":      {{Expr: "COALESCE(g.group_name, '')", Type: "text"}, {Expr: "g.group_id", Type: "uuid"}},
+	"createdAt": {{Expr: "g.created_at", Type: "timestamp"}, {Expr: "g.group_id", Type: "uuid"}},
+}
+
+func (s *SQLRepository) GetUserGroups(user []uint8, page models.PageRequest) (*models.Page[*models.Group], error) {
 
-	rows, err := s.db.Query(`
-        SELECT g.* 
+	query, err := pagination.Build(groupSorts, page, "name", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	condition := ""
+	if query.Condition != "" {
+		condition = "AND " + query.Condition
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+        SELECT %s, %s
         FROM public."group" g
         INNER JOIN auth."user_role" ur ON g.group_id = ur.group_id
-        WHERE ur.user_id = $1`, user)
+        WHERE ur.user_id = $1
+        AND (ur.valid_until IS NULL OR ur.valid_until > CURRENT_TIMESTAMP)
+        %s
+        ORDER BY %s
+        LIMIT %d`, groupColumns, query.Keys, condition, query.Order, query.Limit+1), append([]any{user}, query.Args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("error al obtener los grupos del usuario: %w", err)
 	}
 	defer rows.Close()
 
 	var groups []*models.Group
+	var keys [][]string
 
+	scanner := query.Scanner(rows)
 	for rows.Next() {
-		group := new(models.Group)
-		err := rows.Scan(
-			&group.GroupId,
-			&group.GroupName,
-			&group.Description,
-			&group.PhotoUrl,
-			&group.CreatedAt,
-			&group.CreatedBy,
-			&group.UpdatedAt,
-			&group.UpdatedBy,
-		)
+		group, err := scanRowIntoUser(scanner)
 		if err != nil {
 			return nil, err
 		}
 
 		// Agregar el grupo al slice
 		groups = append(groups, group)
+		keys = append(keys, scanner.Values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return groups, nil
+	return pagination.Page(query, groups, keys), nil
 
 }
 
-func (s *SQLRepository) UploadPhoto(photoUrl string, groupId []uint8) error {
+// Cambia la foto por un link, la foto subida deja de usarse
+func (s *SQLRepository) UploadPhoto(photoUrl string, groupId []uint8, userId []uint8) error {
 
-	_, err := s.db.Exec(
-		"UPDATE  public.\"group\" SET photo_url = $1 WHERE group_id = $2",
-		photoUrl, groupId,
+	res, err := s.db.Exec(
+		"UPDATE public.\"group\" SET photo_url = $1, photo_key = NULL, updated_by = $2, updated_at = CURRENT_TIMESTAMP WHERE group_id = $3",
+		photoUrl, userId, groupId,
 	)
+	if err != nil {
+		return fmt.Errorf("error al actualizar la foto: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrGroupNotFound
+	}
+
+	return nil
+}
+
+// Guarda la clave de la foto subida al storage
+func (s *SQLRepository) UpdatePhotoKey(photoKey string, groupId []uint8, userId []uint8) error {
 
+	res, err := s.db.Exec(
+		"UPDATE public.\"group\" SET photo_key = $1, updated_by = $2, updated_at = CURRENT_TIMESTAMP WHERE group_id = $3",
+		photoKey, userId, groupId,
+	)
 	if err != nil {
 		return fmt.Errorf("error al actualizar la foto: %w", err)
 	}
 
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrGroupNotFound
+	}
+
+	return nil
+}
+
+func (s *SQLRepository) UpdateSimplifyDebts(groupId []uint8, simplifyDebts bool, userId []uint8) error {
+
+	res, err := s.db.Exec(
+		"UPDATE public.\"group\" SET simplify_debts = $1, updated_by = $2, updated_at = CURRENT_TIMESTAMP WHERE group_id = $3",
+		simplifyDebts, userId, groupId,
+	)
+	if err != nil {
+		return fmt.Errorf("error al actualizar el grupo: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrGroupNotFound
+	}
+
 	return nil
 }
 
-func scanRowIntoUser(row *sql.Row) (*models.Group, error) {
+// Actualiza el perfil del grupo solo si nadie lo cambio despues de
+// updatedAt. Un photo_url vacio mantiene la foto actual. La moneda base no
+// cambia si hay liquidaciones o presupuestos, sus montos estan en esa moneda.
+func (s *SQLRepository) UpdateGroup(group models.Group, updatedAt time.Time) error {
+
+	res, err := s.db.Exec(`
+		UPDATE public."group" g
+		SET group_name = $1, description = $2, base_currency = $3,
+			photo_url = CASE WHEN $4::text = '' THEN photo_url ELSE $4 END,
+			photo_key = CASE WHEN $4::text = '' THEN photo_key ELSE NULL END,
+			updated_by = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE g.group_id = $6 AND g.updated_at = $7
+			AND (g.base_currency = $3 OR NOT (`+currencyInUse+`))`,
+		group.GroupName, group.Description, group.BaseCurrency, group.PhotoUrl, group.UpdatedBy, group.GroupId, updatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error al actualizar el grupo: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	// Nada cambio: el grupo no existe, alguien lo edito antes o la moneda
+	// base esta en uso
+	var modified, inUse bool
+	err = s.db.QueryRow(`
+		SELECT g.updated_at <> $2, g.base_currency <> $3 AND (`+currencyInUse+`)
+		FROM public."group" g
+		WHERE g.group_id = $1`, group.GroupId, updatedAt, group.BaseCurrency,
+	).Scan(&modified, &inUse)
+	if err == sql.ErrNoRows {
+		return errors.ErrGroupNotFound
+	} else if err != nil {
+		return fmt.Errorf("error al actualizar el grupo: %w", err)
+	}
+	if modified {
+		return errors.ErrGroupModified
+	}
+	if inUse {
+		return errors.ErrBaseCurrencyInUse
+	}
+	return errors.ErrGroupModified
+}
+
+// Condicion sobre el grupo g: tiene montos guardados en su moneda base
+const currencyInUse = `EXISTS (SELECT 1 FROM public.settlement st WHERE st.group_id = g.group_id)
+	OR EXISTS (SELECT 1 FROM public.budget b WHERE b.group_id = g.group_id)`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRowIntoUser(row rowScanner) (*models.Group, error) {
 
 	group := new(models.Group)
 	err := row.Scan(
@@ -115,10 +279,13 @@ func scanRowIntoUser(row *sql.Row) (*models.Group, error) {
 		&group.GroupName,
 		&group.Description,
 		&group.PhotoUrl,
+		&group.PhotoKey,
 		&group.CreatedAt,
 		&group.CreatedBy,
 		&group.UpdatedAt,
 		&group.UpdatedBy,
+		&group.SimplifyDebts,
+		&group.BaseCurrency,
 	)
 
 	if err != nil {
@@ -2,45 +2,62 @@ package groups
 
 import (
 	"log"
+	"time"
 
 	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/images"
 	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/storage"
 )
 
 type Service struct {
-	repository models.GroupRepository
+	repository     models.GroupRepository
+	authRepository models.AuthRepository
+	userRepository models.UserRepository
+	storage        models.Storage
 }
 
-func NewService(repository models.GroupRepository) *Service {
-	return &Service{repository: repository}
+func NewService(repository models.GroupRepository, authRepository models.AuthRepository, userRepository models.UserRepository, storage models.Storage) *Service {
+	return &Service{repository: repository, authRepository: authRepository, userRepository: userRepository, storage: storage}
 }
 
 func (s *Service) CreateGroup(payload models.CreateGroupPayload, userId []uint8) error {
 
 	group := models.Group{
-		GroupName:   payload.GroupName,
-		Description: payload.Description,
-		PhotoUrl:    payload.PhotoUrl,
-		CreatedBy:   userId,
-		UpdatedBy:   userId,
+		GroupName:    payload.GroupName,
+		Description:  payload.Description,
+		PhotoUrl:     payload.PhotoUrl,
+		BaseCurrency: payload.BaseCurrency,
+		CreatedBy:    userId,
+		UpdatedBy:    userId,
 	}
 
-	if err := s.repository.CreateGroup(group); err != nil {
+	if group.BaseCurrency == "" {
+		group.BaseCurrency = models.DefaultBaseCurrency
+	}
+
+	groupId, err := s.repository.CreateGroup(group)
+	if err != nil {
 		return errors.ErrCreateGroup(err.Error())
 	}
 
-	//asignar al userId permisos de admin sobre el grupo
-	// if err := s.
+	if group.PhotoUrl != "" {
+		return s.repository.UploadPhoto(group.PhotoUrl, groupId, userId)
+	}
 
+	s.storeIdenticon(groupId, userId)
 	return nil
 }
 
-func (s *Service) GetUserGroups(userId []uint8) ([]*models.Group, error) {
+func (s *Service) GetUserGroups(userId []uint8, page models.PageRequest) (*models.Page[*models.Group], error) {
 
-	g, err := s.repository.GetUserGroups(userId)
+	g, err := s.repository.GetUserGroups(userId, page)
 	if err != nil {
 		return nil, err
 	}
+	for _, group := range g.Items {
+		group.PhotoUrl, group.Thumbnails = images.URLs(s.storage, group.PhotoKey, group.PhotoUrl)
+	}
 	return g, nil
 
 }
@@ -52,6 +69,222 @@ func (s *Service) GetGroupById(groupId []uint8) (*models.Group, error) {
 	if err != nil {
 		return nil, err
 	}
+	g.PhotoUrl, g.Thumbnails = images.URLs(s.storage, g.PhotoKey, g.PhotoUrl)
 	return g, nil
 
 }
+
+func (s *Service) AddMember(payload models.AddMemberPayload, groupId []uint8, userId []uint8) error {
+
+	if _, err := s.repository.GetGroupById(groupId); err != nil {
+		return err
+	}
+
+	if err := validateValidUntil(payload.ValidUntil); err != nil {
+		return err
+	}
+
+	user, err := s.userRepository.GetUserByEmail(payload.Email)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.authRepository.GetRoleAssigment(user.UserId, groupId)
+	if err == nil {
+		return errors.ErrMemberAlreadyExist(payload.Email)
+	} else if err != errors.ErrMemberNotFound {
+		return err
+	}
+
+	assigment := models.RoleAssigment{
+		UserId:     user.UserId,
+		RoleId:     payload.RoleId,
+		GroupId:    groupId,
+		CreatedBy:  userId,
+		UpdatedBy:  userId,
+		ValidUntil: utcTime(payload.ValidUntil),
+	}
+
+	return s.authRepository.CreateRoleAssigment(assigment)
+}
+
+func (s *Service) GetGroupMembers(groupId []uint8, page models.PageRequest) (*models.Page[*models.GroupMember], error) {
+
+	members, err := s.authRepository.GetGroupMembers(groupId, page)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members.Items {
+		member.PhotoUrl, member.Thumbnails = images.URLs(s.storage, member.PhotoKey, member.PhotoUrl)
+	}
+	return members, nil
+}
+
+func (s *Service) UpdateMemberRole(payload models.UpdateMemberPayload, groupId []uint8, memberId []uint8, userId []uint8) error {
+
+	assigment, err := s.authRepository.GetRoleAssigment(memberId, groupId)
+	if err != nil {
+		return err
+	}
+
+	assigment.RoleId = payload.RoleId
+	assigment.UpdatedBy = userId
+
+	return s.authRepository.UpdateRoleAssigment(*assigment)
+}
+
+func (s *Service) RemoveMember(groupId []uint8, memberId []uint8) error {
+
+	return s.authRepository.DeleteRoleAssigment(memberId, groupId)
+}
+
+func (s *Service) UpdateMemberValidity(payload models.UpdateMemberValidityPayload, groupId []uint8, memberId []uint8, userId []uint8) error {
+
+	if err := validateValidUntil(payload.ValidUntil); err != nil {
+		return err
+	}
+
+	assigment, err := s.authRepository.GetRoleAssigment(memberId, groupId)
+	if err != nil {
+		return err
+	}
+
+	assigment.ValidUntil = utcTime(payload.ValidUntil)
+	assigment.UpdatedBy = userId
+
+	return s.authRepository.UpdateRoleValidity(*assigment)
+}
+
+func (s *Service) UpdateSimplifyDebts(payload models.UpdateSimplifyDebtsPayload, groupId []uint8, userId []uint8) error {
+
+	return s.repository.UpdateSimplifyDebts(groupId, payload.SimplifyDebts, userId)
+}
+
+// UpdateGroup changes the profile of the group and returns it as saved. The
+// base currency is fixed once settlements or budgets are stored in it.
+func (s *Service) UpdateGroup(payload models.UpdateGroupPayload, groupId []uint8, userId []uint8) (*models.Group, error) {
+
+	current, err := s.repository.GetGroupById(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	group := models.Group{
+		GroupId:      current.GroupId,
+		GroupName:    payload.GroupName,
+		Description:  payload.Description,
+		PhotoUrl:     payload.PhotoUrl,
+		BaseCurrency: payload.BaseCurrency,
+		UpdatedBy:    userId,
+	}
+
+	if err := s.repository.UpdateGroup(group, payload.UpdatedAt.UTC()); err != nil {
+		return nil, err
+	}
+
+	// A link replaces the stored photo
+	if payload.PhotoUrl != "" {
+		images.Delete(s.storage, current.PhotoKey)
+	}
+
+	return s.GetGroupById(groupId)
+}
+
+// UploadPhoto changes the photo of the group by a link to it
+func (s *Service) UploadPhoto(payload models.UploadPhotoPayload, groupId []uint8, userId []uint8) error {
+
+	group, err := s.repository.GetGroupById(groupId)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repository.UploadPhoto(payload.PhotoUrl, groupId, userId); err != nil {
+		return err
+	}
+
+	images.Delete(s.storage, group.PhotoKey)
+	return nil
+}
+
+// StorePhoto saves an uploaded photo of the group, without its metadata and
+// with its thumbnails, and returns a signed URL for it. The previous uploaded
+// photo is deleted.
+func (s *Service) StorePhoto(data []byte, groupId []uint8, userId []uint8) (string, error) {
+
+	group, err := s.repository.GetGroupById(groupId)
+	if err != nil {
+		return "", err
+	}
+
+	photo, err := images.Process(data)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := s.savePhoto(group, photo, userId)
+	if err != nil {
+		return "", err
+	}
+
+	return storage.SignURL(s.storage, key)
+}
+
+// Aux Functions
+
+// storeIdenticon gives a new group a generated photo. The group is already
+// created, so a failure only leaves it without photo.
+func (s *Service) storeIdenticon(groupId []uint8, userId []uint8) {
+
+	group, err := s.repository.GetGroupById(groupId)
+	if err != nil {
+		log.Printf("identicon for group %s: %v", groupId, err)
+		return
+	}
+
+	photo, err := images.Identicon(string(group.GroupId))
+	if err != nil {
+		log.Printf("identicon for group %s: %v", groupId, err)
+		return
+	}
+
+	if _, err := s.savePhoto(group, photo, userId); err != nil {
+		log.Printf("identicon for group %s: %v", groupId, err)
+	}
+}
+
+// savePhoto stores the photo as the photo of the group and deletes the
+// previous one
+func (s *Service) savePhoto(group *models.Group, photo *images.Photo, userId []uint8) (string, error) {
+
+	key, err := images.Store(s.storage, "groups/"+string(group.GroupId), photo)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.repository.UpdatePhotoKey(key, group.GroupId, userId); err != nil {
+		images.Delete(s.storage, key)
+		return "", err
+	}
+
+	images.Delete(s.storage, group.PhotoKey)
+	return key, nil
+}
+
+func validateValidUntil(validUntil *time.Time) error {
+
+	if validUntil != nil && !validUntil.After(time.Now()) {
+		return errors.ErrInvalidaPayload("validUntil must be in the future")
+	}
+
+	return nil
+}
+
+// user_role.valid_until and group.updated_at are stored without time zone
+func utcTime(t *time.Time) *time.Time {
+
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
@@ -0,0 +1,82 @@
+package images
+
+import (
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// An identicon is a grid of identiconCells x identiconCells squares,
+// mirrored around its vertical axis, drawn at identiconSize pixels
+const (
+	identiconCells = 5
+	identiconSize  = 420
+)
+
+// Identicon draws the default photo of a user or group. The same seed always
+// gives the same picture, so it is made from the id and not from a name that
+// can change.
+func Identicon(seed string) (*Photo, error) {
+
+	hash := sha256.Sum256([]byte(seed))
+
+	background := color.NRGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff}
+	foreground := hslColor(float64(uint16(hash[0])<<8|uint16(hash[1]))/65536*360, 0.55, 0.5)
+
+	img := image.NewNRGBA(image.Rect(0, 0, identiconSize, identiconSize))
+	draw.Draw(img, img.Bounds(), &image.Uniform{background}, image.Point{}, draw.Src)
+
+	// A margin of half a cell on every side
+	cell := identiconSize / (identiconCells + 1)
+	margin := (identiconSize - cell*identiconCells) / 2
+
+	half := (identiconCells + 1) / 2
+	for row := 0; row < identiconCells; row++ {
+		for col := 0; col < half; col++ {
+			// One bit of the hash for each cell of the left half
+			bit := row*half + col
+			if hash[2+bit/8]>>(bit%8)&1 == 0 {
+				continue
+			}
+			for _, c := range []int{col, identiconCells - 1 - col} {
+				rect := image.Rect(margin+c*cell, margin+row*cell, margin+(c+1)*cell, margin+(row+1)*cell)
+				draw.Draw(img, rect, &image.Uniform{foreground}, image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	return encode(img, imaging.PNG)
+}
+
+// Aux Functions
+
+// hslColor converts a hue in degrees, saturation and lightness to RGB
+func hslColor(hue float64, saturation float64, lightness float64) color.NRGBA {
+
+	chroma := (1 - math.Abs(2*lightness-1)) * saturation
+	h := hue / 60
+	x := chroma * (1 - math.Abs(math.Mod(h, 2)-1))
+
+	var r, g, b float64
+	switch {
+	case h < 1:
+		r, g = chroma, x
+	case h < 2:
+		r, g = x, chroma
+	case h < 3:
+		g, b = chroma, x
+	case h < 4:
+		g, b = x, chroma
+	case h < 5:
+		r, b = x, chroma
+	default:
+		r, b = chroma, x
+	}
+
+	m := lightness - chroma/2
+	return color.NRGBA{R: uint8((r + m) * 255), G: uint8((g + m) * 255), B: uint8((b + m) * 255), A: 0xff}
+}
@@ -0,0 +1,177 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/storage"
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp"
+)
+
+// Sizes in pixels of the square thumbnails stored next to every photo
+var ThumbnailSizes = []int{64, 128, 256}
+
+// Largest photo accepted, decoding is refused above it so a small file can
+// not expand into gigabytes of pixels
+const (
+	maxPixels    = 50_000_000
+	maxDimension = 12_000
+)
+
+// Quality of the JPEG photos written
+const jpegQuality = 88
+
+// Photo is an image ready to be stored, re-encoded from the decoded pixels so
+// nothing of the uploaded file but the image itself is kept: no EXIF, GPS or
+// other metadata.
+type Photo struct {
+	ContentType string
+	Extension   string
+	Original    []byte
+	Thumbnails  map[int][]byte
+}
+
+// Process checks that data really is a photo and not only a file with the
+// right header, turns it upright following its EXIF orientation and encodes
+// it again with its thumbnails. JPEG photos stay JPEG, the rest become PNG to
+// keep their transparency.
+func Process(data []byte) (*Photo, error) {
+
+	contentType, _, err := storage.Detect(data, storage.PhotoTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.ErrInvalidImage(err.Error())
+	}
+	if config.Width > maxDimension || config.Height > maxDimension || config.Width*config.Height > maxPixels {
+		return nil, errors.ErrInvalidImage(fmt.Sprintf("%dx%d pixels is larger than allowed", config.Width, config.Height))
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, errors.ErrInvalidImage(err.Error())
+	}
+
+	format := imaging.PNG
+	if contentType == "image/jpeg" {
+		format = imaging.JPEG
+	}
+
+	return encode(img, format)
+}
+
+// Store saves the photo and its thumbnails under prefix and returns the key
+// of the original. Nothing is left behind when a file can not be saved.
+func Store(s models.Storage, prefix string, photo *Photo) (string, error) {
+
+	key, err := storage.NewKey(prefix, photo.Extension)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.Put(key, bytes.NewReader(photo.Original), int64(len(photo.Original)), photo.ContentType); err != nil {
+		return "", err
+	}
+
+	for _, size := range ThumbnailSizes {
+		thumbnail := photo.Thumbnails[size]
+		if err := s.Put(ThumbnailKey(key, size), bytes.NewReader(thumbnail), int64(len(thumbnail)), photo.ContentType); err != nil {
+			Delete(s, key)
+			return "", err
+		}
+	}
+
+	return key, nil
+}
+
+// Delete removes a photo that is no longer used with its thumbnails. The
+// change of photo is already saved, so a failure is only logged.
+func Delete(s models.Storage, key string) {
+
+	if key == "" {
+		return
+	}
+
+	keys := []string{key}
+	for _, size := range ThumbnailSizes {
+		keys = append(keys, ThumbnailKey(key, size))
+	}
+
+	for _, key := range keys {
+		if err := s.Delete(key); err != nil {
+			log.Printf("deleting photo %s: %v", key, err)
+		}
+	}
+}
+
+// ThumbnailKey returns the key of the thumbnail of the photo, the photo key
+// with the size before the extension: "users/<id>/<name>_128.png"
+func ThumbnailKey(key string, size int) string {
+
+	extension := path.Ext(key)
+	return strings.TrimSuffix(key, extension) + "_" + strconv.Itoa(size) + extension
+}
+
+// URLs returns a signed URL for a stored photo and for each of its
+// thumbnails by size, or url and no thumbnails for photos that are only a
+// link
+func URLs(s models.Storage, key string, url string) (string, map[string]string) {
+
+	if key == "" {
+		return url, nil
+	}
+
+	photoUrl := storage.PhotoURL(s, key, url)
+
+	thumbnails := make(map[string]string, len(ThumbnailSizes))
+	for _, size := range ThumbnailSizes {
+		thumbnails[strconv.Itoa(size)] = storage.PhotoURL(s, ThumbnailKey(key, size), photoUrl)
+	}
+
+	return photoUrl, thumbnails
+}
+
+// Aux Functions
+
+func encode(img image.Image, format imaging.Format) (*Photo, error) {
+
+	photo := &Photo{ContentType: "image/png", Extension: ".png", Thumbnails: make(map[int][]byte, len(ThumbnailSizes))}
+	if format == imaging.JPEG {
+		photo.ContentType = "image/jpeg"
+		photo.Extension = ".jpg"
+	}
+
+	var err error
+	if photo.Original, err = encodeImage(img, format); err != nil {
+		return nil, err
+	}
+
+	for _, size := range ThumbnailSizes {
+		thumbnail := imaging.Fill(img, size, size, imaging.Center, imaging.Lanczos)
+		if photo.Thumbnails[size], err = encodeImage(thumbnail, format); err != nil {
+			return nil, err
+		}
+	}
+
+	return photo, nil
+}
+
+func encodeImage(img image.Image, format imaging.Format) ([]byte, error) {
+
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, img, format, imaging.JPEGQuality(jpegQuality)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,240 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+var (
+	red  = color.RGBA{R: 255, A: 255}
+	blue = color.RGBA{B: 255, A: 255}
+)
+
+func TestProcessStripsMetadataAndRotates(t *testing.T) {
+
+	// 40x20, red on the left and blue on the right, stored sideways: EXIF
+	// orientation 6 asks to turn it 90 degrees clockwise, so it shows 20x40
+	// with red on top
+	data := withExif(t, encodeJPEG(t, halves(40, 20)), exifSegment(6))
+
+	photo, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if photo.ContentType != "image/jpeg" || photo.Extension != ".jpg" {
+		t.Errorf("Process() = %s %s, want image/jpeg .jpg", photo.ContentType, photo.Extension)
+	}
+
+	files := map[string][]byte{"original": photo.Original}
+	for _, size := range ThumbnailSizes {
+		files[ThumbnailKey("thumbnail.jpg", size)] = photo.Thumbnails[size]
+	}
+	for name, file := range files {
+		for _, marker := range jpegMarkers(t, file) {
+			if marker == 0xE1 {
+				t.Errorf("%s keeps an APP1 segment", name)
+			}
+		}
+		if bytes.Contains(file, []byte("Exif\x00\x00")) {
+			t.Errorf("%s keeps the EXIF data", name)
+		}
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(photo.Original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size != image.Pt(20, 40) {
+		t.Fatalf("Process() size = %v, want 20x40", size)
+	}
+	if !near(img.At(10, 8), red) || !near(img.At(10, 32), blue) {
+		t.Errorf("Process() is not upright: top %v, bottom %v", img.At(10, 8), img.At(10, 32))
+	}
+}
+
+func TestProcessKeepsUprightPhotos(t *testing.T) {
+
+	photo, err := Process(withExif(t, encodeJPEG(t, halves(40, 20)), exifSegment(1)))
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(photo.Original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size != image.Pt(40, 20) {
+		t.Errorf("Process() size = %v, want 40x20", size)
+	}
+	if !near(img.At(5, 10), red) || !near(img.At(35, 10), blue) {
+		t.Errorf("Process() moved the pixels: left %v, right %v", img.At(5, 10), img.At(35, 10))
+	}
+}
+
+func TestProcessPNG(t *testing.T) {
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, halves(8, 8)); err != nil {
+		t.Fatal(err)
+	}
+	// A text chunk before the end, where editors write comments and locations
+	data := buf.Bytes()
+	data = append(data[:len(data)-12:len(data)-12], append(pngChunk("tEXt", "Comment\x00taken at home"), data[len(data)-12:]...)...)
+
+	photo, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if photo.ContentType != "image/png" || bytes.Contains(photo.Original, []byte("taken at home")) {
+		t.Errorf("Process() = %s keeping the text chunk, want a png without it", photo.ContentType)
+	}
+	for _, size := range ThumbnailSizes {
+		img, err := png.Decode(bytes.NewReader(photo.Thumbnails[size]))
+		if err != nil {
+			t.Fatalf("thumbnail %d: %v", size, err)
+		}
+		if got := img.Bounds().Size(); got != image.Pt(size, size) {
+			t.Errorf("thumbnail %d size = %v", size, got)
+		}
+	}
+}
+
+func TestProcessRejectsOtherFiles(t *testing.T) {
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "html", data: []byte("<html><body>photo</body></html>")},
+		{name: "truncated jpeg", data: encodeJPEG(t, halves(40, 20))[:200]},
+		{name: "pdf", data: []byte("%PDF-1.4\n1 0 obj\n<<>>\nendobj\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Process(tt.data); err == nil {
+				t.Errorf("Process() accepted the file")
+			}
+		})
+	}
+}
+
+// Aux Functions
+
+func halves(width int, height int) *image.RGBA {
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			if x < width/2 {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// exifSegment returns an APP1 segment with the orientation and a GPS IFD
+// holding a latitude
+func exifSegment(orientation uint16) []byte {
+
+	le := binary.LittleEndian
+	var tiff []byte
+	tiff = append(tiff, 'I', 'I', 42, 0)
+	tiff = le.AppendUint32(tiff, 8)
+
+	// IFD0: orientation and the pointer to the GPS IFD, which follows it
+	tiff = le.AppendUint16(tiff, 2)
+	tiff = appendEntry(tiff, 0x0112, 3, 1, uint32(orientation))
+	tiff = appendEntry(tiff, 0x8825, 4, 1, 8+2+2*12+4)
+	tiff = le.AppendUint32(tiff, 0)
+
+	// GPS IFD: GPSLatitudeRef "N"
+	tiff = le.AppendUint16(tiff, 1)
+	tiff = appendEntry(tiff, 0x0001, 2, 2, uint32('N'))
+	tiff = le.AppendUint32(tiff, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func appendEntry(tiff []byte, tag uint16, kind uint16, count uint32, value uint32) []byte {
+
+	le := binary.LittleEndian
+	tiff = le.AppendUint16(tiff, tag)
+	tiff = le.AppendUint16(tiff, kind)
+	tiff = le.AppendUint32(tiff, count)
+	return le.AppendUint32(tiff, value)
+}
+
+// withExif puts the segment right after the start of image marker
+func withExif(t *testing.T, data []byte, segment []byte) []byte {
+
+	t.Helper()
+
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		t.Fatal("not a jpeg")
+	}
+	return append(append([]byte{0xFF, 0xD8}, segment...), data[2:]...)
+}
+
+// jpegMarkers lists the markers of the segments before the image data
+func jpegMarkers(t *testing.T, data []byte) []byte {
+
+	t.Helper()
+
+	var markers []byte
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			t.Fatalf("invalid jpeg segment at %d", i)
+		}
+		marker := data[i+1]
+		markers = append(markers, marker)
+		if marker == 0xDA {
+			break
+		}
+		i += 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+	}
+	return markers
+}
+
+func pngChunk(kind string, content string) []byte {
+
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(content)))
+	chunk = append(chunk, kind...)
+	chunk = append(chunk, content...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func near(c color.Color, want color.RGBA) bool {
+
+	r, g, b, _ := c.RGBA()
+	diff := func(got uint32, want uint8) bool {
+		d := int(got>>8) - int(want)
+		return d > -48 && d < 48
+	}
+	return diff(r, want.R) && diff(g, want.G) && diff(b, want.B)
+}
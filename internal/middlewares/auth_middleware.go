@@ -8,6 +8,7 @@ import (
 	"github.com/PabloPei/SmartSpend-backend/internal/errors"
 	"github.com/PabloPei/SmartSpend-backend/internal/models"
 	"github.com/PabloPei/SmartSpend-backend/utils"
+	"github.com/gorilla/mux"
 )
 
 func WithJWTAuth(handlerFunc http.HandlerFunc) http.HandlerFunc {
@@ -64,26 +65,40 @@ func WithRefreshTokenAuth(handlerFunc http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-/* TODO middle ware para verificar los permisos
-func RequirePermission(handlerFunc http.HandlerFunc, permission string) http.HandlerFunc {
-	return func(next http.HandlerFunc) http.HandlerFunc {
-		userID := 1   // Simulación (debe venir del contexto o JWT)
-		groupID := 10 // Simulación (debe venir de la ruta o sesión)
+// RequirePermission must be wrapped by WithJWTAuth, it reads the user from the
+// context and the group from the route
+func RequirePermission(handlerFunc http.HandlerFunc, permission string, repository models.AuthRepository) http.HandlerFunc {
 
-		// Verificar si el usuario tiene el permiso necesario
-		hasPermission, err := userHasSpecificPermission(db, userID, groupID, permission)
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userId, err := auth.GetUserIDFromContext(r.Context())
 		if err != nil {
-			http.Error(w, "Error verificando permisos", http.StatusInternalServerError)
+			utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+			return
+		}
+
+		groupId, ok := mux.Vars(r)["groupId"]
+		if !ok {
+			utils.WriteError(w, http.StatusBadRequest, errors.ErrGroupNotFound)
+			return
+		}
+		// Postgres rejects ids that are not uuids, no group can have one
+		if utils.Validate.Var(groupId, "uuid") != nil {
+			utils.WriteError(w, http.StatusNotFound, errors.ErrGroupNotFound)
+			return
+		}
+
+		hasPermission, err := repository.UserHasPermission(userId, []uint8(groupId), permission)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
 			return
 		}
 
 		if !hasPermission {
-			http.Error(w, "No tienes permisos para esta acción", http.StatusForbidden)
+			utils.WriteError(w, http.StatusForbidden, errors.ErrPermissionDenied(permission))
 			return
 		}
 
-		// Si tiene permisos, ejecutar el handler
-		next(w, r)
+		handlerFunc(w, r)
 	}
 }
-*/
@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/gorilla/mux"
+)
+
+func TestRequirePermission(t *testing.T) {
+
+	groupId := "7f9c24e5-2d3b-4a8e-9c61-0b5e8d4f1a23"
+	repository := &permissionRepository{groups: map[string]bool{groupId: true}}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/group/{groupId}", RequirePermission(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}, models.PermissionViewGroup, repository))
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{name: "member", target: "/group/" + groupId, status: http.StatusNoContent},
+		{name: "not a member", target: "/group/0b5e8d4f-1a23-4a8e-9c61-7f9c24e52d3b", status: http.StatusForbidden},
+		{name: "not a uuid", target: "/group/my-group", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository.calls = 0
+			request := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			request = request.WithContext(context.WithValue(request.Context(), models.UserKey, "user"))
+			recorder := httptest.NewRecorder()
+
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.status {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.status)
+			}
+			if tt.status == http.StatusNotFound && repository.calls != 0 {
+				t.Errorf("RequirePermission() asked the repository about an invalid id")
+			}
+		})
+	}
+}
+
+// Aux Functions
+
+// permissionRepository grants every permission on its groups. The other
+// methods are not used by the middleware.
+type permissionRepository struct {
+	models.AuthRepository
+	groups map[string]bool
+	calls  int
+}
+
+func (r *permissionRepository) UserHasPermission(userId []uint8, groupId []uint8, permission string) (bool, error) {
+
+	r.calls++
+	return r.groups[string(groupId)], nil
+}
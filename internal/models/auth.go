@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	RoleViewer = "V"
+	RoleEditor = "E"
+	RoleAdmin  = "A"
+)
+
+const (
+	PermissionViewGroup     = "VIEW_GROUP"
+	PermissionEditMovements = "EDIT_MOVEMENTS"
+	PermissionManageFields  = "MANAGE_FIELDS"
+	PermissionManageMembers = "MANAGE_MEMBERS"
+	PermissionEditGroup     = "EDIT_GROUP"
+)
+
+type RoleAssigment struct {
+	UserId     []uint8    `json:"userId"`
+	RoleId     string     `json:"roleId"`
+	GroupId    []uint8    `json:"groupId"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	CreatedBy  []uint8    `json:"createdBy"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	UpdatedBy  []uint8    `json:"updatedBy"`
+	ValidUntil *time.Time `json:"validUntil"`
+}
+
+type AuthRepository interface {
+	CreateRoleAssigment(RoleAssigment) error
+	GetRoleAssigment(userId []uint8, groupId []uint8) (*RoleAssigment, error)
+	GetGroupMembers(groupId []uint8, page PageRequest) (*Page[*GroupMember], error)
+	// The role, delete and validity changes fail with ErrLastGroupAdmin when
+	// they would leave the group without a permanent admin
+	UpdateRoleAssigment(RoleAssigment) error
+	DeleteRoleAssigment(userId []uint8, groupId []uint8) error
+	UserHasPermission(userId []uint8, groupId []uint8, permission string) (bool, error)
+	UpdateRoleValidity(RoleAssigment) error
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Version of the archive written by backups. Restore only accepts archives
+// with this version.
+const BackupVersion = 1
+
+const (
+	RestoreConflictVersion   = "version"
+	RestoreConflictUser      = "user"
+	RestoreConflictLanguage  = "language"
+	RestoreConflictReference = "reference"
+	RestoreConflictDuplicate = "duplicate"
+	RestoreConflictInvalid   = "invalid"
+)
+
+// GroupBackup is the archive of a group. Ids are the ones of the server that
+// wrote it: restore gives everything new ids and matches users by email.
+type GroupBackup struct {
+	Version     int                 `json:"version"`
+	CreatedAt   time.Time           `json:"createdAt"`
+	Group       BackupGroup         `json:"group"`
+	Users       []*BackupUser       `json:"users"`
+	Members     []*BackupMember     `json:"members"`
+	Categories  []*BackupCategory   `json:"categories"`
+	Fields      []*BackupField      `json:"fields"`
+	Movements   []*BackupMovement   `json:"movements"`
+	Settlements []*BackupSettlement `json:"settlements"`
+}
+
+type BackupGroup struct {
+	Id            string       `json:"id"`
+	Name          string       `json:"name"`
+	Description   string       `json:"description"`
+	PhotoUrl      string       `json:"photoUrl"`
+	PhotoKey      string       `json:"-"`
+	Photo         *BackupPhoto `json:"photo,omitempty"`
+	SimplifyDebts bool         `json:"simplifyDebts"`
+	BaseCurrency  string       `json:"baseCurrency"`
+	CreatedAt     time.Time    `json:"createdAt"`
+}
+
+// BackupUser is every user the archive refers to: members, former members
+// that are still in movements or settlements, and so on. Restore only gives
+// Photo to users of this server that have no photo.
+type BackupUser struct {
+	Id       string       `json:"id"`
+	Email    string       `json:"email"`
+	UserName string       `json:"userName"`
+	PhotoUrl string       `json:"photoUrl"`
+	PhotoKey string       `json:"-"`
+	Photo    *BackupPhoto `json:"photo,omitempty"`
+}
+
+// BackupPhoto is a photo kept in storage, copied into the archive because
+// its key means nothing to another server. Data is base64 in the JSON.
+type BackupPhoto struct {
+	ContentType string `json:"contentType"`
+	Data        []byte `json:"data"`
+}
+
+type BackupMember struct {
+	UserId      string     `json:"userId"`
+	RoleId      string     `json:"roleId"`
+	MemberSince time.Time  `json:"memberSince"`
+	ValidUntil  *time.Time `json:"validUntil"`
+}
+
+type BackupCategory struct {
+	Id    string            `json:"id"`
+	Code  string            `json:"code,omitempty"`
+	Names map[string]string `json:"names"`
+}
+
+type BackupField struct {
+	Id       string   `json:"id"`
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options"`
+}
+
+type BackupMovement struct {
+	Id                string               `json:"id"`
+	Date              time.Time            `json:"date"`
+	Amount            decimal.Decimal      `json:"amount"`
+	Currency          string               `json:"currency"`
+	Description       string               `json:"description"`
+	SplitMode         string               `json:"splitMode"`
+	Tax               decimal.Decimal      `json:"tax"`
+	Tip               decimal.Decimal      `json:"tip"`
+	CategoryId        string               `json:"categoryId,omitempty"`
+	BankTransactionId string               `json:"bankTransactionId,omitempty"`
+	Participants      []*BackupParticipant `json:"participants"`
+	Items             []*BackupItem        `json:"items,omitempty"`
+	Fields            []*BackupFieldValue  `json:"fields"`
+	CreatedAt         time.Time            `json:"createdAt"`
+	CreatedBy         string               `json:"createdBy,omitempty"`
+}
+
+type BackupParticipant struct {
+	UserId     string           `json:"userId"`
+	Paid       decimal.Decimal  `json:"paid"`
+	Owed       decimal.Decimal  `json:"owed"`
+	SplitValue *decimal.Decimal `json:"splitValue"`
+}
+
+// BackupItem is a line of an itemized movement, in receipt order
+type BackupItem struct {
+	Description  string                   `json:"description"`
+	Amount       decimal.Decimal          `json:"amount"`
+	Extra        decimal.Decimal          `json:"extra"`
+	Participants []*BackupItemParticipant `json:"participants"`
+}
+
+type BackupItemParticipant struct {
+	UserId string          `json:"userId"`
+	Owed   decimal.Decimal `json:"owed"`
+}
+
+type BackupFieldValue struct {
+	FieldId string `json:"fieldId"`
+	Value   string `json:"value"`
+}
+
+type BackupSettlement struct {
+	Id         string          `json:"id"`
+	FromUserId string          `json:"fromUserId"`
+	ToUserId   string          `json:"toUserId"`
+	Amount     decimal.Decimal `json:"amount"`
+	Date       time.Time       `json:"date"`
+	Note       string          `json:"note"`
+	Status     string          `json:"status"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	CreatedBy  string          `json:"createdBy,omitempty"`
+}
+
+// RestoreConflict is a problem of the archive that stops the restore. Id is
+// the archive id of the entry, when there is one.
+type RestoreConflict struct {
+	Type    string `json:"type"`
+	Id      string `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+type RestoreResult struct {
+	DryRun      bool              `json:"dryRun"`
+	GroupId     []uint8           `json:"groupId,omitempty"`
+	Members     int               `json:"members"`
+	Categories  int               `json:"categories"`
+	Fields      int               `json:"fields"`
+	Movements   int               `json:"movements"`
+	Settlements int               `json:"settlements"`
+	Conflicts   []RestoreConflict `json:"conflicts"`
+}
+
+type BackupRepository interface {
+	GetGroupBackup(groupId []uint8) (*GroupBackup, error)
+	// users maps the archive user ids to the ids of this server
+	RestoreGroup(backup *GroupBackup, users map[string][]uint8, userId []uint8) ([]uint8, error)
+	GetUsersSharingGroup(userId []uint8, users []string) (map[string]bool, error)
+}
+
+type BackupService interface {
+	BackupGroup(groupId []uint8) (*GroupBackup, error)
+	RestoreGroup(backup *GroupBackup, dryRun bool, userId []uint8) (*RestoreResult, error)
+}
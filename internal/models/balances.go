@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// LedgerEntry is a movement seen by the balance engine: how much each user
+// paid and how much each user owes of it, keyed by user id. A settlement is
+// an entry where the payer paid the amount and the receiver owes it.
+// Currency and Date are used to convert the amounts to the base currency of
+// the group; an empty Currency means the amounts already are in it.
+type LedgerEntry struct {
+	MovementId []uint8
+	Settlement bool
+	Currency   string
+	Date       time.Time
+	Paid       map[string]decimal.Decimal
+	Owed       map[string]decimal.Decimal
+}
+
+type BalanceMember struct {
+	UserId   []uint8 `json:"userId"`
+	UserName string  `json:"userName"`
+}
+
+type MemberBalance struct {
+	UserId   []uint8         `json:"userId"`
+	UserName string          `json:"userName"`
+	Paid     decimal.Decimal `json:"paid"`
+	Owed     decimal.Decimal `json:"owed"`
+	Sent     decimal.Decimal `json:"sent"`
+	Received decimal.Decimal `json:"received"`
+	Net      decimal.Decimal `json:"net"`
+}
+
+type Debt struct {
+	From     []uint8         `json:"from"`
+	FromName string          `json:"fromName"`
+	To       []uint8         `json:"to"`
+	ToName   string          `json:"toName"`
+	Amount   decimal.Decimal `json:"amount"`
+}
+
+type GroupBalances struct {
+	GroupId  []uint8          `json:"groupId"`
+	Currency string           `json:"currency"`
+	Members  []*MemberBalance `json:"members"`
+	Debts    []*Debt          `json:"debts"`
+}
+
+type SettleUp struct {
+	GroupId    []uint8 `json:"groupId"`
+	Currency   string  `json:"currency"`
+	Simplified bool    `json:"simplified"`
+	Transfers  []*Debt `json:"transfers"`
+}
+
+type BalanceRepository interface {
+	GetBalanceMembers(groupId []uint8) ([]*BalanceMember, error)
+	GetGroupLedger(groupId []uint8) ([]*LedgerEntry, error)
+	GetSimplifyDebts(groupId []uint8) (bool, error)
+	GetBaseCurrency(groupId []uint8) (string, error)
+}
+
+type BalanceService interface {
+	GetGroupBalances(groupId []uint8) (*GroupBalances, error)
+	GetSettleUp(groupId []uint8) (*SettleUp, error)
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	BudgetPeriodMonthly = "monthly"
+	BudgetPeriodCustom  = "custom"
+)
+
+// Thresholds of the budgets created without them, in percent
+var DefaultBudgetThresholds = []int64{80, 100}
+
+// A Budget without CategoryId applies to every movement of the group. Custom
+// budgets cover StartDate to EndDate, monthly ones every calendar month.
+type Budget struct {
+	BudgetId   []uint8         `json:"budgetId"`
+	GroupId    []uint8         `json:"groupId"`
+	CategoryId []uint8         `json:"categoryId"`
+	Amount     decimal.Decimal `json:"amount"`
+	Period     string          `json:"period"`
+	StartDate  *time.Time      `json:"startDate"`
+	EndDate    *time.Time      `json:"endDate"`
+	Thresholds []int64         `json:"thresholds"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	UpdatedAt  time.Time       `json:"updatedAt"`
+	CreatedBy  []uint8         `json:"createdBy"`
+	UpdatedBy  []uint8         `json:"updatedBy"`
+}
+
+// BudgetStatus is the spending of a budget in one of its periods
+type BudgetStatus struct {
+	*Budget
+	PeriodStart time.Time       `json:"periodStart"`
+	PeriodEnd   time.Time       `json:"periodEnd"`
+	Currency    string          `json:"currency"`
+	Spent       decimal.Decimal `json:"spent"`
+	Remaining   decimal.Decimal `json:"remaining"`
+	PercentUsed decimal.Decimal `json:"percentUsed"`
+}
+
+// BudgetAlert is produced once per budget, threshold and period. Alerts stay
+// pending until the notification layer reads them and marks them as delivered.
+type BudgetAlert struct {
+	BudgetAlertId []uint8         `json:"budgetAlertId"`
+	BudgetId      []uint8         `json:"budgetId"`
+	GroupId       []uint8         `json:"groupId"`
+	CategoryId    []uint8         `json:"categoryId"`
+	Threshold     int64           `json:"threshold"`
+	PeriodStart   time.Time       `json:"periodStart"`
+	PeriodEnd     time.Time       `json:"periodEnd"`
+	Amount        decimal.Decimal `json:"amount"`
+	Spent         decimal.Decimal `json:"spent"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	DeliveredAt   *time.Time      `json:"deliveredAt"`
+}
+
+// SpendingEntry is a movement amount counted against a budget
+type SpendingEntry struct {
+	Amount   decimal.Decimal
+	Currency string
+	Date     time.Time
+}
+
+type BudgetRepository interface {
+	CreateBudget(Budget) ([]uint8, error)
+	GetBudgetById(groupId []uint8, budgetId []uint8) (*Budget, error)
+	GetGroupBudgets(groupId []uint8) ([]*Budget, error)
+	UpdateBudget(Budget) error
+	DeleteBudget(groupId []uint8, budgetId []uint8) error
+	GetSpending(groupId []uint8, categoryId []uint8, from time.Time, to time.Time) ([]*SpendingEntry, error)
+	GetBaseCurrency(groupId []uint8) (string, error)
+	CreateAlert(BudgetAlert) (bool, error)
+	GetGroupAlerts(groupId []uint8) ([]*BudgetAlert, error)
+	GetPendingAlerts(groupId []uint8) ([]*BudgetAlert, error)
+	MarkAlertDelivered(groupId []uint8, alertId []uint8) error
+}
+
+type BudgetService interface {
+	CreateBudget(payload CreateBudgetPayload, groupId []uint8, userId []uint8) (*Budget, error)
+	GetGroupBudgets(groupId []uint8) ([]*BudgetStatus, error)
+	UpdateBudget(payload UpdateBudgetPayload, groupId []uint8, budgetId []uint8, userId []uint8) error
+	DeleteBudget(groupId []uint8, budgetId []uint8) error
+	GetGroupAlerts(groupId []uint8) ([]*BudgetAlert, error)
+	GetPendingAlerts(groupId []uint8) ([]*BudgetAlert, error)
+	MarkAlertDelivered(groupId []uint8, alertId []uint8) error
+	CheckBudgets(groupId []uint8, categoryId []uint8, date time.Time) ([]*BudgetAlert, error)
+}
+
+type CreateBudgetPayload struct {
+	CategoryId string          `json:"categoryId" validate:"omitempty,uuid"`
+	Amount     decimal.Decimal `json:"amount"`
+	Period     string          `json:"period" validate:"required,oneof=monthly custom"`
+	StartDate  string          `json:"startDate" validate:"required_if=Period custom,omitempty,datetime=2006-01-02"`
+	EndDate    string          `json:"endDate" validate:"required_if=Period custom,omitempty,datetime=2006-01-02"`
+	Thresholds []int64         `json:"thresholds" validate:"omitempty,unique,dive,min=1,max=1000"`
+}
+
+type UpdateBudgetPayload struct {
+	CategoryId string          `json:"categoryId" validate:"omitempty,uuid"`
+	Amount     decimal.Decimal `json:"amount"`
+	Period     string          `json:"period" validate:"required,oneof=monthly custom"`
+	StartDate  string          `json:"startDate" validate:"required_if=Period custom,omitempty,datetime=2006-01-02"`
+	EndDate    string          `json:"endDate" validate:"required_if=Period custom,omitempty,datetime=2006-01-02"`
+	Thresholds []int64         `json:"thresholds" validate:"omitempty,unique,dive,min=1,max=1000"`
+}
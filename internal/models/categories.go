@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+// Names are returned in the language of the caller, falling back to this one
+const DefaultLanguage = "en"
+
+// Category holds every translation of its name in Names, keyed by language
+// code. Name and Language are the translation chosen for the caller.
+type Category struct {
+	CategoryId []uint8           `json:"categoryId"`
+	GroupId    []uint8           `json:"groupId"`
+	Code       string            `json:"code,omitempty"`
+	Name       string            `json:"name"`
+	Language   string            `json:"language"`
+	Names      map[string]string `json:"names"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	UpdatedAt  time.Time         `json:"updatedAt"`
+	CreatedBy  []uint8           `json:"createdBy"`
+	UpdatedBy  []uint8           `json:"updatedBy"`
+}
+
+type CategoryRepository interface {
+	CreateCategory(Category) ([]uint8, error)
+	GetCategoryById(groupId []uint8, categoryId []uint8) (*Category, error)
+	GetGroupCategories(groupId []uint8) ([]*Category, error)
+	UpdateCategory(Category) error
+	DeleteCategory(groupId []uint8, categoryId []uint8) error
+	GetLanguages() ([]string, error)
+}
+
+type CategoryService interface {
+	CreateCategory(payload CreateCategoryPayload, groupId []uint8, userId []uint8) (*Category, error)
+	GetCategoryById(groupId []uint8, categoryId []uint8, userId []uint8) (*Category, error)
+	GetGroupCategories(groupId []uint8, userId []uint8) ([]*Category, error)
+	UpdateCategory(payload UpdateCategoryPayload, groupId []uint8, categoryId []uint8, userId []uint8) error
+	DeleteCategory(groupId []uint8, categoryId []uint8) error
+}
+
+// Names maps a language code from conf.language to the category name
+type CreateCategoryPayload struct {
+	Names map[string]string `json:"names" validate:"required,min=1,dive,keys,required,max=10,endkeys,required,max=50"`
+}
+
+type UpdateCategoryPayload struct {
+	Names map[string]string `json:"names" validate:"required,min=1,dive,keys,required,max=10,endkeys,required,max=50"`
+}
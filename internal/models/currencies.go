@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// The ECB publishes every rate against the euro, so it is used as the
+// intermediate currency when there is no direct rate between two currencies.
+const PivotCurrency = "EUR"
+
+// Base currency of the groups created without one
+const DefaultBaseCurrency = "USD"
+
+// One unit of BaseCurrency is worth Rate units of QuoteCurrency on RateDate
+type ExchangeRate struct {
+	RateDate      time.Time       `json:"rateDate"`
+	BaseCurrency  string          `json:"baseCurrency"`
+	QuoteCurrency string          `json:"quoteCurrency"`
+	Rate          decimal.Decimal `json:"rate"`
+}
+
+type ExchangeRateRepository interface {
+	SaveExchangeRates(rates []ExchangeRate) (int64, error)
+	GetExchangeRate(baseCurrency string, quoteCurrency string, date time.Time) (*ExchangeRate, error)
+}
+
+type ExchangeRateService interface {
+	LoadFile(path string) (int64, error)
+	GetRate(from string, to string, date time.Time) (decimal.Decimal, error)
+	Convert(amount decimal.Decimal, from string, to string, date time.Time) (decimal.Decimal, error)
+}
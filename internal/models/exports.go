@@ -0,0 +1,53 @@
+package models
+
+import (
+	"io"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ExportFormatCSV       = "csv"
+	ExportFormatXLSX      = "xlsx"
+	ExportFormatJSON      = "json"
+	ExportFormatLedger    = "ledger"
+	ExportFormatBeancount = "beancount"
+)
+
+// ExportEntry is a movement or a settlement of a group export. Movements
+// have participants and fields, settlements From, To and Status.
+type ExportEntry struct {
+	Type         string               `json:"type"`
+	Id           []uint8              `json:"id"`
+	Date         time.Time            `json:"date"`
+	Amount       decimal.Decimal      `json:"amount"`
+	Currency     string               `json:"currency"`
+	Description  string               `json:"description"`
+	CategoryId   []uint8              `json:"-"`
+	Category     string               `json:"category,omitempty"`
+	SplitMode    string               `json:"splitMode,omitempty"`
+	Participants []*ExportParticipant `json:"participants,omitempty"`
+	Fields       []MovementFieldValue `json:"fields,omitempty"`
+	From         string               `json:"from,omitempty"`
+	To           string               `json:"to,omitempty"`
+	Status       string               `json:"status,omitempty"`
+}
+
+type ExportParticipant struct {
+	UserId   []uint8         `json:"userId"`
+	UserName string          `json:"userName"`
+	Paid     decimal.Decimal `json:"paid"`
+	Owed     decimal.Decimal `json:"owed"`
+}
+
+// The repository calls fn once per entry while it reads the rows, so exports
+// never hold the whole group in memory
+type ExportRepository interface {
+	StreamMovements(groupId []uint8, fn func(*ExportEntry) error) error
+	StreamSettlements(groupId []uint8, fn func(*ExportEntry) error) error
+}
+
+type ExportService interface {
+	Export(groupId []uint8, userId []uint8, format string, w io.Writer) error
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	FieldTypeText    = "text"
+	FieldTypeNumber  = "number"
+	FieldTypeDate    = "date"
+	FieldTypeBoolean = "boolean"
+	FieldTypeSelect  = "select"
+)
+
+type MovementField struct {
+	MovementFieldId []uint8   `json:"movementFieldId"`
+	GroupId         []uint8   `json:"groupId"`
+	Name            string    `json:"name"`
+	Type            string    `json:"type"`
+	Required        bool      `json:"required"`
+	Options         []string  `json:"options"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+	CreatedBy       []uint8   `json:"createdBy"`
+	UpdatedBy       []uint8   `json:"updatedBy"`
+}
+
+type MovementFieldValue struct {
+	MovementFieldId []uint8 `json:"movementFieldId"`
+	Name            string  `json:"name"`
+	Value           string  `json:"value"`
+}
+
+type FieldRepository interface {
+	CreateField(MovementField) ([]uint8, error)
+	GetFieldById(groupId []uint8, fieldId []uint8) (*MovementField, error)
+	GetFieldByName(groupId []uint8, name string) (*MovementField, error)
+	GetGroupFields(groupId []uint8) ([]*MovementField, error)
+	UpdateField(MovementField) error
+	DeleteField(groupId []uint8, fieldId []uint8) error
+}
+
+type FieldService interface {
+	CreateField(payload CreateFieldPayload, groupId []uint8, userId []uint8) (*MovementField, error)
+	GetFieldById(groupId []uint8, fieldId []uint8) (*MovementField, error)
+	GetGroupFields(groupId []uint8) ([]*MovementField, error)
+	UpdateField(payload UpdateFieldPayload, groupId []uint8, fieldId []uint8, userId []uint8) error
+	DeleteField(groupId []uint8, fieldId []uint8) error
+}
+
+type CreateFieldPayload struct {
+	Name     string   `json:"name" validate:"required,max=50"`
+	Type     string   `json:"type" validate:"required,oneof=text number date boolean select"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options" validate:"omitempty,unique,dive,required,max=255"`
+}
+
+type UpdateFieldPayload struct {
+	Name     string   `json:"name" validate:"required,max=50"`
+	Type     string   `json:"type" validate:"required,oneof=text number date boolean select"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options" validate:"omitempty,unique,dive,required,max=255"`
+}
@@ -5,33 +5,94 @@ import (
 )
 
 type Group struct {
-	GroupId     []uint8   `json:"groupId"`
-	GroupName   string    `json:"groupName"`
-	Description string    `json:"description"`
-	PhotoUrl    string    `json:"photoUrl"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
-	CreatedBy   []uint8   `json:"createdBy"`
-	UpdatedBy   []uint8   `json:"updatedBy"`
+	GroupId       []uint8           `json:"groupId"`
+	GroupName     string            `json:"groupName"`
+	Description   string            `json:"description"`
+	PhotoUrl      string            `json:"photoUrl"`
+	PhotoKey      string            `json:"-"`
+	Thumbnails    map[string]string `json:"thumbnails,omitempty"`
+	SimplifyDebts bool              `json:"simplifyDebts"`
+	BaseCurrency  string            `json:"baseCurrency"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	UpdatedAt     time.Time         `json:"updatedAt"`
+	CreatedBy     []uint8           `json:"createdBy"`
+	UpdatedBy     []uint8           `json:"updatedBy"`
+}
+
+type GroupMember struct {
+	UserId      []uint8           `json:"userId"`
+	UserName    string            `json:"userName"`
+	Email       string            `json:"email"`
+	PhotoUrl    string            `json:"photoUrl"`
+	PhotoKey    string            `json:"-"`
+	Thumbnails  map[string]string `json:"thumbnails,omitempty"`
+	RoleId      string            `json:"roleId"`
+	RoleName    string            `json:"roleName"`
+	MemberSince time.Time         `json:"memberSince"`
+	ValidUntil  *time.Time        `json:"validUntil"`
+	Expired     bool              `json:"expired"`
 }
 
 type GroupRepository interface {
-	CreateGroup(Group) error
+	CreateGroup(Group) ([]uint8, error)
 	GetGroupById(groupId []uint8) (*Group, error)
 	GetGroupByName(name string) (*Group, error)
 	GetUserGroupByName(user []uint8, name string) (*Group, error)
-	UploadPhoto(photoUrl string, groupId []uint8) error
-	GetUserGroups(user []uint8) ([]*Group, error)
+	UploadPhoto(photoUrl string, groupId []uint8, userId []uint8) error
+	UpdatePhotoKey(photoKey string, groupId []uint8, userId []uint8) error
+	GetUserGroups(user []uint8, page PageRequest) (*Page[*Group], error)
+	UpdateSimplifyDebts(groupId []uint8, simplifyDebts bool, userId []uint8) error
+	UpdateGroup(group Group, updatedAt time.Time) error
 }
 
 type GroupService interface {
 	CreateGroup(payload CreateGroupPayload, userId []uint8) error
 	GetGroupById(groupId []uint8) (*Group, error)
-	GetUserGroups(userId []uint8) ([]*Group, error)
+	GetUserGroups(userId []uint8, page PageRequest) (*Page[*Group], error)
+	AddMember(payload AddMemberPayload, groupId []uint8, userId []uint8) error
+	GetGroupMembers(groupId []uint8, page PageRequest) (*Page[*GroupMember], error)
+	UpdateMemberRole(payload UpdateMemberPayload, groupId []uint8, memberId []uint8, userId []uint8) error
+	RemoveMember(groupId []uint8, memberId []uint8) error
+	UpdateMemberValidity(payload UpdateMemberValidityPayload, groupId []uint8, memberId []uint8, userId []uint8) error
+	UpdateSimplifyDebts(payload UpdateSimplifyDebtsPayload, groupId []uint8, userId []uint8) error
+	StorePhoto(data []byte, groupId []uint8, userId []uint8) (string, error)
+	UploadPhoto(payload UploadPhotoPayload, groupId []uint8, userId []uint8) error
+	UpdateGroup(payload UpdateGroupPayload, groupId []uint8, userId []uint8) (*Group, error)
 }
 
 type CreateGroupPayload struct {
-	GroupName   string `json:"groupName" validate:"required"`
-	Description string `json:"description" validate:"required"`
-	PhotoUrl    string `json:"photoUrl" validate:"omitempty,uri"`
+	GroupName    string `json:"groupName" validate:"required"`
+	Description  string `json:"description" validate:"required"`
+	PhotoUrl     string `json:"photoUrl" validate:"omitempty,uri"`
+	BaseCurrency string `json:"baseCurrency" validate:"omitempty,iso4217"`
+}
+
+// UpdatedAt is the updatedAt of the group as the client last read it, the
+// update is refused when the group was changed since. A missing photoUrl
+// keeps the current photo.
+type UpdateGroupPayload struct {
+	GroupName    string    `json:"groupName" validate:"required,max=50"`
+	Description  string    `json:"description" validate:"required"`
+	PhotoUrl     string    `json:"photoUrl" validate:"omitempty,http_url"`
+	BaseCurrency string    `json:"baseCurrency" validate:"required,iso4217"`
+	UpdatedAt    time.Time `json:"updatedAt" validate:"required"`
+}
+
+type AddMemberPayload struct {
+	Email      string     `json:"email" validate:"required,email"`
+	RoleId     string     `json:"roleId" validate:"required,oneof=V E A"`
+	ValidUntil *time.Time `json:"validUntil"`
+}
+
+type UpdateMemberPayload struct {
+	RoleId string `json:"roleId" validate:"required,oneof=V E A"`
+}
+
+// A null validUntil gives permanent access
+type UpdateMemberValidityPayload struct {
+	ValidUntil *time.Time `json:"validUntil"`
+}
+
+type UpdateSimplifyDebtsPayload struct {
+	SimplifyDebts bool `json:"simplifyDebts"`
 }
@@ -0,0 +1,218 @@
+package models
+
+import (
+	"io"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	SplitModeEqual      = "equal"
+	SplitModeExact      = "exact"
+	SplitModePercentage = "percentage"
+	SplitModeShares     = "shares"
+	SplitModeItems      = "items"
+)
+
+type Movement struct {
+	MovementId        []uint8                `json:"movementId"`
+	GroupId           []uint8                `json:"groupId"`
+	Amount            decimal.Decimal        `json:"amount"`
+	Currency          string                 `json:"currency"`
+	Description       string                 `json:"description"`
+	MovementDate      time.Time              `json:"movementDate"`
+	SplitMode         string                 `json:"splitMode"`
+	Tax               decimal.Decimal        `json:"tax"`
+	Tip               decimal.Decimal        `json:"tip"`
+	CategoryId        []uint8                `json:"categoryId"`
+	BankTransactionId string                 `json:"bankTransactionId,omitempty"`
+	Participants      []*MovementParticipant `json:"participants"`
+	Items             []*MovementItem        `json:"items,omitempty"`
+	Fields            []MovementFieldValue   `json:"fields"`
+	CreatedAt         time.Time              `json:"createdAt"`
+	UpdatedAt         time.Time              `json:"updatedAt"`
+	CreatedBy         []uint8                `json:"createdBy"`
+	UpdatedBy         []uint8                `json:"updatedBy"`
+}
+
+// MovementParticipant holds how much a user paid of a movement and how much
+// of it the user owes. SplitValue is the exact amount, percentage or share
+// used to compute Owed.
+type MovementParticipant struct {
+	UserId     []uint8          `json:"userId"`
+	Paid       decimal.Decimal  `json:"paid"`
+	Owed       decimal.Decimal  `json:"owed"`
+	SplitValue *decimal.Decimal `json:"splitValue"`
+}
+
+// MovementItem is a line of an itemized receipt. Extra is its part of the tax
+// and tip of the movement, and Amount plus Extra is split equally between its
+// participants. What a participant owes of the movement is the sum of its
+// items.
+type MovementItem struct {
+	MovementItemId []uint8                    `json:"movementItemId"`
+	Description    string                     `json:"description"`
+	Amount         decimal.Decimal            `json:"amount"`
+	Extra          decimal.Decimal            `json:"extra"`
+	Participants   []*MovementItemParticipant `json:"participants"`
+}
+
+type MovementItemParticipant struct {
+	UserId []uint8         `json:"userId"`
+	Owed   decimal.Decimal `json:"owed"`
+}
+
+// Comment is a message members write on a movement
+type Comment struct {
+	CommentId  []uint8   `json:"commentId"`
+	MovementId []uint8   `json:"movementId"`
+	Text       string    `json:"text"`
+	CreatedAt  time.Time `json:"createdAt"`
+	CreatedBy  []uint8   `json:"createdBy"`
+}
+
+// MovementFilter narrows the movements of a group, every field that is set
+// must match. Fields maps a custom field name to its exact value and Text is
+// looked for in the description and the field values.
+type MovementFilter struct {
+	From          *time.Time
+	To            *time.Time
+	MinAmount     *decimal.Decimal
+	MaxAmount     *decimal.Decimal
+	PayerId       []uint8
+	ParticipantId []uint8
+	CategoryId    []uint8
+	Fields        map[string]string
+	Text          string
+}
+
+type MovementRepository interface {
+	CreateMovement(Movement) ([]uint8, error)
+	GetMovementById(groupId []uint8, movementId []uint8) (*Movement, error)
+	GetGroupMovements(groupId []uint8, filter MovementFilter, page PageRequest) (*Page[*Movement], error)
+	UpdateMovement(Movement) error
+	DeleteMovement(groupId []uint8, movementId []uint8) error
+	GetGroupMemberIds(groupId []uint8) ([]string, error)
+	GetGroupBaseCurrency(groupId []uint8) (string, error)
+	CreateMovements([]Movement) ([][]uint8, error)
+	GetGroupMemberEmails(groupId []uint8) (map[string]string, error)
+	GetImportedTransactionIds(groupId []uint8, ids []string) (map[string]bool, error)
+	CreateAttachment(Attachment) ([]uint8, error)
+	GetAttachments(groupId []uint8, movementId []uint8) ([]*Attachment, error)
+	GetAttachmentById(groupId []uint8, movementId []uint8, attachmentId []uint8) (*Attachment, error)
+	DeleteAttachment(attachmentId []uint8) error
+	CreateComment(Comment) ([]uint8, error)
+	GetComments(groupId []uint8, movementId []uint8) ([]*Comment, error)
+	GetCommentById(groupId []uint8, movementId []uint8, commentId []uint8) (*Comment, error)
+	DeleteComment(commentId []uint8) error
+}
+
+type MovementService interface {
+	CreateMovement(payload CreateMovementPayload, groupId []uint8, userId []uint8) (*Movement, error)
+	GetMovementById(groupId []uint8, movementId []uint8) (*Movement, error)
+	GetGroupMovements(groupId []uint8, filter MovementFilter, page PageRequest) (*Page[*Movement], error)
+	UpdateMovement(payload UpdateMovementPayload, groupId []uint8, movementId []uint8, userId []uint8) error
+	DeleteMovement(groupId []uint8, movementId []uint8) error
+	ImportMovements(mapping ImportMappingPayload, file io.Reader, dryRun bool, groupId []uint8, userId []uint8) (*ImportResult, error)
+	ImportStatement(filename string, data []byte, personal bool, dryRun bool, groupId []uint8, userId []uint8) (*ImportResult, error)
+	AddAttachment(fileName string, data []byte, groupId []uint8, movementId []uint8, userId []uint8) (*Attachment, error)
+	GetAttachments(groupId []uint8, movementId []uint8) ([]*Attachment, error)
+	DeleteAttachment(groupId []uint8, movementId []uint8, attachmentId []uint8) error
+	AddComment(payload CommentPayload, groupId []uint8, movementId []uint8, userId []uint8) (*Comment, error)
+	GetComments(groupId []uint8, movementId []uint8) ([]*Comment, error)
+	DeleteComment(groupId []uint8, movementId []uint8, commentId []uint8, userId []uint8) error
+}
+
+// ImportResult reports every row of an imported CSV or bank statement. Line
+// is the line of a CSV, starting at 2 after the header, or the position of the
+// transaction in a statement. Nothing is created when a row fails.
+type ImportResult struct {
+	DryRun  bool         `json:"dryRun"`
+	Total   int          `json:"total"`
+	Valid   int          `json:"valid"`
+	Invalid int          `json:"invalid"`
+	Skipped int          `json:"skipped"`
+	Created int          `json:"created"`
+	Rows    []*ImportRow `json:"rows"`
+}
+
+// Skipped rows are not errors: statement credits and transactions that were
+// already imported
+type ImportRow struct {
+	Line     int       `json:"line"`
+	Movement *Movement `json:"movement,omitempty"`
+	Skipped  string    `json:"skipped,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type CreateMovementPayload struct {
+	Amount       decimal.Decimal              `json:"amount"`
+	Currency     string                       `json:"currency" validate:"omitempty,iso4217"`
+	Description  string                       `json:"description" validate:"required"`
+	MovementDate string                       `json:"movementDate" validate:"omitempty,datetime=2006-01-02"`
+	SplitMode    string                       `json:"splitMode" validate:"omitempty,oneof=equal exact percentage shares items"`
+	CategoryId   string                       `json:"categoryId" validate:"omitempty,uuid"`
+	Payers       []MovementPayerPayload       `json:"payers" validate:"omitempty,dive"`
+	Participants []MovementParticipantPayload `json:"participants" validate:"omitempty,dive"`
+	Items        []MovementItemPayload        `json:"items" validate:"omitempty,max=200,dive"`
+	Tax          decimal.Decimal              `json:"tax"`
+	Tip          decimal.Decimal              `json:"tip"`
+	Fields       map[string]string            `json:"fields"`
+}
+
+type UpdateMovementPayload struct {
+	Amount       decimal.Decimal              `json:"amount"`
+	Currency     string                       `json:"currency" validate:"omitempty,iso4217"`
+	Description  string                       `json:"description" validate:"required"`
+	MovementDate string                       `json:"movementDate" validate:"omitempty,datetime=2006-01-02"`
+	SplitMode    string                       `json:"splitMode" validate:"omitempty,oneof=equal exact percentage shares items"`
+	CategoryId   string                       `json:"categoryId" validate:"omitempty,uuid"`
+	Payers       []MovementPayerPayload       `json:"payers" validate:"omitempty,dive"`
+	Participants []MovementParticipantPayload `json:"participants" validate:"omitempty,dive"`
+	Items        []MovementItemPayload        `json:"items" validate:"omitempty,max=200,dive"`
+	Tax          decimal.Decimal              `json:"tax"`
+	Tip          decimal.Decimal              `json:"tip"`
+	Fields       map[string]string            `json:"fields"`
+}
+
+type MovementPayerPayload struct {
+	UserId string          `json:"userId" validate:"required,uuid"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// MovementItemPayload is a line of the receipt of an items split, shared by
+// the users in Participants
+type MovementItemPayload struct {
+	Description  string          `json:"description" validate:"required,max=255"`
+	Amount       decimal.Decimal `json:"amount"`
+	Participants []string        `json:"participants" validate:"required,min=1,dive,uuid"`
+}
+
+// Value is the exact amount, percentage or share of the participant,
+// depending on the split mode. It is ignored on equal splits.
+type MovementParticipantPayload struct {
+	UserId string          `json:"userId" validate:"required,uuid"`
+	Value  decimal.Decimal `json:"value"`
+}
+
+type CommentPayload struct {
+	Text string `json:"text" validate:"required,max=2000"`
+}
+
+// ImportMappingPayload maps each movement attribute to a column header of the
+// CSV. Payer holds the email of a member, Category the name of a category in
+// any language and Fields maps movement field names to columns. Unmapped
+// attributes take the same defaults as a new movement.
+type ImportMappingPayload struct {
+	Date             string            `json:"date" validate:"required"`
+	Amount           string            `json:"amount" validate:"required"`
+	Description      string            `json:"description" validate:"required"`
+	Payer            string            `json:"payer"`
+	Category         string            `json:"category"`
+	Currency         string            `json:"currency"`
+	Fields           map[string]string `json:"fields" validate:"omitempty,dive,required"`
+	DateFormat       string            `json:"dateFormat" validate:"omitempty,oneof=YYYY-MM-DD DD/MM/YYYY MM/DD/YYYY DD.MM.YYYY DD-MM-YYYY"`
+	DecimalSeparator string            `json:"decimalSeparator" validate:"omitempty,oneof=. 0x2C"`
+	Delimiter        string            `json:"delimiter" validate:"omitempty,len=1"`
+}
@@ -0,0 +1,22 @@
+package models
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+// PageRequest asks for the page after Cursor, which is the NextCursor of the
+// previous page or empty for the first one. Sort is the name of a field, with
+// a leading "-" for descending order, and must not change between pages.
+type PageRequest struct {
+	Limit  int
+	Cursor string
+	Sort   string
+}
+
+// Page is the response of every paginated list. NextCursor is empty on the
+// last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	OccurrenceScheduled  = "scheduled"
+	OccurrenceProcessing = "processing"
+	OccurrenceCreated    = "created"
+	OccurrenceSkipped    = "skipped"
+	OccurrenceFailed     = "failed"
+)
+
+// RecurringMovement creates a movement from Template on every date of
+// Schedule, a 5 field cron expression or an RRULE, between StartDate and
+// EndDate and up to MaxCount occurrences. Dates before ResumedAt are never
+// created, so pausing or rescheduling a template does not backfill them.
+type RecurringMovement struct {
+	RecurringMovementId []uint8                `json:"recurringMovementId"`
+	GroupId             []uint8                `json:"groupId"`
+	Schedule            string                 `json:"schedule"`
+	StartDate           time.Time              `json:"startDate"`
+	EndDate             *time.Time             `json:"endDate"`
+	MaxCount            *int64                 `json:"maxCount"`
+	Active              bool                   `json:"active"`
+	ResumedAt           time.Time              `json:"resumedAt"`
+	Template            CreateMovementPayload  `json:"template"`
+	NextOccurrences     []time.Time            `json:"nextOccurrences,omitempty"`
+	Occurrences         []*RecurringOccurrence `json:"occurrences,omitempty"`
+	CreatedAt           time.Time              `json:"createdAt"`
+	UpdatedAt           time.Time              `json:"updatedAt"`
+	CreatedBy           []uint8                `json:"createdBy"`
+	UpdatedBy           []uint8                `json:"updatedBy"`
+}
+
+// RecurringOccurrence records what happened with one date of the schedule.
+// Occurrences are stored when they are processed, skipped or edited; an
+// edited occurrence keeps its Override until the movement is created.
+type RecurringOccurrence struct {
+	RecurringMovementId []uint8                `json:"recurringMovementId"`
+	OccurrenceDate      time.Time              `json:"occurrenceDate"`
+	Status              string                 `json:"status"`
+	MovementId          []uint8                `json:"movementId"`
+	Override            *CreateMovementPayload `json:"override"`
+	Error               string                 `json:"error,omitempty"`
+	UpdatedAt           time.Time              `json:"updatedAt"`
+	UpdatedBy           []uint8                `json:"updatedBy"`
+}
+
+type RecurringRepository interface {
+	CreateRecurring(RecurringMovement) ([]uint8, error)
+	GetRecurringById(groupId []uint8, recurringId []uint8) (*RecurringMovement, error)
+	GetGroupRecurring(groupId []uint8) ([]*RecurringMovement, error)
+	GetActiveRecurring() ([]*RecurringMovement, error)
+	UpdateRecurring(RecurringMovement) error
+	DeleteRecurring(groupId []uint8, recurringId []uint8) error
+	GetOccurrences(recurringId []uint8) ([]*RecurringOccurrence, error)
+	SaveOccurrence(RecurringOccurrence) (bool, error)
+	ClaimOccurrence(recurringId []uint8, date time.Time) (bool, *CreateMovementPayload, error)
+	CompleteOccurrence(RecurringOccurrence) error
+}
+
+type RecurringService interface {
+	CreateRecurring(payload CreateRecurringPayload, groupId []uint8, userId []uint8) (*RecurringMovement, error)
+	GetRecurringById(groupId []uint8, recurringId []uint8) (*RecurringMovement, error)
+	GetGroupRecurring(groupId []uint8) ([]*RecurringMovement, error)
+	UpdateRecurring(payload UpdateRecurringPayload, groupId []uint8, recurringId []uint8, userId []uint8) error
+	DeleteRecurring(groupId []uint8, recurringId []uint8) error
+	SkipOccurrence(groupId []uint8, recurringId []uint8, date string, userId []uint8) error
+	EditOccurrence(payload CreateMovementPayload, groupId []uint8, recurringId []uint8, date string, userId []uint8) error
+	ProcessDue(today time.Time) (int, error)
+}
+
+// The movementDate of the template is ignored, every occurrence uses its own
+type CreateRecurringPayload struct {
+	Schedule  string                `json:"schedule" validate:"required"`
+	StartDate string                `json:"startDate" validate:"required,datetime=2006-01-02"`
+	EndDate   string                `json:"endDate" validate:"omitempty,datetime=2006-01-02"`
+	MaxCount  *int64                `json:"maxCount" validate:"omitempty,min=1"`
+	Template  CreateMovementPayload `json:"template"`
+}
+
+// Without active the template keeps its current state
+type UpdateRecurringPayload struct {
+	Schedule  string                `json:"schedule" validate:"required"`
+	StartDate string                `json:"startDate" validate:"required,datetime=2006-01-02"`
+	EndDate   string                `json:"endDate" validate:"omitempty,datetime=2006-01-02"`
+	MaxCount  *int64                `json:"maxCount" validate:"omitempty,min=1"`
+	Active    *bool                 `json:"active"`
+	Template  CreateMovementPayload `json:"template"`
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"io"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ReportFormatJSON = "json"
+	ReportFormatCSV  = "csv"
+)
+
+// Largest expenses listed in a report
+const ReportLargestCount = 10
+
+// Report sums the expenses of a group, or the share of the expenses of a
+// user across its groups, between From and To. Every amount is converted to
+// Currency with the rate of the movement date. Settlements are not expenses.
+type Report struct {
+	GroupId        []uint8             `json:"groupId,omitempty"`
+	UserId         []uint8             `json:"userId,omitempty"`
+	Currency       string              `json:"currency"`
+	From           time.Time           `json:"from"`
+	To             time.Time           `json:"to"`
+	Total          decimal.Decimal     `json:"total"`
+	Count          int                 `json:"count"`
+	Average        decimal.Decimal     `json:"average"`
+	MonthlyAverage decimal.Decimal     `json:"monthlyAverage"`
+	ByCategory     []*ReportTotal      `json:"byCategory"`
+	ByMember       []*ReportTotal      `json:"byMember,omitempty"`
+	ByGroup        []*ReportTotal      `json:"byGroup,omitempty"`
+	ByField        []*ReportFieldTotal `json:"byField"`
+	Months         []*ReportMonth      `json:"months"`
+	Largest        []*ReportExpense    `json:"largest"`
+}
+
+// ReportTotal is a slice of a chart. Share is the percentage of the report
+// total. Paid is only set for members, whose Total is what they owe.
+type ReportTotal struct {
+	Id    []uint8          `json:"id"`
+	Name  string           `json:"name"`
+	Total decimal.Decimal  `json:"total"`
+	Paid  *decimal.Decimal `json:"paid,omitempty"`
+	Count int              `json:"count"`
+	Share decimal.Decimal  `json:"share"`
+}
+
+type ReportFieldTotal struct {
+	Field string          `json:"field"`
+	Value string          `json:"value"`
+	Total decimal.Decimal `json:"total"`
+	Count int             `json:"count"`
+}
+
+// ReportMonth is a month of the period, months without expenses included.
+// Change is the percentage against the previous month, nil for the first
+// month and after a month without expenses.
+type ReportMonth struct {
+	Month  string           `json:"month"`
+	Total  decimal.Decimal  `json:"total"`
+	Count  int              `json:"count"`
+	Change *decimal.Decimal `json:"change"`
+}
+
+// ReportExpense keeps the original amount and currency next to the
+// converted Total
+type ReportExpense struct {
+	MovementId  []uint8         `json:"movementId"`
+	GroupId     []uint8         `json:"groupId"`
+	Date        time.Time       `json:"date"`
+	Description string          `json:"description"`
+	Category    string          `json:"category"`
+	Amount      decimal.Decimal `json:"amount"`
+	Currency    string          `json:"currency"`
+	Total       decimal.Decimal `json:"total"`
+}
+
+// Statement is the content of the PDF statement of a group period. Balances
+// and SettleUp are the ones of the statement date, not of To.
+type Statement struct {
+	Group       *Group
+	From        time.Time
+	To          time.Time
+	GeneratedAt time.Time
+	Photo       []byte
+	PhotoType   string
+	Movements   []*StatementMovement
+	Balances    *GroupBalances
+	SettleUp    *SettleUp
+}
+
+type StatementMovement struct {
+	Date        time.Time
+	Description string
+	Category    string
+	PaidBy      string
+	Amount      decimal.Decimal
+	Currency    string
+}
+
+type ReportRepository interface {
+	// language picks the category names, like categories.Localize
+	GetGroupReport(groupId []uint8, from time.Time, to time.Time, currency string, language string) (*Report, error)
+	GetUserReport(userId []uint8, from time.Time, to time.Time, currency string, language string) (*Report, error)
+}
+
+type ReportService interface {
+	GetGroupReport(groupId []uint8, from time.Time, to time.Time, userId []uint8) (*Report, error)
+	GetUserReport(userId []uint8, from time.Time, to time.Time, currency string) (*Report, error)
+	WriteCSV(report *Report, w io.Writer) error
+	// WriteGroupStatement writes the PDF in the language of the user asking
+	WriteGroupStatement(groupId []uint8, from time.Time, to time.Time, userId []uint8, w io.Writer) error
+}
@@ -0,0 +1,33 @@
+package models
+
+// Types of search results
+const (
+	SearchMovement   = "movement"
+	SearchField      = "field"
+	SearchComment    = "comment"
+	SearchSettlement = "settlement"
+	SearchGroup      = "group"
+)
+
+// SearchResult is a text that matched a search. Id is the id of the movement,
+// settlement or group; for custom field values and comments it is the
+// movement, and Field is the name of the field. Snippet is HTML escaped with the matches inside
+// <mark> tags.
+type SearchResult struct {
+	Type      string  `json:"type"`
+	Id        []uint8 `json:"id"`
+	GroupId   []uint8 `json:"groupId"`
+	GroupName string  `json:"groupName"`
+	Field     string  `json:"field,omitempty"`
+	Snippet   string  `json:"snippet"`
+	Rank      float64 `json:"rank"`
+}
+
+type SearchRepository interface {
+	// config is the Postgres text search configuration of the query
+	Search(userId []uint8, text string, config string, page PageRequest) (*Page[*SearchResult], error)
+}
+
+type SearchService interface {
+	Search(userId []uint8, text string, page PageRequest) (*Page[*SearchResult], error)
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	SettlementPending   = "pending"
+	SettlementConfirmed = "confirmed"
+	SettlementDisputed  = "disputed"
+)
+
+const (
+	HistoryMovement   = "movement"
+	HistorySettlement = "settlement"
+)
+
+type Settlement struct {
+	SettlementId   []uint8         `json:"settlementId"`
+	GroupId        []uint8         `json:"groupId"`
+	FromUserId     []uint8         `json:"fromUserId"`
+	ToUserId       []uint8         `json:"toUserId"`
+	Amount         decimal.Decimal `json:"amount"`
+	SettlementDate time.Time       `json:"settlementDate"`
+	Note           string          `json:"note"`
+	Status         string          `json:"status"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+	CreatedBy      []uint8         `json:"createdBy"`
+	UpdatedBy      []uint8         `json:"updatedBy"`
+}
+
+// HistoryItem is a movement or a settlement in the group history
+type HistoryItem struct {
+	Type        string          `json:"type"`
+	Id          []uint8         `json:"id"`
+	Date        time.Time       `json:"date"`
+	Amount      decimal.Decimal `json:"amount"`
+	Description string          `json:"description"`
+	FromUserId  []uint8         `json:"fromUserId,omitempty"`
+	ToUserId    []uint8         `json:"toUserId,omitempty"`
+	Status      string          `json:"status,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	CreatedBy   []uint8         `json:"createdBy"`
+}
+
+type SettlementRepository interface {
+	CreateSettlement(Settlement) ([]uint8, error)
+	GetSettlementById(groupId []uint8, settlementId []uint8) (*Settlement, error)
+	GetGroupSettlements(groupId []uint8) ([]*Settlement, error)
+	UpdateSettlementStatus(Settlement) error
+	DeleteSettlement(groupId []uint8, settlementId []uint8) error
+	IsActiveMember(groupId []uint8, userId []uint8) (bool, error)
+	GetGroupHistory(groupId []uint8) ([]*HistoryItem, error)
+}
+
+type SettlementService interface {
+	CreateSettlement(payload CreateSettlementPayload, groupId []uint8, userId []uint8) (*Settlement, error)
+	GetSettlementById(groupId []uint8, settlementId []uint8) (*Settlement, error)
+	GetGroupSettlements(groupId []uint8) ([]*Settlement, error)
+	ConfirmSettlement(groupId []uint8, settlementId []uint8, userId []uint8) error
+	DisputeSettlement(groupId []uint8, settlementId []uint8, userId []uint8) error
+	DeleteSettlement(groupId []uint8, settlementId []uint8, userId []uint8) error
+	GetGroupHistory(groupId []uint8) ([]*HistoryItem, error)
+}
+
+// Without fromUserId the settlement is paid by the user who registers it.
+// Only admins can register a payment of another member.
+type CreateSettlementPayload struct {
+	FromUserId     string          `json:"fromUserId" validate:"omitempty,uuid"`
+	ToUserId       string          `json:"toUserId" validate:"required,uuid"`
+	Amount         decimal.Decimal `json:"amount"`
+	SettlementDate string          `json:"settlementDate" validate:"omitempty,datetime=2006-01-02"`
+	Note           string          `json:"note"`
+}
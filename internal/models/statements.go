@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	StatementFormatOFX   = "ofx"
+	StatementFormatQIF   = "qif"
+	StatementFormatCAMT  = "camt053"
+	StatementFormatMT940 = "mt940"
+)
+
+// BankTransaction is one entry of a bank statement. Amount is negative for
+// debits. Id is the transaction id given by the bank, or a hash of the entry
+// for formats without ids, and is what makes imports repeatable.
+type BankTransaction struct {
+	Id          string          `json:"id"`
+	Account     string          `json:"account"`
+	Date        time.Time       `json:"date"`
+	Amount      decimal.Decimal `json:"amount"`
+	Currency    string          `json:"currency"`
+	Description string          `json:"description"`
+}
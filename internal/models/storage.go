@@ -0,0 +1,29 @@
+package models
+
+import (
+	"io"
+	"time"
+)
+
+// Storage keeps uploaded files under keys like "users/<id>/<name>.png". Files
+// are never served directly: clients get a signed URL that expires.
+type Storage interface {
+	Put(key string, content io.Reader, size int64, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	SignedURL(key string, expiry time.Duration) (string, error)
+}
+
+// Attachment is a file, usually a receipt, uploaded for a movement. Url is
+// signed every time the attachment is read.
+type Attachment struct {
+	AttachmentId []uint8   `json:"attachmentId"`
+	MovementId   []uint8   `json:"movementId"`
+	FileName     string    `json:"fileName"`
+	ContentType  string    `json:"contentType"`
+	Size         int64     `json:"size"`
+	Url          string    `json:"url"`
+	StorageKey   string    `json:"-"`
+	CreatedAt    time.Time `json:"createdAt"`
+	CreatedBy    []uint8   `json:"createdBy"`
+}
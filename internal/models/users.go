@@ -8,6 +8,7 @@ type User struct {
 	UserId       []uint8   `json:"userId"`
 	UserName     string    `json:"userName"`
 	PhotoUrl     string    `json:"photoUrl"`
+	PhotoKey     string    `json:"-"`
 	Email        string    `json:"email"`
 	Password     string    `json:"-"`
 	LanguageCode string    `json:"languageCode"`
@@ -19,6 +20,7 @@ type UserRepository interface {
 	GetUserByEmail(email string) (*User, error)
 	CreateUser(User) error
 	UploadPhoto(photoUrl string, email string) error
+	UpdatePhotoKey(photoKey string, email string) error
 	GetUserById(id []uint8) (*User, error)
 }
 
@@ -27,7 +29,8 @@ type UserService interface {
 	LogInUser(user LogInUserPayload) (string, string, error)
 	GetUserPublicByEmail(email string) (*UserPublicPayload, error)
 	RefreshToken(userId []uint8) (string, error)
-	UploadPhoto(payload UploadPhotoPayload, email string) error
+	UploadPhoto(payload UploadPhotoPayload, email string, userId []uint8) error
+	StorePhoto(data []byte, email string, userId []uint8) (string, error)
 }
 
 type ContextKey string
@@ -51,7 +54,9 @@ type UploadPhotoPayload struct {
 }
 
 type UserPublicPayload struct {
-	UserName string  `json:"userName" validate:"required"`
-	Email    string  `json:"email" validate:"required,email"`
-	UserId   []uint8 `json:"userId"`
+	UserName   string            `json:"userName" validate:"required"`
+	Email      string            `json:"email" validate:"required,email"`
+	UserId     []uint8           `json:"userId"`
+	PhotoUrl   string            `json:"photoUrl"`
+	Thumbnails map[string]string `json:"thumbnails,omitempty"`
 }
@@ -0,0 +1,57 @@
+package money
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+var cent = decimal.New(1, -2)
+
+// Allocate divides amount in cents proportionally to the weights. Every user
+// gets its proportional part truncated to cents and the cents left over go,
+// one each, to the users with the largest truncated remainder. Ties go to the
+// lowest user id, so equal splits give the extra cents to the first users.
+func Allocate(amount decimal.Decimal, weights map[string]decimal.Decimal) map[string]decimal.Decimal {
+
+	type part struct {
+		userId    string
+		remainder decimal.Decimal
+	}
+
+	total := Sum(weights)
+	shares := make(map[string]decimal.Decimal, len(weights))
+	parts := make([]part, 0, len(weights))
+	left := amount
+
+	for userId, weight := range weights {
+		exact := amount.Mul(weight).Div(total)
+		share := exact.Truncate(2)
+		shares[userId] = share
+		left = left.Sub(share)
+		parts = append(parts, part{userId: userId, remainder: exact.Sub(share)})
+	}
+
+	sort.Slice(parts, func(i, j int) bool {
+		if cmp := parts[i].remainder.Cmp(parts[j].remainder); cmp != 0 {
+			return cmp > 0
+		}
+		return parts[i].userId < parts[j].userId
+	})
+
+	for i := 0; left.IsPositive() && i < len(parts); i++ {
+		shares[parts[i].userId] = shares[parts[i].userId].Add(cent)
+		left = left.Sub(cent)
+	}
+
+	return shares
+}
+
+func Sum(values map[string]decimal.Decimal) decimal.Decimal {
+
+	total := decimal.Zero
+	for _, value := range values {
+		total = total.Add(value)
+	}
+	return total
+}
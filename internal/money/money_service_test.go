@@ -0,0 +1,77 @@
+package money
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestAllocate(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		amount  string
+		weights map[string]string
+		want    map[string]string
+	}{
+		{
+			name:    "equal weights give the extra cent to the lowest id",
+			amount:  "10",
+			weights: map[string]string{"a": "1", "b": "1", "c": "1"},
+			want:    map[string]string{"a": "3.34", "b": "3.33", "c": "3.33"},
+		},
+		{
+			name:    "fewer cents than users",
+			amount:  "0.02",
+			weights: map[string]string{"a": "1", "b": "1", "c": "1"},
+			want:    map[string]string{"a": "0.01", "b": "0.01", "c": "0"},
+		},
+		{
+			name:    "the largest remainder gets the cent",
+			amount:  "100",
+			weights: map[string]string{"a": "1", "b": "2"},
+			want:    map[string]string{"a": "33.33", "b": "66.67"},
+		},
+		{
+			name:    "percentages",
+			amount:  "10",
+			weights: map[string]string{"a": "33.33", "b": "33.33", "c": "33.34"},
+			want:    map[string]string{"a": "3.33", "b": "3.33", "c": "3.34"},
+		},
+		{
+			name:    "zero weights get nothing",
+			amount:  "5",
+			weights: map[string]string{"a": "0", "b": "1"},
+			want:    map[string]string{"a": "0", "b": "5"},
+		},
+		{
+			name:    "one user takes everything",
+			amount:  "12.34",
+			weights: map[string]string{"a": "7"},
+			want:    map[string]string{"a": "12.34"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weights := make(map[string]decimal.Decimal, len(tt.weights))
+			for userId, weight := range tt.weights {
+				weights[userId] = decimal.RequireFromString(weight)
+			}
+
+			shares := Allocate(decimal.RequireFromString(tt.amount), weights)
+
+			got := make(map[string]string, len(shares))
+			for userId, share := range shares {
+				got[userId] = share.String()
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Allocate() = %v, want %v", got, tt.want)
+			}
+			if total := Sum(shares); !total.Equal(decimal.RequireFromString(tt.amount)) {
+				t.Errorf("Allocate() adds up to %s, want %s", total, tt.amount)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package movements
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/storage"
+)
+
+// Most attachments a movement can have
+const maxAttachments = 20
+
+// AddAttachment saves a receipt or other file for the movement. The content
+// type is detected from the data, the name given by the user is only kept
+// for display.
+func (s *Service) AddAttachment(fileName string, data []byte, groupId []uint8, movementId []uint8, userId []uint8) (*models.Attachment, error) {
+
+	if _, err := s.repository.GetMovementById(groupId, movementId); err != nil {
+		return nil, err
+	}
+
+	attachments, err := s.repository.GetAttachments(groupId, movementId)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) >= maxAttachments {
+		return nil, errors.ErrInvalidaPayload(fmt.Sprintf("a movement can not have more than %d attachments", maxAttachments))
+	}
+
+	contentType, extension, err := storage.Detect(data, storage.AttachmentTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := storage.NewKey("movements/"+string(movementId), extension)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.Put(key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return nil, err
+	}
+
+	attachment := models.Attachment{
+		MovementId:  movementId,
+		FileName:    attachmentName(fileName, extension),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		StorageKey:  key,
+		CreatedBy:   userId,
+	}
+
+	attachmentId, err := s.repository.CreateAttachment(attachment)
+	if err != nil {
+		s.deleteFile(key)
+		return nil, err
+	}
+
+	saved, err := s.repository.GetAttachmentById(groupId, movementId, attachmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	return saved, s.signAttachment(saved)
+}
+
+// GetAttachments lists the attachments of the movement, each with a fresh
+// signed URL
+func (s *Service) GetAttachments(groupId []uint8, movementId []uint8) ([]*models.Attachment, error) {
+
+	if _, err := s.repository.GetMovementById(groupId, movementId); err != nil {
+		return nil, err
+	}
+
+	attachments, err := s.repository.GetAttachments(groupId, movementId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range attachments {
+		if err := s.signAttachment(attachment); err != nil {
+			return nil, err
+		}
+	}
+
+	return attachments, nil
+}
+
+func (s *Service) DeleteAttachment(groupId []uint8, movementId []uint8, attachmentId []uint8) error {
+
+	attachment, err := s.repository.GetAttachmentById(groupId, movementId, attachmentId)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repository.DeleteAttachment(attachment.AttachmentId); err != nil {
+		return err
+	}
+
+	s.deleteFile(attachment.StorageKey)
+	return nil
+}
+
+// Aux Functions
+
+func (s *Service) signAttachment(attachment *models.Attachment) error {
+
+	url, err := storage.SignURL(s.storage, attachment.StorageKey)
+	if err != nil {
+		return err
+	}
+	attachment.Url = url
+	return nil
+}
+
+// deleteFile removes a file whose row is already gone, a failure only leaves
+// an unused file behind
+func (s *Service) deleteFile(key string) {
+
+	if err := s.storage.Delete(key); err != nil {
+		log.Printf("deleting file %s: %v", key, err)
+	}
+}
+
+// attachmentName keeps the base name given by the user, with the extension
+// of the detected type when it has none
+func attachmentName(fileName string, extension string) string {
+
+	name := strings.TrimSpace(filepath.Base(strings.ReplaceAll(fileName, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		name = "attachment"
+	}
+	if filepath.Ext(name) == "" {
+		name += extension
+	}
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+	return name
+}
@@ -0,0 +1,53 @@
+package movements
+
+import (
+	"strings"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+// AddComment saves a message of the user on the movement
+func (s *Service) AddComment(payload models.CommentPayload, groupId []uint8, movementId []uint8, userId []uint8) (*models.Comment, error) {
+
+	text := strings.TrimSpace(payload.Text)
+	if text == "" {
+		return nil, errors.ErrInvalidaPayload("a comment needs some text")
+	}
+
+	if _, err := s.repository.GetMovementById(groupId, movementId); err != nil {
+		return nil, err
+	}
+
+	commentId, err := s.repository.CreateComment(models.Comment{MovementId: movementId, Text: text, CreatedBy: userId})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.GetCommentById(groupId, movementId, commentId)
+}
+
+func (s *Service) GetComments(groupId []uint8, movementId []uint8) ([]*models.Comment, error) {
+
+	if _, err := s.repository.GetMovementById(groupId, movementId); err != nil {
+		return nil, err
+	}
+
+	return s.repository.GetComments(groupId, movementId)
+}
+
+// DeleteComment only lets users delete their own comments, the rest go with
+// the movement
+func (s *Service) DeleteComment(groupId []uint8, movementId []uint8, commentId []uint8, userId []uint8) error {
+
+	comment, err := s.repository.GetCommentById(groupId, movementId, commentId)
+	if err != nil {
+		return err
+	}
+
+	if string(comment.CreatedBy) != string(userId) {
+		return errors.ErrNotCommentAuthor
+	}
+
+	return s.repository.DeleteComment(comment.CommentId)
+}
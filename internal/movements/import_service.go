@@ -0,0 +1,369 @@
+package movements
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/statements"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+	"github.com/shopspring/decimal"
+)
+
+// Rows per INSERT when an import is saved
+const importBatchSize = 500
+
+// Every row is validated like a new movement, so imports are bounded
+const maxImportRows = 5000
+
+// Used for statement transactions without any text
+const defaultStatementDescription = "Bank transaction"
+
+var importDateFormats = map[string]string{
+	"YYYY-MM-DD": time.DateOnly,
+	"DD/MM/YYYY": "02/01/2006",
+	"MM/DD/YYYY": "01/02/2006",
+	"DD.MM.YYYY": "02.01.2006",
+	"DD-MM-YYYY": "02-01-2006",
+}
+
+// ImportMovements reads a CSV with a header row and builds a movement from
+// every row using the column mapping. With dryRun, or when any row is not
+// valid, nothing is saved and the result reports what would be created.
+func (s *Service) ImportMovements(mapping models.ImportMappingPayload, file io.Reader, dryRun bool, groupId []uint8, userId []uint8) (*models.ImportResult, error) {
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	if mapping.Delimiter != "" {
+		reader.Comma = rune(mapping.Delimiter[0])
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.ErrInvalidaPayload("the CSV file is empty")
+	} else if err != nil {
+		return nil, errors.ErrInvalidaPayload(err.Error())
+	}
+
+	columns, err := mapColumns(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := time.DateOnly
+	if mapping.DateFormat != "" {
+		layout = importDateFormats[mapping.DateFormat]
+	}
+
+	// Everything the rows are checked against is read once
+	group, err := s.loadGroupData(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.repository.GetGroupMemberEmails(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := categoryIndex(group.categories)
+
+	result := &models.ImportResult{DryRun: dryRun}
+	var movements []models.Movement
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.ErrInvalidaPayload(err.Error())
+		}
+		line, _ := reader.FieldPos(0)
+		if isEmptyRecord(record) {
+			continue
+		}
+
+		result.Total++
+		if result.Total > maxImportRows {
+			return nil, errors.ErrInvalidaPayload(fmt.Sprintf("the CSV file can not have more than %d rows", maxImportRows))
+		}
+
+		row := &models.ImportRow{Line: line}
+		result.Rows = append(result.Rows, row)
+
+		payload, err := buildImportPayload(record, columns, mapping, layout, members, categories)
+		if err == nil {
+			row.Movement, err = s.buildMovement(*payload, group, userId, nil)
+		}
+		if err != nil {
+			row.Error = err.Error()
+			result.Invalid++
+			continue
+		}
+
+		result.Valid++
+		movements = append(movements, *row.Movement)
+	}
+
+	return s.saveImport(result, movements)
+}
+
+// ImportStatement creates a movement for every debit of a bank statement.
+// Credits and transactions imported before, recognised by their bank id, are
+// skipped. Personal movements are owed only by the user who imports them,
+// which keeps a personal ledger inside the group.
+func (s *Service) ImportStatement(filename string, data []byte, personal bool, dryRun bool, groupId []uint8, userId []uint8) (*models.ImportResult, error) {
+
+	transactions, err := statements.Parse(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(transactions) > maxImportRows {
+		return nil, errors.ErrInvalidaPayload(fmt.Sprintf("the statement can not have more than %d transactions", maxImportRows))
+	}
+
+	ids := make([]string, len(transactions))
+	for i, transaction := range transactions {
+		ids[i] = transaction.Id
+	}
+	imported, err := s.repository.GetImportedTransactionIds(groupId, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := s.loadGroupData(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ImportResult{DryRun: dryRun}
+	var movements []models.Movement
+
+	for i, transaction := range transactions {
+		result.Total++
+		row := &models.ImportRow{Line: i + 1}
+		result.Rows = append(result.Rows, row)
+
+		if !transaction.Amount.IsNegative() {
+			row.Skipped = "credit"
+		} else if imported[transaction.Id] {
+			row.Skipped = "already imported"
+		}
+		if row.Skipped != "" {
+			result.Skipped++
+			continue
+		}
+		imported[transaction.Id] = true
+
+		payload := models.CreateMovementPayload{
+			Amount:       transaction.Amount.Neg(),
+			Currency:     transaction.Currency,
+			Description:  transaction.Description,
+			MovementDate: transaction.Date.Format(time.DateOnly),
+		}
+		if payload.Description == "" {
+			payload.Description = defaultStatementDescription
+		}
+		if personal {
+			payload.Participants = []models.MovementParticipantPayload{{UserId: string(userId)}}
+		}
+
+		row.Movement, err = s.buildMovement(payload, group, userId, nil)
+		if err != nil {
+			row.Error = err.Error()
+			result.Invalid++
+			continue
+		}
+		row.Movement.BankTransactionId = transaction.Id
+
+		result.Valid++
+		movements = append(movements, *row.Movement)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	return s.saveImport(result, movements)
+}
+
+// Aux Functions
+
+// saveImport creates the valid movements of the result in one transaction.
+// Dry runs and imports with invalid rows are returned without saving.
+func (s *Service) saveImport(result *models.ImportResult, movements []models.Movement) (*models.ImportResult, error) {
+
+	if result.DryRun || result.Invalid > 0 || len(movements) == 0 {
+		return result, nil
+	}
+
+	ids, err := s.repository.CreateMovements(movements)
+	if err != nil {
+		return nil, errors.ErrCreateMovement(err.Error())
+	}
+
+	i := 0
+	for _, row := range result.Rows {
+		if row.Movement != nil {
+			row.Movement.MovementId = ids[i]
+			i++
+		}
+	}
+	result.Created = len(ids)
+
+	// Budgets only depend on the category and the date
+	checked := make(map[string]bool)
+	for _, movement := range movements {
+		key := string(movement.CategoryId) + movement.MovementDate.Format(time.DateOnly)
+		if !checked[key] {
+			checked[key] = true
+			s.checkBudgets(movement)
+		}
+	}
+
+	return result, nil
+}
+
+// mapColumns returns the position in the header of every mapped column.
+// Headers are compared without case and surrounding spaces.
+func mapColumns(header []string, mapping models.ImportMappingPayload) (map[string]int, error) {
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := positions[name]; !ok {
+			positions[name] = i
+		}
+	}
+
+	mapped := []string{mapping.Date, mapping.Amount, mapping.Description, mapping.Payer, mapping.Category, mapping.Currency}
+	for _, column := range mapping.Fields {
+		mapped = append(mapped, column)
+	}
+
+	columns := make(map[string]int)
+	for _, column := range mapped {
+		if column == "" {
+			continue
+		}
+		position, ok := positions[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			return nil, errors.ErrInvalidaPayload(fmt.Sprintf("column %s is not in the CSV header", column))
+		}
+		columns[column] = position
+	}
+
+	return columns, nil
+}
+
+func buildImportPayload(record []string, columns map[string]int, mapping models.ImportMappingPayload, layout string, members map[string]string, categories map[string]string) (*models.CreateMovementPayload, error) {
+
+	value := func(column string) string {
+		position, ok := columns[column]
+		if column == "" || !ok || position >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[position])
+	}
+
+	date, err := time.Parse(layout, value(mapping.Date))
+	if err != nil {
+		return nil, errors.ErrInvalidaPayload(fmt.Sprintf("date %q does not match the date format", value(mapping.Date)))
+	}
+
+	amount, err := parseImportAmount(value(mapping.Amount), mapping.DecimalSeparator)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &models.CreateMovementPayload{
+		Amount:       amount,
+		Currency:     strings.ToUpper(value(mapping.Currency)),
+		Description:  value(mapping.Description),
+		MovementDate: date.Format(time.DateOnly),
+	}
+
+	if email := strings.ToLower(value(mapping.Payer)); email != "" {
+		payerId, ok := members[email]
+		if !ok {
+			return nil, errors.ErrInvalidaPayload(fmt.Sprintf("payer %s is not a member of the group", email))
+		}
+		payload.Payers = []models.MovementPayerPayload{{UserId: payerId, Amount: amount}}
+	}
+
+	if name := strings.ToLower(value(mapping.Category)); name != "" {
+		categoryId, ok := categories[name]
+		if !ok {
+			return nil, errors.ErrInvalidaPayload(fmt.Sprintf("category %s does not exist in the group", value(mapping.Category)))
+		}
+		payload.CategoryId = categoryId
+	}
+
+	if len(mapping.Fields) > 0 {
+		payload.Fields = make(map[string]string, len(mapping.Fields))
+		for name, column := range mapping.Fields {
+			if v := value(column); v != "" {
+				payload.Fields[name] = v
+			}
+		}
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		return nil, errors.ErrInvalidaPayload(err.Error())
+	}
+
+	return payload, nil
+}
+
+// parseImportAmount accepts thousands separators and a comma as the decimal
+// separator when the mapping asks for it.
+func parseImportAmount(text string, separator string) (decimal.Decimal, error) {
+
+	clean := strings.ReplaceAll(text, " ", "")
+	if separator == "," {
+		clean = strings.ReplaceAll(clean, ".", "")
+		clean = strings.ReplaceAll(clean, ",", ".")
+	} else {
+		clean = strings.ReplaceAll(clean, ",", "")
+	}
+
+	amount, err := decimal.NewFromString(clean)
+	if err != nil {
+		return decimal.Zero, errors.ErrInvalidaPayload(fmt.Sprintf("amount %q is not a number", text))
+	}
+
+	return amount, nil
+}
+
+// categoryIndex maps the code and every name of the group categories, in
+// lower case, to the category id.
+func categoryIndex(categories []*models.Category) map[string]string {
+
+	index := make(map[string]string)
+	for _, category := range categories {
+		if category.Code != "" {
+			index[strings.ToLower(category.Code)] = string(category.CategoryId)
+		}
+		for _, name := range category.Names {
+			index[strings.ToLower(name)] = string(category.CategoryId)
+		}
+	}
+
+	return index
+}
+
+func isEmptyRecord(record []string) bool {
+
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
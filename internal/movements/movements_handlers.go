@@ -0,0 +1,517 @@
+package movements
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/auth"
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/middlewares"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/pagination"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+// Max size of an imported CSV file
+const maxImportSize = 10 << 20
+
+// Max size of an attachment
+const maxAttachmentSize = 10 << 20
+
+type Handler struct {
+	service        models.MovementService
+	authRepository models.AuthRepository
+}
+
+func NewHandler(service models.MovementService, authRepository models.AuthRepository) *Handler {
+	return &Handler{service: service, authRepository: authRepository}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+
+	router.HandleFunc("/group/{groupId}/movement", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleMovementCreate, models.PermissionEditMovements, h.authRepository))).Methods("POST")
+	router.HandleFunc("/group/{groupId}/movement", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetMovements, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}/movement/import", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleMovementImport, models.PermissionEditMovements, h.authRepository))).Methods("POST")
+	router.HandleFunc("/group/{groupId}/movement/import/statement", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleStatementImport, models.PermissionEditMovements, h.authRepository))).Methods("POST")
+	router.HandleFunc("/group/{groupId}/movement/{movementId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetMovement, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}/movement/{movementId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleMovementUpdate, models.PermissionEditMovements, h.authRepository))).Methods("PUT")
+	router.HandleFunc("/group/{groupId}/movement/{movementId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleMovementDelete, models.PermissionEditMovements, h.authRepository))).Methods("DELETE")
+
+	// Attachment routes
+	router.HandleFunc("/group/{groupId}/movement/{movementId}/attachment", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetAttachments, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}/movement/{movementId}/attachment", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleAttachmentAdd, models.PermissionEditMovements, h.authRepository))).Methods("POST")
+	router.HandleFunc("/group/{groupId}/movement/{movementId}/attachment/{attachmentId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleAttachmentDelete, models.PermissionEditMovements, h.authRepository))).Methods("DELETE")
+
+	// Comment routes, every member can take part in the conversation
+	router.HandleFunc("/group/{groupId}/movement/{movementId}/comment", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetComments, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}/movement/{movementId}/comment", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleCommentAdd, models.PermissionViewGroup, h.authRepository))).Methods("POST")
+	router.HandleFunc("/group/{groupId}/movement/{movementId}/comment/{commentId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleCommentDelete, models.PermissionViewGroup, h.authRepository))).Methods("DELETE")
+}
+
+func (h *Handler) handleMovementCreate(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	var payload models.CreateMovementPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	movement, err := h.service.CreateMovement(payload, groupId, userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, movement)
+}
+
+// handleGetMovements lists the movements of the group a page at a time. See
+// parseMovementFilter for the filters and pagination.ParseRequest for paging.
+func (h *Handler) handleGetMovements(w http.ResponseWriter, r *http.Request) {
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	filter, err := parseMovementFilter(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := pagination.ParseRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	movements, err := h.service.GetGroupMovements(groupId, filter, page)
+	if err == errors.ErrInvalidCursor || err == errors.ErrInvalidSort {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, movements)
+}
+
+func (h *Handler) handleGetMovement(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	movementId := []uint8(vars["movementId"])
+
+	movement, err := h.service.GetMovementById(groupId, movementId)
+	if err == errors.ErrMovementNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, movement)
+}
+
+func (h *Handler) handleMovementUpdate(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	movementId := []uint8(vars["movementId"])
+
+	var payload models.UpdateMovementPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	err = h.service.UpdateMovement(payload, groupId, movementId, userId)
+	if err == errors.ErrMovementNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Movement updated successfully",
+	})
+}
+
+func (h *Handler) handleMovementDelete(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	movementId := []uint8(vars["movementId"])
+
+	err := h.service.DeleteMovement(groupId, movementId)
+	if err == errors.ErrMovementNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Movement deleted successfully",
+	})
+}
+
+// handleMovementImport expects a multipart form with the CSV in "file" and the
+// column mapping as JSON in "mapping". With ?dryRun=true nothing is saved.
+func (h *Handler) handleMovementImport(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(err.Error()))
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload("missing CSV file"))
+		return
+	}
+	defer file.Close()
+
+	var mapping models.ImportMappingPayload
+	if err := json.Unmarshal([]byte(r.FormValue("mapping")), &mapping); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload("mapping must be a JSON object"))
+		return
+	}
+
+	if err := utils.Validate.Struct(mapping); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	result, err := h.service.ImportMovements(mapping, file, dryRun, groupId, userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeImportResult(w, result)
+}
+
+// handleStatementImport expects a multipart form with the OFX, QFX, QIF,
+// CAMT.053 or MT940 file in "file". With ?personal=true the movements are
+// owed only by the caller and with ?dryRun=true nothing is saved.
+func (h *Handler) handleStatementImport(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+	personal := r.URL.Query().Get("personal") == "true"
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(err.Error()))
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload("missing statement file"))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := h.service.ImportStatement(header.Filename, data, personal, dryRun, groupId, userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeImportResult(w, result)
+}
+
+// handleAttachmentAdd expects a multipart form with the file in "file"
+func (h *Handler) handleAttachmentAdd(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	movementId := []uint8(vars["movementId"])
+
+	data, fileName, err := utils.ParseFile(w, r, "file", maxAttachmentSize)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(err.Error()))
+		return
+	}
+
+	attachment, err := h.service.AddAttachment(fileName, data, groupId, movementId, userId)
+	if err == errors.ErrMovementNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, attachment)
+}
+
+func (h *Handler) handleGetAttachments(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	movementId := []uint8(vars["movementId"])
+
+	attachments, err := h.service.GetAttachments(groupId, movementId)
+	if err == errors.ErrMovementNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, attachments)
+}
+
+func (h *Handler) handleAttachmentDelete(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	movementId := []uint8(vars["movementId"])
+	attachmentId := []uint8(vars["attachmentId"])
+
+	err := h.service.DeleteAttachment(groupId, movementId, attachmentId)
+	if err == errors.ErrAttachmentNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Attachment deleted successfully",
+	})
+}
+
+func (h *Handler) handleCommentAdd(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	movementId := []uint8(vars["movementId"])
+
+	var payload models.CommentPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	comment, err := h.service.AddComment(payload, groupId, movementId, userId)
+	if err == errors.ErrMovementNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, comment)
+}
+
+func (h *Handler) handleGetComments(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	movementId := []uint8(vars["movementId"])
+
+	comments, err := h.service.GetComments(groupId, movementId)
+	if err == errors.ErrMovementNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, comments)
+}
+
+func (h *Handler) handleCommentDelete(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	movementId := []uint8(vars["movementId"])
+	commentId := []uint8(vars["commentId"])
+
+	err = h.service.DeleteComment(groupId, movementId, commentId, userId)
+	if err == errors.ErrCommentNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err == errors.ErrNotCommentAuthor {
+		utils.WriteError(w, http.StatusForbidden, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Comment deleted successfully",
+	})
+}
+
+// Aux Functions
+
+// parseMovementFilter reads ?from= and ?to= (YYYY-MM-DD), ?minAmount=,
+// ?maxAmount=, ?payer=, ?participant=, ?category=, ?q= for free text and
+// ?field.<name>=<value> for every custom field to match
+func parseMovementFilter(r *http.Request) (models.MovementFilter, error) {
+
+	query := r.URL.Query()
+	filter := models.MovementFilter{
+		PayerId:       []uint8(query.Get("payer")),
+		ParticipantId: []uint8(query.Get("participant")),
+		CategoryId:    []uint8(query.Get("category")),
+		Text:          strings.TrimSpace(query.Get("q")),
+	}
+
+	var err error
+	if filter.From, err = dateParam(query, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = dateParam(query, "to"); err != nil {
+		return filter, err
+	}
+	if filter.MinAmount, err = amountParam(query, "minAmount"); err != nil {
+		return filter, err
+	}
+	if filter.MaxAmount, err = amountParam(query, "maxAmount"); err != nil {
+		return filter, err
+	}
+
+	for key, values := range query {
+		if name, ok := strings.CutPrefix(key, "field."); ok && name != "" {
+			if filter.Fields == nil {
+				filter.Fields = make(map[string]string)
+			}
+			filter.Fields[name] = values[0]
+		}
+	}
+
+	return filter, nil
+}
+
+func dateParam(query url.Values, name string) (*time.Time, error) {
+
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, errors.ErrInvalidaPayload(fmt.Sprintf("%s must be a YYYY-MM-DD date", name))
+	}
+	return &date, nil
+}
+
+func amountParam(query url.Values, name string) (*decimal.Decimal, error) {
+
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	amount, err := decimal.NewFromString(value)
+	if err != nil {
+		return nil, errors.ErrInvalidaPayload(fmt.Sprintf("%s must be a number", name))
+	}
+	return &amount, nil
+}
+
+func writeImportResult(w http.ResponseWriter, result *models.ImportResult) {
+
+	status := http.StatusCreated
+	if result.DryRun {
+		status = http.StatusOK
+	} else if result.Invalid > 0 {
+		status = http.StatusBadRequest
+	}
+
+	utils.WriteJSON(w, status, result)
+}
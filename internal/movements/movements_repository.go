@@ -0,0 +1,857 @@
+package movements
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/pagination"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+// Postgres SQL Repository
+type SQLRepository struct {
+	db *sql.DB
+}
+
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+const movementColumns = `movement_id, group_id, amount, currency, description, movement_date, split_mode, tax_amount, tip_amount, category_id, bank_transaction_id, created_at, created_by, updated_at, updated_by`
+
+const attachmentColumns = `a.attachment_id, a.movement_id, a.storage_key, a.file_name, a.content_type, a.size, a.created_at, a.created_by`
+
+const commentColumns = `c.comment_id, c.movement_id, c.text, c.created_at, c.created_by`
+
+func (s *SQLRepository) CreateMovement(movement models.Movement) ([]uint8, error) {
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var movementId []uint8
+	err = tx.QueryRow(
+		`INSERT INTO public.movement (group_id, amount, currency, description, movement_date, split_mode, tax_amount, tip_amount, category_id, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING movement_id`,
+		movement.GroupId, movement.Amount, movement.Currency, movement.Description, movement.MovementDate, movement.SplitMode, movement.Tax, movement.Tip, nullableId(movement.CategoryId), movement.CreatedBy, movement.UpdatedBy,
+	).Scan(&movementId)
+
+	if err != nil {
+		return nil, fmt.Errorf("error al crear el movimiento: %w", err)
+	}
+
+	if err := insertParticipants(tx, movementId, movement.Participants); err != nil {
+		return nil, err
+	}
+
+	if err := insertItems(tx, movementId, movement.Items); err != nil {
+		return nil, err
+	}
+
+	if err := insertFieldValues(tx, movementId, movement.Fields); err != nil {
+		return nil, err
+	}
+
+	return movementId, tx.Commit()
+}
+
+func (s *SQLRepository) GetMovementById(groupId []uint8, movementId []uint8) (*models.Movement, error) {
+
+	row := s.db.QueryRow("SELECT "+movementColumns+" FROM public.movement WHERE group_id = $1 AND movement_id = $2", groupId, movementId)
+	movement, err := scanRowIntoMovement(row)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := s.getFieldValues("v.movement_id = $1", movement.MovementId)
+	if err != nil {
+		return nil, err
+	}
+	movement.Fields = values[string(movement.MovementId)]
+
+	participants, err := s.getParticipants("p.movement_id = $1", movement.MovementId)
+	if err != nil {
+		return nil, err
+	}
+	movement.Participants = participants[string(movement.MovementId)]
+
+	items, err := s.getItems("i.movement_id = $1", movement.MovementId)
+	if err != nil {
+		return nil, err
+	}
+	movement.Items = items[string(movement.MovementId)]
+
+	return movement, nil
+}
+
+// Sortable fields of the movements, the default is the newest first
+var movementSorts = pagination.Fields{
+	"date":        {{Expr: "m.movement_date", Type: "date"}, {Expr: "m.created_at", Type: "timestamp"}, {Expr: "m.movement_id", Type: "uuid"}},
+	"amount":      {{Expr: "m.amount", Type: "numeric"}, {Expr: "m.movement_id", Type: "uuid"}},
+	"description": {{Expr: "COALESCE(m.description, '')", Type: "text"}, {Expr: "m.movement_id", Type: "uuid"}},
+	"createdAt":   {{Expr: "m.created_at", Type: "timestamp"}, {Expr: "m.movement_id", Type: "uuid"}},
+	"updatedAt":   {{Expr: "m.updated_at", Type: "timestamp"}, {Expr: "m.movement_id", Type: "uuid"}},
+}
+
+// Devuelve una pagina de los movimientos del grupo que cumplen el filtro
+func (s *SQLRepository) GetGroupMovements(groupId []uint8, filter models.MovementFilter, page models.PageRequest) (*models.Page[*models.Movement], error) {
+
+	conditions, args := movementConditions(groupId, filter)
+
+	query, err := pagination.Build(movementSorts, page, "-date", len(args))
+	if err != nil {
+		return nil, err
+	}
+	if query.Condition != "" {
+		conditions = append(conditions, query.Condition)
+		args = append(args, query.Args...)
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT %s, %s
+		FROM public.movement m
+		WHERE %s
+		ORDER BY %s
+		LIMIT %d`, movementColumns, query.Keys, strings.Join(conditions, " AND "), query.Order, query.Limit+1), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los movimientos del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var movements []*models.Movement
+	var keys [][]string
+	var ids []string
+
+	scanner := query.Scanner(rows)
+	for rows.Next() {
+		movement, err := scanRowIntoMovement(scanner)
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, movement)
+		keys = append(keys, scanner.Values)
+		ids = append(ids, string(movement.MovementId))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := pagination.Page(query, movements, keys)
+	if len(result.Items) == 0 {
+		return result, nil
+	}
+
+	values, err := s.getFieldValues("m.movement_id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	participants, err := s.getParticipants("m.movement_id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.getItems("i.movement_id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, movement := range result.Items {
+		movement.Fields = values[string(movement.MovementId)]
+		movement.Participants = participants[string(movement.MovementId)]
+		movement.Items = items[string(movement.MovementId)]
+	}
+
+	return result, nil
+}
+
+func (s *SQLRepository) UpdateMovement(movement models.Movement) error {
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		`UPDATE public.movement
+		SET amount = $1, currency = $2, description = $3, movement_date = $4, split_mode = $5, tax_amount = $6, tip_amount = $7, category_id = $8, updated_by = $9, updated_at = CURRENT_TIMESTAMP
+		WHERE group_id = $10 AND movement_id = $11`,
+		movement.Amount, movement.Currency, movement.Description, movement.MovementDate, movement.SplitMode, movement.Tax, movement.Tip, nullableId(movement.CategoryId), movement.UpdatedBy, movement.GroupId, movement.MovementId,
+	)
+	if err != nil {
+		return fmt.Errorf("error al actualizar el movimiento: %w", err)
+	}
+	if err := checkAffected(res); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM public.movement_participant WHERE movement_id = $1", movement.MovementId); err != nil {
+		return fmt.Errorf("error al actualizar los participantes del movimiento: %w", err)
+	}
+
+	if err := insertParticipants(tx, movement.MovementId, movement.Participants); err != nil {
+		return err
+	}
+
+	// The participants of the items go with them
+	if _, err := tx.Exec("DELETE FROM public.movement_item WHERE movement_id = $1", movement.MovementId); err != nil {
+		return fmt.Errorf("error al actualizar los items del movimiento: %w", err)
+	}
+
+	if err := insertItems(tx, movement.MovementId, movement.Items); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM public.movement_field_value WHERE movement_id = $1", movement.MovementId); err != nil {
+		return fmt.Errorf("error al actualizar los campos del movimiento: %w", err)
+	}
+
+	if err := insertFieldValues(tx, movement.MovementId, movement.Fields); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLRepository) DeleteMovement(groupId []uint8, movementId []uint8) error {
+
+	res, err := s.db.Exec("DELETE FROM public.movement WHERE group_id = $1 AND movement_id = $2", groupId, movementId)
+	if err != nil {
+		return fmt.Errorf("error al eliminar el movimiento: %w", err)
+	}
+
+	return checkAffected(res)
+}
+
+// Devuelve los miembros con acceso vigente al grupo
+func (s *SQLRepository) GetGroupMemberIds(groupId []uint8) ([]string, error) {
+
+	rows, err := s.db.Query(`
+		SELECT user_id
+		FROM auth.user_role
+		WHERE group_id = $1
+		AND (valid_until IS NULL OR valid_until > CURRENT_TIMESTAMP)`, groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los miembros del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id []uint8
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, string(id))
+	}
+
+	return ids, rows.Err()
+}
+
+func (s *SQLRepository) GetGroupBaseCurrency(groupId []uint8) (string, error) {
+
+	var currency string
+	err := s.db.QueryRow("SELECT base_currency FROM public.\"group\" WHERE group_id = $1", groupId).Scan(&currency)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.ErrGroupNotFound
+		}
+		return "", fmt.Errorf("error al obtener la moneda del grupo: %w", err)
+	}
+
+	return currency, nil
+}
+
+// Crea todos los movimientos en una sola transaccion, insertando por lotes.
+// Si falla un lote no se crea ninguno
+func (s *SQLRepository) CreateMovements(movements []models.Movement) ([][]uint8, error) {
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// The ids are generated first so participants and values can reference them
+	rows, err := tx.Query("SELECT gen_random_uuid() FROM generate_series(1, $1)", len(movements))
+	if err != nil {
+		return nil, fmt.Errorf("error al generar los ids de los movimientos: %w", err)
+	}
+	ids := make([][]uint8, 0, len(movements))
+	for rows.Next() {
+		var id []uint8
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var movementRows, participantRows, valueRows [][]any
+	for i, movement := range movements {
+		movementRows = append(movementRows, []any{
+			ids[i], movement.GroupId, movement.Amount, movement.Currency, movement.Description, movement.MovementDate, movement.SplitMode, nullableId(movement.CategoryId),
+			sql.NullString{String: movement.BankTransactionId, Valid: movement.BankTransactionId != ""}, movement.CreatedBy, movement.UpdatedBy,
+		})
+		for _, participant := range movement.Participants {
+			participantRows = append(participantRows, []any{ids[i], participant.UserId, participant.Paid, participant.Owed, participant.SplitValue})
+		}
+		for _, value := range movement.Fields {
+			valueRows = append(valueRows, []any{ids[i], value.MovementFieldId, value.Value})
+		}
+	}
+
+	if err := insertBatches(tx, "public.movement (movement_id, group_id, amount, currency, description, movement_date, split_mode, category_id, bank_transaction_id, created_by, updated_by)", movementRows); err != nil {
+		return nil, fmt.Errorf("error al crear los movimientos: %w", err)
+	}
+	if err := insertBatches(tx, "public.movement_participant (movement_id, user_id, paid_amount, owed_amount, split_value)", participantRows); err != nil {
+		return nil, fmt.Errorf("error al guardar los participantes de los movimientos: %w", err)
+	}
+	if err := insertBatches(tx, "public.movement_field_value (movement_id, movement_field_id, value)", valueRows); err != nil {
+		return nil, fmt.Errorf("error al guardar los campos de los movimientos: %w", err)
+	}
+
+	return ids, tx.Commit()
+}
+
+// Devuelve cuales de los ids de transacciones bancarias ya fueron importados
+func (s *SQLRepository) GetImportedTransactionIds(groupId []uint8, ids []string) (map[string]bool, error) {
+
+	rows, err := s.db.Query(`
+		SELECT bank_transaction_id
+		FROM public.movement
+		WHERE group_id = $1
+		AND bank_transaction_id = ANY($2)`, groupId, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las transacciones importadas: %w", err)
+	}
+	defer rows.Close()
+
+	imported := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		imported[id] = true
+	}
+
+	return imported, rows.Err()
+}
+
+// Devuelve el id de los miembros con acceso vigente al grupo por su email
+func (s *SQLRepository) GetGroupMemberEmails(groupId []uint8) (map[string]string, error) {
+
+	rows, err := s.db.Query(`
+		SELECT LOWER(u.email), u.user_id
+		FROM auth.user_role ur
+		INNER JOIN auth."user" u ON u.user_id = ur.user_id
+		WHERE ur.group_id = $1
+		AND (ur.valid_until IS NULL OR ur.valid_until > CURRENT_TIMESTAMP)`, groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los miembros del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	members := make(map[string]string)
+	for rows.Next() {
+		var email string
+		var id []uint8
+		if err := rows.Scan(&email, &id); err != nil {
+			return nil, err
+		}
+		members[email] = string(id)
+	}
+
+	return members, rows.Err()
+}
+
+// Guarda los datos de un archivo ya subido al storage
+func (s *SQLRepository) CreateAttachment(attachment models.Attachment) ([]uint8, error) {
+
+	var attachmentId []uint8
+	err := s.db.QueryRow(
+		"INSERT INTO public.movement_attachment (movement_id, storage_key, file_name, content_type, size, created_by) VALUES ($1, $2, $3, $4, $5, $6) RETURNING attachment_id",
+		attachment.MovementId, attachment.StorageKey, attachment.FileName, attachment.ContentType, attachment.Size, attachment.CreatedBy,
+	).Scan(&attachmentId)
+	if err != nil {
+		return nil, fmt.Errorf("error al guardar el adjunto: %w", err)
+	}
+
+	return attachmentId, nil
+}
+
+// Devuelve los adjuntos del movimiento, del mas viejo al mas nuevo
+func (s *SQLRepository) GetAttachments(groupId []uint8, movementId []uint8) ([]*models.Attachment, error) {
+
+	rows, err := s.db.Query(`
+		SELECT `+attachmentColumns+`
+		FROM public.movement_attachment a
+		INNER JOIN public.movement m ON m.movement_id = a.movement_id
+		WHERE m.group_id = $1 AND a.movement_id = $2
+		ORDER BY a.created_at, a.attachment_id`, groupId, movementId)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los adjuntos: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []*models.Attachment{}
+	for rows.Next() {
+		attachment, err := scanRowIntoAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+func (s *SQLRepository) GetAttachmentById(groupId []uint8, movementId []uint8, attachmentId []uint8) (*models.Attachment, error) {
+
+	row := s.db.QueryRow(`
+		SELECT `+attachmentColumns+`
+		FROM public.movement_attachment a
+		INNER JOIN public.movement m ON m.movement_id = a.movement_id
+		WHERE m.group_id = $1 AND a.movement_id = $2 AND a.attachment_id = $3`, groupId, movementId, attachmentId)
+
+	return scanRowIntoAttachment(row)
+}
+
+func (s *SQLRepository) DeleteAttachment(attachmentId []uint8) error {
+
+	res, err := s.db.Exec("DELETE FROM public.movement_attachment WHERE attachment_id = $1", attachmentId)
+	if err != nil {
+		return fmt.Errorf("error al eliminar el adjunto: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrAttachmentNotFound
+	}
+
+	return nil
+}
+
+func (s *SQLRepository) CreateComment(comment models.Comment) ([]uint8, error) {
+
+	var commentId []uint8
+	err := s.db.QueryRow(
+		"INSERT INTO public.movement_comment (movement_id, text, created_by) VALUES ($1, $2, $3) RETURNING comment_id",
+		comment.MovementId, comment.Text, comment.CreatedBy,
+	).Scan(&commentId)
+	if err != nil {
+		return nil, fmt.Errorf("error al guardar el comentario: %w", err)
+	}
+
+	return commentId, nil
+}
+
+// Devuelve los comentarios del movimiento, del mas viejo al mas nuevo
+func (s *SQLRepository) GetComments(groupId []uint8, movementId []uint8) ([]*models.Comment, error) {
+
+	rows, err := s.db.Query(`
+		SELECT `+commentColumns+`
+		FROM public.movement_comment c
+		INNER JOIN public.movement m ON m.movement_id = c.movement_id
+		WHERE m.group_id = $1 AND c.movement_id = $2
+		ORDER BY c.created_at, c.comment_id`, groupId, movementId)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los comentarios: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []*models.Comment{}
+	for rows.Next() {
+		comment, err := scanRowIntoComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+func (s *SQLRepository) GetCommentById(groupId []uint8, movementId []uint8, commentId []uint8) (*models.Comment, error) {
+
+	row := s.db.QueryRow(`
+		SELECT `+commentColumns+`
+		FROM public.movement_comment c
+		INNER JOIN public.movement m ON m.movement_id = c.movement_id
+		WHERE m.group_id = $1 AND c.movement_id = $2 AND c.comment_id = $3`, groupId, movementId, commentId)
+
+	return scanRowIntoComment(row)
+}
+
+func (s *SQLRepository) DeleteComment(commentId []uint8) error {
+
+	res, err := s.db.Exec("DELETE FROM public.movement_comment WHERE comment_id = $1", commentId)
+	if err != nil {
+		return fmt.Errorf("error al eliminar el comentario: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrCommentNotFound
+	}
+
+	return nil
+}
+
+// movementConditions turns the filter into the WHERE conditions of the
+// movements query and their parameters
+func movementConditions(groupId []uint8, filter models.MovementFilter) ([]string, []any) {
+
+	conditions := []string{"m.group_id = $1"}
+	args := []any{groupId}
+	param := func(arg any) string {
+		args = append(args, arg)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.From != nil {
+		conditions = append(conditions, "m.movement_date >= "+param(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "m.movement_date <= "+param(*filter.To))
+	}
+	if filter.MinAmount != nil {
+		conditions = append(conditions, "m.amount >= "+param(*filter.MinAmount))
+	}
+	if filter.MaxAmount != nil {
+		conditions = append(conditions, "m.amount <= "+param(*filter.MaxAmount))
+	}
+	if len(filter.CategoryId) > 0 {
+		conditions = append(conditions, "m.category_id = "+param(filter.CategoryId))
+	}
+	if len(filter.PayerId) > 0 {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM public.movement_participant p
+			WHERE p.movement_id = m.movement_id AND p.user_id = `+param(filter.PayerId)+` AND p.paid_amount > 0)`)
+	}
+	if len(filter.ParticipantId) > 0 {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM public.movement_participant p
+			WHERE p.movement_id = m.movement_id AND p.user_id = `+param(filter.ParticipantId)+`)`)
+	}
+	for name, value := range filter.Fields {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM public.movement_field_value v
+			INNER JOIN public.movement_field f ON f.movement_field_id = v.movement_field_id
+			WHERE v.movement_id = m.movement_id AND f.name = `+param(name)+` AND v.value = `+param(value)+`)`)
+	}
+	if filter.Text != "" {
+		pattern := param("%" + escapeLike(filter.Text) + "%")
+		conditions = append(conditions, `(m.description ILIKE `+pattern+` OR EXISTS (
+			SELECT 1 FROM public.movement_field_value v
+			WHERE v.movement_id = m.movement_id AND v.value ILIKE `+pattern+`))`)
+	}
+
+	return conditions, args
+}
+
+// escapeLike makes the wildcards of a LIKE pattern match themselves
+func escapeLike(text string) string {
+
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text)
+}
+
+// getParticipants returns the participants of the movements matching the
+// condition, grouped by movement id.
+func (s *SQLRepository) getParticipants(condition string, arg any) (map[string][]*models.MovementParticipant, error) {
+
+	rows, err := s.db.Query(`
+		SELECT p.movement_id, p.user_id, p.paid_amount, p.owed_amount, p.split_value
+		FROM public.movement_participant p
+		INNER JOIN public.movement m ON m.movement_id = p.movement_id
+		WHERE `+condition+`
+		ORDER BY p.user_id`, arg)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los participantes del movimiento: %w", err)
+	}
+	defer rows.Close()
+
+	participants := make(map[string][]*models.MovementParticipant)
+	for rows.Next() {
+		var movementId []uint8
+		var splitValue decimal.NullDecimal
+		participant := new(models.MovementParticipant)
+		if err := rows.Scan(&movementId, &participant.UserId, &participant.Paid, &participant.Owed, &splitValue); err != nil {
+			return nil, err
+		}
+		if splitValue.Valid {
+			participant.SplitValue = &splitValue.Decimal
+		}
+		participants[string(movementId)] = append(participants[string(movementId)], participant)
+	}
+
+	return participants, rows.Err()
+}
+
+func insertParticipants(tx *sql.Tx, movementId []uint8, participants []*models.MovementParticipant) error {
+
+	for _, participant := range participants {
+		_, err := tx.Exec(
+			"INSERT INTO public.movement_participant (movement_id, user_id, paid_amount, owed_amount, split_value) VALUES ($1, $2, $3, $4, $5)",
+			movementId, participant.UserId, participant.Paid, participant.Owed, participant.SplitValue,
+		)
+		if err != nil {
+			return fmt.Errorf("error al guardar el participante del movimiento: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// getItems returns the items of the movements matching the condition, in
+// their receipt order and grouped by movement id.
+func (s *SQLRepository) getItems(condition string, arg any) (map[string][]*models.MovementItem, error) {
+
+	rows, err := s.db.Query(`
+		SELECT i.movement_id, i.movement_item_id, i.description, i.amount, i.extra_amount, ip.user_id, ip.owed_amount
+		FROM public.movement_item i
+		INNER JOIN public.movement_item_participant ip ON ip.movement_item_id = i.movement_item_id
+		WHERE `+condition+`
+		ORDER BY i.movement_id, i.position, ip.user_id`, arg)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los items del movimiento: %w", err)
+	}
+	defer rows.Close()
+
+	items := make(map[string][]*models.MovementItem)
+	var item *models.MovementItem
+	for rows.Next() {
+		var movementId, itemId []uint8
+		var description string
+		var amount, extra decimal.Decimal
+		participant := new(models.MovementItemParticipant)
+		if err := rows.Scan(&movementId, &itemId, &description, &amount, &extra, &participant.UserId, &participant.Owed); err != nil {
+			return nil, err
+		}
+		if item == nil || string(item.MovementItemId) != string(itemId) {
+			item = &models.MovementItem{MovementItemId: itemId, Description: description, Amount: amount, Extra: extra}
+			items[string(movementId)] = append(items[string(movementId)], item)
+		}
+		item.Participants = append(item.Participants, participant)
+	}
+
+	return items, rows.Err()
+}
+
+func insertItems(tx *sql.Tx, movementId []uint8, items []*models.MovementItem) error {
+
+	for position, item := range items {
+		var itemId []uint8
+		err := tx.QueryRow(
+			"INSERT INTO public.movement_item (movement_id, position, description, amount, extra_amount) VALUES ($1, $2, $3, $4, $5) RETURNING movement_item_id",
+			movementId, position, item.Description, item.Amount, item.Extra,
+		).Scan(&itemId)
+		if err != nil {
+			return fmt.Errorf("error al guardar el item del movimiento: %w", err)
+		}
+
+		for _, participant := range item.Participants {
+			_, err := tx.Exec(
+				"INSERT INTO public.movement_item_participant (movement_item_id, user_id, owed_amount) VALUES ($1, $2, $3)",
+				itemId, participant.UserId, participant.Owed,
+			)
+			if err != nil {
+				return fmt.Errorf("error al guardar el participante del item: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// getFieldValues returns the custom field values of the movements matching
+// the condition, grouped by movement id.
+func (s *SQLRepository) getFieldValues(condition string, arg any) (map[string][]models.MovementFieldValue, error) {
+
+	rows, err := s.db.Query(`
+		SELECT v.movement_id, v.movement_field_id, f.name, v.value
+		FROM public.movement_field_value v
+		INNER JOIN public.movement_field f ON f.movement_field_id = v.movement_field_id
+		INNER JOIN public.movement m ON m.movement_id = v.movement_id
+		WHERE `+condition+`
+		ORDER BY f.name`, arg)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los campos del movimiento: %w", err)
+	}
+	defer rows.Close()
+
+	values := make(map[string][]models.MovementFieldValue)
+	for rows.Next() {
+		var movementId []uint8
+		var value models.MovementFieldValue
+		if err := rows.Scan(&movementId, &value.MovementFieldId, &value.Name, &value.Value); err != nil {
+			return nil, err
+		}
+		values[string(movementId)] = append(values[string(movementId)], value)
+	}
+
+	return values, rows.Err()
+}
+
+func insertFieldValues(tx *sql.Tx, movementId []uint8, values []models.MovementFieldValue) error {
+
+	for _, value := range values {
+		_, err := tx.Exec(
+			"INSERT INTO public.movement_field_value (movement_id, movement_field_id, value) VALUES ($1, $2, $3)",
+			movementId, value.MovementFieldId, value.Value,
+		)
+		if err != nil {
+			return fmt.Errorf("error al guardar el campo %s: %w", value.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// insertBatches inserts the rows into target with one multi-row INSERT for
+// every importBatchSize rows.
+func insertBatches(tx *sql.Tx, target string, rows [][]any) error {
+
+	for start := 0; start < len(rows); start += importBatchSize {
+		end := min(start+importBatchSize, len(rows))
+
+		var query strings.Builder
+		args := make([]any, 0, (end-start)*len(rows[start]))
+		query.WriteString("INSERT INTO " + target + " VALUES ")
+		for i, row := range rows[start:end] {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteString("(")
+			for j, value := range row {
+				if j > 0 {
+					query.WriteString(", ")
+				}
+				args = append(args, value)
+				fmt.Fprintf(&query, "$%d", len(args))
+			}
+			query.WriteString(")")
+		}
+
+		if _, err := tx.Exec(query.String(), args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRowIntoMovement(row rowScanner) (*models.Movement, error) {
+
+	movement := new(models.Movement)
+	var description, bankTransactionId sql.NullString
+	err := row.Scan(
+		&movement.MovementId,
+		&movement.GroupId,
+		&movement.Amount,
+		&movement.Currency,
+		&description,
+		&movement.MovementDate,
+		&movement.SplitMode,
+		&movement.Tax,
+		&movement.Tip,
+		&movement.CategoryId,
+		&bankTransactionId,
+		&movement.CreatedAt,
+		&movement.CreatedBy,
+		&movement.UpdatedAt,
+		&movement.UpdatedBy,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrMovementNotFound
+		}
+		return nil, errors.ErrMovementScan(err.Error())
+	}
+	movement.Description = description.String
+	movement.BankTransactionId = bankTransactionId.String
+	return movement, nil
+}
+
+// nullableId stores an empty id as NULL instead of an empty string
+func nullableId(id []uint8) any {
+
+	if len(id) == 0 {
+		return nil
+	}
+	return id
+}
+
+func scanRowIntoAttachment(row rowScanner) (*models.Attachment, error) {
+
+	attachment := new(models.Attachment)
+	err := row.Scan(
+		&attachment.AttachmentId,
+		&attachment.MovementId,
+		&attachment.StorageKey,
+		&attachment.FileName,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.CreatedAt,
+		&attachment.CreatedBy,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrAttachmentNotFound
+		}
+		return nil, errors.ErrAttachmentScan(err.Error())
+	}
+	return attachment, nil
+}
+
+func scanRowIntoComment(row rowScanner) (*models.Comment, error) {
+
+	comment := new(models.Comment)
+	err := row.Scan(
+		&comment.CommentId,
+		&comment.MovementId,
+		&comment.Text,
+		&comment.CreatedAt,
+		&comment.CreatedBy,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrCommentNotFound
+		}
+		return nil, errors.ErrCommentScan(err.Error())
+	}
+	return comment, nil
+}
+
+func checkAffected(res sql.Result) error {
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrMovementNotFound
+	}
+	return nil
+}
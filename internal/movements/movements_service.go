@@ -0,0 +1,268 @@
+package movements
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/fields"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+)
+
+type Service struct {
+	repository         models.MovementRepository
+	fieldRepository    models.FieldRepository
+	rateService        models.ExchangeRateService
+	categoryRepository models.CategoryRepository
+	budgetService      models.BudgetService
+	storage            models.Storage
+}
+
+func NewService(repository models.MovementRepository, fieldRepository models.FieldRepository, rateService models.ExchangeRateService, categoryRepository models.CategoryRepository, budgetService models.BudgetService, storage models.Storage) *Service {
+	return &Service{repository: repository, fieldRepository: fieldRepository, rateService: rateService, categoryRepository: categoryRepository, budgetService: budgetService, storage: storage}
+}
+
+func (s *Service) CreateMovement(payload models.CreateMovementPayload, groupId []uint8, userId []uint8) (*models.Movement, error) {
+
+	group, err := s.loadGroupData(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	movement, err := s.buildMovement(payload, group, userId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	movementId, err := s.repository.CreateMovement(*movement)
+	if err != nil {
+		return nil, errors.ErrCreateMovement(err.Error())
+	}
+
+	s.checkBudgets(*movement)
+
+	return s.repository.GetMovementById(groupId, movementId)
+}
+
+func (s *Service) GetMovementById(groupId []uint8, movementId []uint8) (*models.Movement, error) {
+
+	return s.repository.GetMovementById(groupId, movementId)
+}
+
+func (s *Service) GetGroupMovements(groupId []uint8, filter models.MovementFilter, page models.PageRequest) (*models.Page[*models.Movement], error) {
+
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return nil, errors.ErrInvalidaPayload("from must not be after to")
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && filter.MinAmount.GreaterThan(*filter.MaxAmount) {
+		return nil, errors.ErrInvalidaPayload("minAmount must not be greater than maxAmount")
+	}
+
+	return s.repository.GetGroupMovements(groupId, filter, page)
+}
+
+func (s *Service) UpdateMovement(payload models.UpdateMovementPayload, groupId []uint8, movementId []uint8, userId []uint8) error {
+
+	group, err := s.loadGroupData(groupId)
+	if err != nil {
+		return err
+	}
+
+	stored, err := s.repository.GetMovementById(groupId, movementId)
+	if err != nil {
+		return err
+	}
+
+	// Both payloads have the same fields, so an update is validated as a new
+	// movement that keeps the split of the stored one where the payload has
+	// none, and only its id and the editor are set on top
+	movement, err := s.buildMovement(models.CreateMovementPayload(payload), group, userId, stored)
+	if err != nil {
+		return err
+	}
+	movement.MovementId = movementId
+	movement.CreatedBy = nil
+
+	if err := s.repository.UpdateMovement(*movement); err != nil {
+		return err
+	}
+
+	s.checkBudgets(*movement)
+
+	return nil
+}
+
+// DeleteMovement also deletes the files of its attachments, their rows go
+// with the movement
+func (s *Service) DeleteMovement(groupId []uint8, movementId []uint8) error {
+
+	attachments, err := s.repository.GetAttachments(groupId, movementId)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repository.DeleteMovement(groupId, movementId); err != nil {
+		return err
+	}
+
+	for _, attachment := range attachments {
+		s.deleteFile(attachment.StorageKey)
+	}
+	return nil
+}
+
+// Aux Functions
+
+// groupData is what validating a movement reads from its group. Imports load
+// it once and check every row against it instead of querying for each one.
+type groupData struct {
+	groupId      []uint8
+	baseCurrency string
+	memberIds    []string
+	fields       []*models.MovementField
+	categories   []*models.Category
+	categoryIds  map[string][]uint8
+	// Conversions already checked, by currency and date
+	rates map[string]error
+}
+
+func (s *Service) loadGroupData(groupId []uint8) (*groupData, error) {
+
+	baseCurrency, err := s.repository.GetGroupBaseCurrency(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	memberIds, err := s.repository.GetGroupMemberIds(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := s.fieldRepository.GetGroupFields(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := s.categoryRepository.GetGroupCategories(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryIds := make(map[string][]uint8, len(categories))
+	for _, category := range categories {
+		categoryIds[strings.ToLower(string(category.CategoryId))] = category.CategoryId
+	}
+
+	return &groupData{
+		groupId:      groupId,
+		baseCurrency: baseCurrency,
+		memberIds:    memberIds,
+		fields:       schema,
+		categories:   categories,
+		categoryIds:  categoryIds,
+		rates:        make(map[string]error),
+	}, nil
+}
+
+// buildMovement validates a movement without saving it. stored is the saved
+// movement on updates and nil on new ones.
+func (s *Service) buildMovement(payload models.CreateMovementPayload, group *groupData, userId []uint8, stored *models.Movement) (*models.Movement, error) {
+
+	if err := utils.ValidateAmount(payload.Amount); err != nil {
+		return nil, err
+	}
+
+	movementDate, err := utils.ParseDate("movementDate", payload.MovementDate)
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := s.resolveCurrency(group, payload.Currency, movementDate)
+	if err != nil {
+		return nil, err
+	}
+
+	splitMode, participants, items, err := s.buildParticipants(group, userId, stored, payload.Amount, payload.SplitMode, payload.Payers, payload.Participants, payload.Items, payload.Tax, payload.Tip)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := validateFields(group, payload.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryId, err := validateCategory(group, payload.CategoryId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Movement{
+		GroupId:      group.groupId,
+		Amount:       payload.Amount,
+		Currency:     currency,
+		Description:  payload.Description,
+		MovementDate: movementDate,
+		SplitMode:    splitMode,
+		Tax:          payload.Tax,
+		Tip:          payload.Tip,
+		CategoryId:   categoryId,
+		Participants: participants,
+		Items:        items,
+		Fields:       values,
+		CreatedBy:    userId,
+		UpdatedBy:    userId,
+	}, nil
+}
+
+func validateFields(group *groupData, values map[string]string) ([]models.MovementFieldValue, error) {
+
+	return fields.ValidateValues(group.fields, values)
+}
+
+// checkBudgets runs after the movement is saved, so a failure is only logged
+func (s *Service) checkBudgets(movement models.Movement) {
+
+	if _, err := s.budgetService.CheckBudgets(movement.GroupId, movement.CategoryId, movement.MovementDate); err != nil {
+		log.Printf("error checking the budgets of group %s: %v", movement.GroupId, err)
+	}
+}
+
+// validateCategory checks that the category belongs to the group
+func validateCategory(group *groupData, categoryId string) ([]uint8, error) {
+
+	if categoryId == "" {
+		return nil, nil
+	}
+
+	id, ok := group.categoryIds[strings.ToLower(categoryId)]
+	if !ok {
+		return nil, errors.ErrInvalidaPayload(fmt.Sprintf("category %s does not belong to the group", categoryId))
+	}
+
+	return id, nil
+}
+
+// resolveCurrency defaults the currency to the base currency of the group and
+// checks that the movement can be converted to it on its date.
+func (s *Service) resolveCurrency(group *groupData, currency string, movementDate time.Time) (string, error) {
+
+	if currency == "" || currency == group.baseCurrency {
+		return group.baseCurrency, nil
+	}
+
+	key := currency + "|" + movementDate.Format(time.DateOnly)
+	err, ok := group.rates[key]
+	if !ok {
+		_, err = s.rateService.GetRate(currency, group.baseCurrency, movementDate)
+		group.rates[key] = err
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return currency, nil
+}
@@ -0,0 +1,305 @@
+package movements
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/money"
+	"github.com/shopspring/decimal"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// Split returns how much of amount each user owes. values holds the exact
+// amount, percentage or share of every participant depending on mode; on
+// equal splits only its keys are used.
+func Split(amount decimal.Decimal, mode string, values map[string]decimal.Decimal) (map[string]decimal.Decimal, error) {
+
+	if len(values) == 0 {
+		return nil, errors.ErrInvalidaPayload("a movement needs at least one participant")
+	}
+
+	for userId, value := range values {
+		if value.IsNegative() {
+			return nil, errors.ErrInvalidaPayload(fmt.Sprintf("participant %s has a negative value", userId))
+		}
+	}
+
+	switch mode {
+	case models.SplitModeEqual:
+		weights := make(map[string]decimal.Decimal, len(values))
+		for userId := range values {
+			weights[userId] = decimal.NewFromInt(1)
+		}
+		return money.Allocate(amount, weights), nil
+
+	case models.SplitModeExact:
+		total := decimal.Zero
+		for userId, value := range values {
+			if !value.Equal(value.Round(2)) {
+				return nil, errors.ErrInvalidaPayload(fmt.Sprintf("participant %s amount can not have more than 2 decimals", userId))
+			}
+			total = total.Add(value)
+		}
+		if !total.Equal(amount) {
+			return nil, errors.ErrInvalidaPayload(fmt.Sprintf("exact amounts add up to %s but the movement amount is %s", total, amount))
+		}
+		return values, nil
+
+	case models.SplitModePercentage:
+		if total := money.Sum(values); !total.Equal(hundred) {
+			return nil, errors.ErrInvalidaPayload(fmt.Sprintf("percentages add up to %s instead of 100", total))
+		}
+		return money.Allocate(amount, values), nil
+
+	case models.SplitModeShares:
+		if !money.Sum(values).IsPositive() {
+			return nil, errors.ErrInvalidaPayload("at least one participant needs a share greater than zero")
+		}
+		return money.Allocate(amount, values), nil
+	}
+
+	return nil, errors.ErrInvalidaPayload(fmt.Sprintf("unknown split mode %s", mode))
+}
+
+// SplitItems returns how much each user owes of an itemized movement, and its
+// items. The tax and tip are spread over the items in proportion to their
+// amount, and every item with its part is split equally between its
+// participants, so the items, tax and tip must add up to amount.
+func SplitItems(amount decimal.Decimal, tax decimal.Decimal, tip decimal.Decimal, items []models.MovementItemPayload) (map[string]decimal.Decimal, []*models.MovementItem, error) {
+
+	if len(items) == 0 {
+		return nil, nil, errors.ErrInvalidaPayload("an items split needs at least one item")
+	}
+
+	for name, value := range map[string]decimal.Decimal{"tax": tax, "tip": tip} {
+		if value.IsNegative() || !value.Equal(value.Round(2)) {
+			return nil, nil, errors.ErrInvalidaPayload(fmt.Sprintf("%s can not be negative or have more than 2 decimals", name))
+		}
+	}
+
+	extra := tax.Add(tip)
+	total := extra
+	weights := make(map[string]decimal.Decimal, len(items))
+	for i, item := range items {
+		if !item.Amount.IsPositive() || !item.Amount.Equal(item.Amount.Round(2)) {
+			return nil, nil, errors.ErrInvalidaPayload(fmt.Sprintf("item %d amount must be positive with at most 2 decimals", i+1))
+		}
+		weights[itemKey(i)] = item.Amount
+		total = total.Add(item.Amount)
+	}
+	if !total.Equal(amount) {
+		return nil, nil, errors.ErrInvalidaPayload(fmt.Sprintf("items, tax and tip add up to %s but the movement amount is %s", total, amount))
+	}
+
+	extras := money.Allocate(extra, weights)
+	owed := make(map[string]decimal.Decimal)
+	movementItems := make([]*models.MovementItem, len(items))
+
+	for i, item := range items {
+		shares := make(map[string]decimal.Decimal, len(item.Participants))
+		for _, participantId := range item.Participants {
+			participantId = strings.ToLower(participantId)
+			if _, ok := shares[participantId]; ok {
+				return nil, nil, errors.ErrInvalidaPayload(fmt.Sprintf("participant %s is repeated in item %d", participantId, i+1))
+			}
+			shares[participantId] = decimal.NewFromInt(1)
+		}
+		if len(shares) == 0 {
+			return nil, nil, errors.ErrInvalidaPayload(fmt.Sprintf("item %d needs at least one participant", i+1))
+		}
+
+		movementItem := &models.MovementItem{Description: item.Description, Amount: item.Amount, Extra: extras[itemKey(i)]}
+		for participantId, share := range money.Allocate(item.Amount.Add(movementItem.Extra), shares) {
+			owed[participantId] = owed[participantId].Add(share)
+			movementItem.Participants = append(movementItem.Participants, &models.MovementItemParticipant{UserId: []uint8(participantId), Owed: share})
+		}
+		sort.Slice(movementItem.Participants, func(a, b int) bool {
+			return string(movementItem.Participants[a].UserId) < string(movementItem.Participants[b].UserId)
+		})
+		movementItems[i] = movementItem
+	}
+
+	return owed, movementItems, nil
+}
+
+// buildParticipants validates who paid the movement and how it is split.
+// Without payers the creator paid everything, and an equal split without
+// participants is shared by every member of the group. Items, tax and tip
+// are only used by items splits, which take the participants from the items.
+// On updates stored is the saved movement: the split mode, payers and
+// participants the payload leaves out are kept from it, and its people stay
+// valid even if they left the group.
+func (s *Service) buildParticipants(group *groupData, userId []uint8, stored *models.Movement, amount decimal.Decimal, mode string, payers []models.MovementPayerPayload, participants []models.MovementParticipantPayload, items []models.MovementItemPayload, tax decimal.Decimal, tip decimal.Decimal) (string, []*models.MovementParticipant, []*models.MovementItem, error) {
+
+	if mode == "" {
+		mode = models.SplitModeEqual
+		if stored != nil {
+			mode = stored.SplitMode
+		}
+	}
+
+	if mode == models.SplitModeItems && len(participants) > 0 {
+		return "", nil, nil, errors.ErrInvalidaPayload("the participants of an items split are the ones of its items")
+	}
+	if mode != models.SplitModeItems && (len(items) > 0 || !tax.IsZero() || !tip.IsZero()) {
+		return "", nil, nil, errors.ErrInvalidaPayload("items, tax and tip are only used with the items split mode")
+	}
+
+	memberIds := group.memberIds
+	members := make(map[string]bool, len(memberIds))
+	for _, id := range memberIds {
+		members[id] = true
+	}
+
+	storedPaid, storedValues := map[string]decimal.Decimal{}, map[string]decimal.Decimal{}
+	if stored != nil {
+		storedPaid, storedValues = storedSplit(stored, mode)
+		for _, participant := range stored.Participants {
+			members[string(participant.UserId)] = true
+		}
+	}
+
+	paid := map[string]decimal.Decimal{string(userId): amount}
+	if len(storedPaid) > 0 {
+		// Scaled to the new amount, so a changed amount keeps the proportions
+		paid = money.Allocate(amount, storedPaid)
+	}
+	if len(payers) > 0 {
+		paid = make(map[string]decimal.Decimal, len(payers))
+		for _, payer := range payers {
+			payerId := strings.ToLower(payer.UserId)
+			if _, ok := paid[payerId]; ok {
+				return "", nil, nil, errors.ErrInvalidaPayload(fmt.Sprintf("payer %s is repeated", payerId))
+			}
+			if !payer.Amount.IsPositive() || !payer.Amount.Equal(payer.Amount.Round(2)) {
+				return "", nil, nil, errors.ErrInvalidaPayload(fmt.Sprintf("payer %s amount must be positive with at most 2 decimals", payerId))
+			}
+			paid[payerId] = payer.Amount
+		}
+		if total := money.Sum(paid); !total.Equal(amount) {
+			return "", nil, nil, errors.ErrInvalidaPayload(fmt.Sprintf("payers add up to %s but the movement amount is %s", total, amount))
+		}
+	}
+
+	for id := range paid {
+		if !members[id] {
+			return "", nil, nil, errors.ErrInvalidaPayload(fmt.Sprintf("payer %s is not a member of the group", id))
+		}
+	}
+
+	if mode == models.SplitModeItems {
+		owed, movementItems, err := SplitItems(amount, tax, tip, items)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		for id := range owed {
+			if !members[id] {
+				return "", nil, nil, errors.ErrInvalidaPayload(fmt.Sprintf("participant %s is not a member of the group", id))
+			}
+		}
+		return mode, mergeParticipants(mode, paid, owed, nil), movementItems, nil
+	}
+
+	values := make(map[string]decimal.Decimal, len(participants))
+	for _, participant := range participants {
+		participantId := strings.ToLower(participant.UserId)
+		if _, ok := values[participantId]; ok {
+			return "", nil, nil, errors.ErrInvalidaPayload(fmt.Sprintf("participant %s is repeated", participantId))
+		}
+		values[participantId] = participant.Value
+	}
+	if len(values) == 0 {
+		values = storedValues
+	}
+	if len(values) == 0 && mode == models.SplitModeEqual {
+		for _, id := range memberIds {
+			values[id] = decimal.Zero
+		}
+	}
+
+	for id := range values {
+		if !members[id] {
+			return "", nil, nil, errors.ErrInvalidaPayload(fmt.Sprintf("participant %s is not a member of the group", id))
+		}
+	}
+
+	owed, err := Split(amount, mode, values)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return mode, mergeParticipants(mode, paid, owed, values), nil, nil
+}
+
+// Aux Functions
+
+// storedSplit returns the payers of a stored movement and the split values
+// an update in mode can keep: the values of the same mode, or the people who
+// owe something when the update splits equally. Items are never kept.
+func storedSplit(stored *models.Movement, mode string) (map[string]decimal.Decimal, map[string]decimal.Decimal) {
+
+	paid := make(map[string]decimal.Decimal)
+	values := make(map[string]decimal.Decimal)
+
+	for _, participant := range stored.Participants {
+		userId := string(participant.UserId)
+		if participant.Paid.IsPositive() {
+			paid[userId] = participant.Paid
+		}
+
+		switch {
+		case mode == models.SplitModeItems || stored.SplitMode == models.SplitModeItems:
+		case mode == stored.SplitMode && participant.SplitValue != nil:
+			values[userId] = *participant.SplitValue
+		case mode == models.SplitModeEqual && participant.Owed.IsPositive():
+			values[userId] = decimal.Zero
+		}
+	}
+
+	return paid, values
+}
+
+func mergeParticipants(mode string, paid map[string]decimal.Decimal, owed map[string]decimal.Decimal, values map[string]decimal.Decimal) []*models.MovementParticipant {
+
+	byId := make(map[string]*models.MovementParticipant)
+	participant := func(userId string) *models.MovementParticipant {
+		if p, ok := byId[userId]; ok {
+			return p
+		}
+		p := &models.MovementParticipant{UserId: []uint8(userId)}
+		byId[userId] = p
+		return p
+	}
+
+	for userId, amount := range paid {
+		participant(userId).Paid = amount
+	}
+	for userId, amount := range owed {
+		p := participant(userId)
+		p.Owed = amount
+		if value, ok := values[userId]; ok && mode != models.SplitModeEqual {
+			p.SplitValue = &value
+		}
+	}
+
+	result := make([]*models.MovementParticipant, 0, len(byId))
+	for _, p := range byId {
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return string(result[i].UserId) < string(result[j].UserId)
+	})
+
+	return result
+}
+
+// itemKey orders like the position of the item, so the cents Allocate
+// hands out on ties go to the first items
+func itemKey(position int) string {
+
+	return fmt.Sprintf("%06d", position)
+}
@@ -0,0 +1,392 @@
+package movements
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestSplit(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		amount  string
+		mode    string
+		values  map[string]string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "equal ignores the values",
+			amount: "10",
+			mode:   models.SplitModeEqual,
+			values: map[string]string{"a": "0", "b": "7", "c": "0"},
+			want:   map[string]string{"a": "3.34", "b": "3.33", "c": "3.33"},
+		},
+		{
+			name:   "exact",
+			amount: "10",
+			mode:   models.SplitModeExact,
+			values: map[string]string{"a": "4", "b": "6"},
+			want:   map[string]string{"a": "4", "b": "6"},
+		},
+		{
+			name:    "exact amounts that do not add up",
+			amount:  "10",
+			mode:    models.SplitModeExact,
+			values:  map[string]string{"a": "4", "b": "5"},
+			wantErr: true,
+		},
+		{
+			name:    "exact amount with fractions of a cent",
+			amount:  "10",
+			mode:    models.SplitModeExact,
+			values:  map[string]string{"a": "3.333", "b": "6.667"},
+			wantErr: true,
+		},
+		{
+			name:   "percentage remainder",
+			amount: "9.99",
+			mode:   models.SplitModePercentage,
+			values: map[string]string{"a": "50", "b": "50"},
+			want:   map[string]string{"a": "5", "b": "4.99"},
+		},
+		{
+			name:    "percentages that do not add up to 100",
+			amount:  "10",
+			mode:    models.SplitModePercentage,
+			values:  map[string]string{"a": "50", "b": "49.99"},
+			wantErr: true,
+		},
+		{
+			name:   "shares",
+			amount: "30",
+			mode:   models.SplitModeShares,
+			values: map[string]string{"a": "1", "b": "2", "c": "0"},
+			want:   map[string]string{"a": "10", "b": "20", "c": "0"},
+		},
+		{
+			name:   "shares remainder",
+			amount: "10",
+			mode:   models.SplitModeShares,
+			values: map[string]string{"a": "1", "b": "1", "c": "1"},
+			want:   map[string]string{"a": "3.34", "b": "3.33", "c": "3.33"},
+		},
+		{
+			name:    "no shares",
+			amount:  "10",
+			mode:    models.SplitModeShares,
+			values:  map[string]string{"a": "0", "b": "0"},
+			wantErr: true,
+		},
+		{
+			name:    "negative value",
+			amount:  "10",
+			mode:    models.SplitModeShares,
+			values:  map[string]string{"a": "-1", "b": "2"},
+			wantErr: true,
+		},
+		{
+			name:    "no participants",
+			amount:  "10",
+			mode:    models.SplitModeEqual,
+			wantErr: true,
+		},
+		{
+			name:    "unknown mode",
+			amount:  "10",
+			mode:    "halves",
+			values:  map[string]string{"a": "1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := make(map[string]decimal.Decimal, len(tt.values))
+			for userId, value := range tt.values {
+				values[userId] = dec(value)
+			}
+
+			owed, err := Split(dec(tt.amount), tt.mode, values)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Split() = %v, want an error", owed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Split() error = %v", err)
+			}
+
+			if got := amounts(owed); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Split() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitItems(t *testing.T) {
+
+	item := func(amount string, participants ...string) models.MovementItemPayload {
+		return models.MovementItemPayload{Description: "item", Amount: dec(amount), Participants: participants}
+	}
+
+	tests := []struct {
+		name       string
+		amount     string
+		tax        string
+		tip        string
+		items      []models.MovementItemPayload
+		want       map[string]string
+		wantExtras []string
+		wantErr    bool
+	}{
+		{
+			name:       "tax and tip in proportion to the items",
+			amount:     "33",
+			tax:        "1.5",
+			tip:        "1.5",
+			items:      []models.MovementItemPayload{item("10", "a"), item("20", "a", "b")},
+			want:       map[string]string{"a": "22", "b": "11"},
+			wantExtras: []string{"1", "2"},
+		},
+		{
+			name:       "the extra cent goes to the first item",
+			amount:     "10.01",
+			tax:        "0.01",
+			tip:        "0",
+			items:      []models.MovementItemPayload{item("5", "a"), item("5", "b")},
+			want:       map[string]string{"a": "5.01", "b": "5"},
+			wantExtras: []string{"0.01", "0"},
+		},
+		{
+			name:       "items are split equally with their remainder",
+			amount:     "10",
+			tax:        "0",
+			tip:        "0",
+			items:      []models.MovementItemPayload{item("10", "C", "b", "a")},
+			want:       map[string]string{"a": "3.34", "b": "3.33", "c": "3.33"},
+			wantExtras: []string{"0"},
+		},
+		{
+			name:       "tip spread over uneven items",
+			amount:     "11",
+			tax:        "0",
+			tip:        "1",
+			items:      []models.MovementItemPayload{item("3", "a"), item("3", "b"), item("4", "c")},
+			want:       map[string]string{"a": "3.3", "b": "3.3", "c": "4.4"},
+			wantExtras: []string{"0.3", "0.3", "0.4"},
+		},
+		{
+			name:    "no items",
+			amount:  "10",
+			tax:     "0",
+			tip:     "0",
+			wantErr: true,
+		},
+		{
+			name:    "negative tax",
+			amount:  "9",
+			tax:     "-1",
+			tip:     "0",
+			items:   []models.MovementItemPayload{item("10", "a")},
+			wantErr: true,
+		},
+		{
+			name:    "tip with fractions of a cent",
+			amount:  "10.005",
+			tax:     "0",
+			tip:     "0.005",
+			items:   []models.MovementItemPayload{item("10", "a")},
+			wantErr: true,
+		},
+		{
+			name:    "item without amount",
+			amount:  "10",
+			tax:     "0",
+			tip:     "0",
+			items:   []models.MovementItemPayload{item("10", "a"), item("0", "b")},
+			wantErr: true,
+		},
+		{
+			name:    "items that do not add up",
+			amount:  "12",
+			tax:     "1",
+			tip:     "0",
+			items:   []models.MovementItemPayload{item("10", "a")},
+			wantErr: true,
+		},
+		{
+			name:    "repeated participant",
+			amount:  "10",
+			tax:     "0",
+			tip:     "0",
+			items:   []models.MovementItemPayload{item("10", "a", "A")},
+			wantErr: true,
+		},
+		{
+			name:    "item without participants",
+			amount:  "10",
+			tax:     "0",
+			tip:     "0",
+			items:   []models.MovementItemPayload{item("10")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owed, items, err := SplitItems(dec(tt.amount), dec(tt.tax), dec(tt.tip), tt.items)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SplitItems() = %v, want an error", owed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SplitItems() error = %v", err)
+			}
+
+			if got := amounts(owed); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("SplitItems() owed = %v, want %v", got, tt.want)
+			}
+
+			extras := make([]string, len(items))
+			total := decimal.Zero
+			for i, item := range items {
+				extras[i] = item.Extra.String()
+				for _, participant := range item.Participants {
+					total = total.Add(participant.Owed)
+				}
+			}
+			if fmt.Sprint(extras) != fmt.Sprint(tt.wantExtras) {
+				t.Errorf("SplitItems() extras = %v, want %v", extras, tt.wantExtras)
+			}
+			if !total.Equal(dec(tt.amount)) {
+				t.Errorf("SplitItems() items add up to %s, want %s", total, tt.amount)
+			}
+		})
+	}
+}
+
+func TestBuildParticipantsKeepsStoredSplit(t *testing.T) {
+
+	group := &groupData{memberIds: []string{"a", "b", "c"}}
+
+	// a paid 30 shared equally with b, "old" was part of it and left the group
+	equal := &models.Movement{SplitMode: models.SplitModeEqual, Participants: []*models.MovementParticipant{
+		{UserId: []uint8("a"), Paid: dec("30"), Owed: dec("10")},
+		{UserId: []uint8("b"), Owed: dec("10")},
+		{UserId: []uint8("old"), Owed: dec("10")},
+	}}
+	shares := &models.Movement{SplitMode: models.SplitModeShares, Participants: []*models.MovementParticipant{
+		{UserId: []uint8("a"), Paid: dec("20"), Owed: dec("5"), SplitValue: ptr(dec("1"))},
+		{UserId: []uint8("b"), Paid: dec("10"), Owed: dec("25"), SplitValue: ptr(dec("5"))},
+		{UserId: []uint8("c"), SplitValue: ptr(dec("0"))},
+	}}
+
+	tests := []struct {
+		name         string
+		stored       *models.Movement
+		amount       string
+		mode         string
+		payers       []models.MovementPayerPayload
+		participants []models.MovementParticipantPayload
+		want         map[string]string
+	}{
+		{
+			name:   "new movement",
+			amount: "30",
+			want:   map[string]string{"a": "0/10", "b": "0/10", "c": "30/10"},
+		},
+		{
+			name:   "same amount keeps payers and participants",
+			stored: equal,
+			amount: "30",
+			want:   map[string]string{"a": "30/10", "b": "0/10", "old": "0/10"},
+		},
+		{
+			name:   "new amount scales the payers",
+			stored: equal,
+			amount: "31",
+			want:   map[string]string{"a": "31/10.34", "b": "0/10.33", "old": "0/10.33"},
+		},
+		{
+			name:   "omitted mode keeps the shares",
+			stored: shares,
+			amount: "60",
+			want:   map[string]string{"a": "40/10", "b": "20/50", "c": "0/0"},
+		},
+		{
+			name:   "equal split keeps the people who owed",
+			stored: shares,
+			amount: "30",
+			mode:   models.SplitModeEqual,
+			want:   map[string]string{"a": "20/15", "b": "10/15"},
+		},
+		{
+			name:         "payload payers and participants win",
+			stored:       equal,
+			amount:       "30",
+			payers:       []models.MovementPayerPayload{{UserId: "B", Amount: dec("30")}},
+			participants: []models.MovementParticipantPayload{{UserId: "c"}},
+			want:         map[string]string{"b": "30/0", "c": "0/30"},
+		},
+	}
+
+	s := &Service{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, participants, _, err := s.buildParticipants(group, []uint8("c"), tt.stored, dec(tt.amount), tt.mode, tt.payers, tt.participants, nil, decimal.Zero, decimal.Zero)
+			if err != nil {
+				t.Fatalf("buildParticipants() error = %v", err)
+			}
+
+			got := make(map[string]string, len(participants))
+			for _, p := range participants {
+				got[string(p.UserId)] = p.Paid.String() + "/" + p.Owed.String()
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("buildParticipants() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildParticipantsNeedsValuesOnModeChange(t *testing.T) {
+
+	group := &groupData{memberIds: []string{"a", "b"}}
+	stored := &models.Movement{SplitMode: models.SplitModeEqual, Participants: []*models.MovementParticipant{
+		{UserId: []uint8("a"), Paid: dec("10"), Owed: dec("5")},
+		{UserId: []uint8("b"), Owed: dec("5")},
+	}}
+
+	s := &Service{}
+	if _, _, _, err := s.buildParticipants(group, []uint8("a"), stored, dec("10"), models.SplitModePercentage, nil, nil, nil, decimal.Zero, decimal.Zero); err == nil {
+		t.Errorf("buildParticipants() kept equal participants for a percentage split")
+	}
+}
+
+// Aux Functions
+
+func dec(value string) decimal.Decimal {
+
+	return decimal.RequireFromString(value)
+}
+
+func ptr(value decimal.Decimal) *decimal.Decimal {
+
+	return &value
+}
+
+func amounts(values map[string]decimal.Decimal) map[string]string {
+
+	result := make(map[string]string, len(values))
+	for key, value := range values {
+		result[key] = value.String()
+	}
+	return result
+}
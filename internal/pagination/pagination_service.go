@@ -0,0 +1,186 @@
+package pagination
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+// Column is an SQL expression a list is ordered by and the type its cursor
+// value is cast to
+type Column struct {
+	Expr string
+	Type string
+}
+
+// Fields maps the sortable fields of a list to the columns they order by. The
+// last column of every field must be unique, so the order is total and a
+// cursor always points between two rows.
+type Fields map[string][]Column
+
+// Query holds the SQL parts of a page. Keys must be appended to the select
+// list and scanned with Scanner; Condition, when not empty, goes in the WHERE
+// clause with Args as its parameters.
+type Query struct {
+	Keys      string
+	Condition string
+	Args      []any
+	Order     string
+	Limit     int
+	sort      string
+	columns   int
+}
+
+// cursor is the position after the last row of a page: the sort it belongs
+// to and the text value of every column of that sort for the row. Rows
+// inserted later never move it, unlike an offset.
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+// ParseRequest reads ?limit=, ?cursor= and ?sort= from the request
+func ParseRequest(r *http.Request) (models.PageRequest, error) {
+
+	query := r.URL.Query()
+	page := models.PageRequest{
+		Limit:  models.DefaultPageLimit,
+		Cursor: query.Get("cursor"),
+		Sort:   query.Get("sort"),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > models.MaxPageLimit {
+			return page, errors.ErrInvalidaPayload(fmt.Sprintf("limit must be a number between 1 and %d", models.MaxPageLimit))
+		}
+		page.Limit = n
+	}
+
+	return page, nil
+}
+
+// Build returns the query of the page. The cursor parameters are numbered
+// after the first args parameters of the rest of the query.
+func Build(fields Fields, page models.PageRequest, defaultSort string, args int) (*Query, error) {
+
+	sort := page.Sort
+	if sort == "" {
+		sort = defaultSort
+	}
+
+	columns, ok := fields[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return nil, errors.ErrInvalidSort
+	}
+
+	direction, operator := "ASC", ">"
+	if strings.HasPrefix(sort, "-") {
+		direction, operator = "DESC", "<"
+	}
+
+	limit := page.Limit
+	if limit <= 0 {
+		limit = models.DefaultPageLimit
+	}
+
+	query := &Query{Limit: limit, sort: sort, columns: len(columns)}
+
+	keys := make([]string, len(columns))
+	order := make([]string, len(columns))
+	for i, column := range columns {
+		keys[i] = column.Expr + "::text"
+		order[i] = column.Expr + " " + direction
+	}
+	query.Keys = strings.Join(keys, ", ")
+	query.Order = strings.Join(order, ", ")
+
+	if page.Cursor == "" {
+		return query, nil
+	}
+
+	position, err := decode(page.Cursor)
+	if err != nil || position.Sort != sort || len(position.Values) != len(columns) {
+		return nil, errors.ErrInvalidCursor
+	}
+
+	exprs := make([]string, len(columns))
+	params := make([]string, len(columns))
+	for i, column := range columns {
+		exprs[i] = column.Expr
+		params[i] = fmt.Sprintf("$%d::%s", args+i+1, column.Type)
+		query.Args = append(query.Args, position.Values[i])
+	}
+	query.Condition = fmt.Sprintf("(%s) %s (%s)", strings.Join(exprs, ", "), operator, strings.Join(params, ", "))
+
+	return query, nil
+}
+
+// Scanner wraps rows so the scan functions of the repositories also read the
+// keys of the row, which are left in Values
+func (q *Query) Scanner(rows *sql.Rows) *KeyScanner {
+
+	return &KeyScanner{rows: rows, Values: make([]string, q.columns)}
+}
+
+// Page fetches one row more than the limit to know if there is a next page.
+// keys are the Values of the scanner for each item.
+func Page[T any](q *Query, items []T, keys [][]string) *models.Page[T] {
+
+	page := &models.Page[T]{Items: items}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+
+	if len(items) > q.Limit {
+		page.Items = items[:q.Limit]
+		page.NextCursor = encode(cursor{Sort: q.sort, Values: keys[q.Limit-1]})
+	}
+
+	return page
+}
+
+type KeyScanner struct {
+	rows   *sql.Rows
+	Values []string
+}
+
+func (k *KeyScanner) Scan(dest ...any) error {
+
+	values := make([]string, len(k.Values))
+	for i := range values {
+		dest = append(dest, &values[i])
+	}
+	if err := k.rows.Scan(dest...); err != nil {
+		return err
+	}
+	k.Values = values
+
+	return nil
+}
+
+// Aux Functions
+
+func encode(position cursor) string {
+
+	data, _ := json.Marshal(position)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(text string) (cursor, error) {
+
+	var position cursor
+	data, err := base64.RawURLEncoding.DecodeString(text)
+	if err != nil {
+		return position, err
+	}
+	err = json.Unmarshal(data, &position)
+	return position, err
+}
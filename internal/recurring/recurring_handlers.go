@@ -0,0 +1,217 @@
+package recurring
+
+import (
+	"net/http"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/auth"
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/middlewares"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service        models.RecurringService
+	authRepository models.AuthRepository
+}
+
+func NewHandler(service models.RecurringService, authRepository models.AuthRepository) *Handler {
+	return &Handler{service: service, authRepository: authRepository}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+
+	router.HandleFunc("/group/{groupId}/recurring", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleRecurringCreate, models.PermissionEditMovements, h.authRepository))).Methods("POST")
+	router.HandleFunc("/group/{groupId}/recurring", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetGroupRecurring, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}/recurring/{recurringId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetRecurring, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}/recurring/{recurringId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleRecurringUpdate, models.PermissionEditMovements, h.authRepository))).Methods("PUT")
+	router.HandleFunc("/group/{groupId}/recurring/{recurringId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleRecurringDelete, models.PermissionEditMovements, h.authRepository))).Methods("DELETE")
+	router.HandleFunc("/group/{groupId}/recurring/{recurringId}/occurrence/{date}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleOccurrenceEdit, models.PermissionEditMovements, h.authRepository))).Methods("PUT")
+	router.HandleFunc("/group/{groupId}/recurring/{recurringId}/occurrence/{date}/skip", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleOccurrenceSkip, models.PermissionEditMovements, h.authRepository))).Methods("PUT")
+}
+
+func (h *Handler) handleRecurringCreate(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	var payload models.CreateRecurringPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	recurring, err := h.service.CreateRecurring(payload, groupId, userId)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, recurring)
+}
+
+func (h *Handler) handleGetGroupRecurring(w http.ResponseWriter, r *http.Request) {
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	recurring, err := h.service.GetGroupRecurring(groupId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, recurring)
+}
+
+func (h *Handler) handleGetRecurring(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	recurringId := []uint8(vars["recurringId"])
+
+	recurring, err := h.service.GetRecurringById(groupId, recurringId)
+	if err == errors.ErrRecurringNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, recurring)
+}
+
+func (h *Handler) handleRecurringUpdate(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	recurringId := []uint8(vars["recurringId"])
+
+	var payload models.UpdateRecurringPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	err = h.service.UpdateRecurring(payload, groupId, recurringId, userId)
+	if err == errors.ErrRecurringNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Recurring movement updated successfully",
+	})
+}
+
+func (h *Handler) handleRecurringDelete(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	recurringId := []uint8(vars["recurringId"])
+
+	err := h.service.DeleteRecurring(groupId, recurringId)
+	if err == errors.ErrRecurringNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Recurring movement deleted successfully",
+	})
+}
+
+func (h *Handler) handleOccurrenceEdit(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	recurringId := []uint8(vars["recurringId"])
+
+	var payload models.CreateMovementPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	err = h.service.EditOccurrence(payload, groupId, recurringId, vars["date"], userId)
+	writeOccurrenceResult(w, err, "Occurrence updated successfully")
+}
+
+func (h *Handler) handleOccurrenceSkip(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	recurringId := []uint8(vars["recurringId"])
+
+	err = h.service.SkipOccurrence(groupId, recurringId, vars["date"], userId)
+	writeOccurrenceResult(w, err, "Occurrence skipped successfully")
+}
+
+// Aux Functions
+
+func writeOccurrenceResult(w http.ResponseWriter, err error, message string) {
+
+	if err == errors.ErrRecurringNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err == errors.ErrOccurrenceProcessed {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": message,
+	})
+}
@@ -0,0 +1,298 @@
+package recurring
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+// Postgres SQL Repository
+type SQLRepository struct {
+	db *sql.DB
+}
+
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+const recurringColumns = `recurring_movement_id, group_id, schedule, start_date, end_date, max_count, active, resumed_at, template, created_at, created_by, updated_at, updated_by`
+
+func (s *SQLRepository) CreateRecurring(recurring models.RecurringMovement) ([]uint8, error) {
+
+	template, err := json.Marshal(recurring.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	var recurringId []uint8
+	err = s.db.QueryRow(
+		`INSERT INTO public.recurring_movement (group_id, schedule, start_date, end_date, max_count, active, resumed_at, template, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING recurring_movement_id`,
+		recurring.GroupId, recurring.Schedule, recurring.StartDate, recurring.EndDate, recurring.MaxCount, recurring.Active, recurring.ResumedAt, template, recurring.CreatedBy, recurring.UpdatedBy,
+	).Scan(&recurringId)
+
+	if err != nil {
+		return nil, fmt.Errorf("error al crear el movimiento recurrente: %w", err)
+	}
+
+	return recurringId, nil
+}
+
+func (s *SQLRepository) GetRecurringById(groupId []uint8, recurringId []uint8) (*models.RecurringMovement, error) {
+
+	row := s.db.QueryRow("SELECT "+recurringColumns+" FROM public.recurring_movement WHERE group_id = $1 AND recurring_movement_id = $2", groupId, recurringId)
+	return scanRowIntoRecurring(row)
+}
+
+func (s *SQLRepository) GetGroupRecurring(groupId []uint8) ([]*models.RecurringMovement, error) {
+
+	return s.queryRecurring("group_id = $1", groupId)
+}
+
+func (s *SQLRepository) GetActiveRecurring() ([]*models.RecurringMovement, error) {
+
+	return s.queryRecurring("active AND start_date <= CURRENT_DATE")
+}
+
+func (s *SQLRepository) UpdateRecurring(recurring models.RecurringMovement) error {
+
+	template, err := json.Marshal(recurring.Template)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.Exec(
+		`UPDATE public.recurring_movement
+		SET schedule = $1, start_date = $2, end_date = $3, max_count = $4, active = $5, resumed_at = $6, template = $7, updated_by = $8, updated_at = CURRENT_TIMESTAMP
+		WHERE group_id = $9 AND recurring_movement_id = $10`,
+		recurring.Schedule, recurring.StartDate, recurring.EndDate, recurring.MaxCount, recurring.Active, recurring.ResumedAt, template, recurring.UpdatedBy, recurring.GroupId, recurring.RecurringMovementId,
+	)
+	if err != nil {
+		return fmt.Errorf("error al actualizar el movimiento recurrente: %w", err)
+	}
+
+	return checkAffected(res)
+}
+
+// Los movimientos ya creados se conservan, solo se eliminan las ocurrencias
+func (s *SQLRepository) DeleteRecurring(groupId []uint8, recurringId []uint8) error {
+
+	res, err := s.db.Exec("DELETE FROM public.recurring_movement WHERE group_id = $1 AND recurring_movement_id = $2", groupId, recurringId)
+	if err != nil {
+		return fmt.Errorf("error al eliminar el movimiento recurrente: %w", err)
+	}
+
+	return checkAffected(res)
+}
+
+func (s *SQLRepository) GetOccurrences(recurringId []uint8) ([]*models.RecurringOccurrence, error) {
+
+	rows, err := s.db.Query(`
+		SELECT recurring_movement_id, occurrence_date, status, movement_id, override, error, updated_at, updated_by
+		FROM public.recurring_occurrence
+		WHERE recurring_movement_id = $1
+		ORDER BY occurrence_date`, recurringId)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener las ocurrencias del movimiento recurrente: %w", err)
+	}
+	defer rows.Close()
+
+	var occurrences []*models.RecurringOccurrence
+	for rows.Next() {
+		occurrence := new(models.RecurringOccurrence)
+		var override []byte
+		var errorText sql.NullString
+		err := rows.Scan(
+			&occurrence.RecurringMovementId,
+			&occurrence.OccurrenceDate,
+			&occurrence.Status,
+			&occurrence.MovementId,
+			&override,
+			&errorText,
+			&occurrence.UpdatedAt,
+			&occurrence.UpdatedBy,
+		)
+		if err != nil {
+			return nil, errors.ErrRecurringScan(err.Error())
+		}
+		if override != nil {
+			occurrence.Override = new(models.CreateMovementPayload)
+			if err := json.Unmarshal(override, occurrence.Override); err != nil {
+				return nil, errors.ErrRecurringScan(err.Error())
+			}
+		}
+		occurrence.Error = errorText.String
+		occurrences = append(occurrences, occurrence)
+	}
+
+	return occurrences, rows.Err()
+}
+
+// Guarda una ocurrencia omitida o editada. Devuelve false si el scheduler ya
+// la proceso
+func (s *SQLRepository) SaveOccurrence(occurrence models.RecurringOccurrence) (bool, error) {
+
+	// A nil []byte would be sent as an empty string, which is not valid JSONB
+	var override any
+	if occurrence.Override != nil {
+		data, err := json.Marshal(occurrence.Override)
+		if err != nil {
+			return false, err
+		}
+		override = data
+	}
+
+	res, err := s.db.Exec(
+		`INSERT INTO public.recurring_occurrence (recurring_movement_id, occurrence_date, status, override, updated_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (recurring_movement_id, occurrence_date) DO UPDATE
+		SET status = EXCLUDED.status, override = EXCLUDED.override, updated_by = EXCLUDED.updated_by, updated_at = CURRENT_TIMESTAMP
+		WHERE recurring_occurrence.status IN ('scheduled', 'skipped')`,
+		occurrence.RecurringMovementId, occurrence.OccurrenceDate, occurrence.Status, override, occurrence.UpdatedBy,
+	)
+	if err != nil {
+		return false, fmt.Errorf("error al guardar la ocurrencia: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// Marca la ocurrencia como en proceso si nadie la proceso ni la omitio antes.
+// Es lo que evita crear dos veces el mismo movimiento
+func (s *SQLRepository) ClaimOccurrence(recurringId []uint8, date time.Time) (bool, *models.CreateMovementPayload, error) {
+
+	var override []byte
+	err := s.db.QueryRow(
+		`INSERT INTO public.recurring_occurrence (recurring_movement_id, occurrence_date, status)
+		VALUES ($1, $2, 'processing')
+		ON CONFLICT (recurring_movement_id, occurrence_date) DO UPDATE
+		SET status = 'processing', updated_at = CURRENT_TIMESTAMP
+		WHERE recurring_occurrence.status = 'scheduled'
+		RETURNING override`,
+		recurringId, date,
+	).Scan(&override)
+
+	if err == sql.ErrNoRows {
+		return false, nil, nil
+	} else if err != nil {
+		return false, nil, fmt.Errorf("error al reservar la ocurrencia: %w", err)
+	}
+
+	if override == nil {
+		return true, nil, nil
+	}
+
+	payload := new(models.CreateMovementPayload)
+	if err := json.Unmarshal(override, payload); err != nil {
+		return true, nil, errors.ErrRecurringScan(err.Error())
+	}
+
+	return true, payload, nil
+}
+
+func (s *SQLRepository) CompleteOccurrence(occurrence models.RecurringOccurrence) error {
+
+	var errorText any
+	if occurrence.Error != "" {
+		errorText = occurrence.Error
+	}
+
+	_, err := s.db.Exec(
+		`UPDATE public.recurring_occurrence
+		SET status = $1, movement_id = $2, error = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE recurring_movement_id = $4 AND occurrence_date = $5`,
+		occurrence.Status, nullableId(occurrence.MovementId), errorText, occurrence.RecurringMovementId, occurrence.OccurrenceDate,
+	)
+	if err != nil {
+		return fmt.Errorf("error al completar la ocurrencia: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLRepository) queryRecurring(condition string, args ...any) ([]*models.RecurringMovement, error) {
+
+	rows, err := s.db.Query("SELECT "+recurringColumns+" FROM public.recurring_movement WHERE "+condition+" ORDER BY created_at", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los movimientos recurrentes: %w", err)
+	}
+	defer rows.Close()
+
+	var recurring []*models.RecurringMovement
+	for rows.Next() {
+		r, err := scanRowIntoRecurring(rows)
+		if err != nil {
+			return nil, err
+		}
+		recurring = append(recurring, r)
+	}
+
+	return recurring, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRowIntoRecurring(row rowScanner) (*models.RecurringMovement, error) {
+
+	recurring := new(models.RecurringMovement)
+	var template []byte
+	err := row.Scan(
+		&recurring.RecurringMovementId,
+		&recurring.GroupId,
+		&recurring.Schedule,
+		&recurring.StartDate,
+		&recurring.EndDate,
+		&recurring.MaxCount,
+		&recurring.Active,
+		&recurring.ResumedAt,
+		&template,
+		&recurring.CreatedAt,
+		&recurring.CreatedBy,
+		&recurring.UpdatedAt,
+		&recurring.UpdatedBy,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrRecurringNotFound
+		}
+		return nil, errors.ErrRecurringScan(err.Error())
+	}
+
+	if err := json.Unmarshal(template, &recurring.Template); err != nil {
+		return nil, errors.ErrRecurringScan(err.Error())
+	}
+	return recurring, nil
+}
+
+// nullableId stores an empty id as NULL instead of an empty string
+func nullableId(id []uint8) any {
+
+	if len(id) == 0 {
+		return nil
+	}
+	return id
+}
+
+func checkAffected(res sql.Result) error {
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrRecurringNotFound
+	}
+	return nil
+}
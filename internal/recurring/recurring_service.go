@@ -0,0 +1,339 @@
+package recurring
+
+import (
+	"log"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+// Number of upcoming dates returned with a recurring movement
+const nextOccurrences = 5
+
+// How far ahead upcoming dates are searched
+const lookaheadYears = 5
+
+type Service struct {
+	repository      models.RecurringRepository
+	movementService models.MovementService
+}
+
+func NewService(repository models.RecurringRepository, movementService models.MovementService) *Service {
+	return &Service{repository: repository, movementService: movementService}
+}
+
+func (s *Service) CreateRecurring(payload models.CreateRecurringPayload, groupId []uint8, userId []uint8) (*models.RecurringMovement, error) {
+
+	recurring, err := buildRecurring(payload.Schedule, payload.StartDate, payload.EndDate, payload.MaxCount, payload.Template)
+	if err != nil {
+		return nil, err
+	}
+	recurring.GroupId = groupId
+	recurring.Active = true
+	recurring.ResumedAt = recurring.StartDate
+	recurring.CreatedBy = userId
+	recurring.UpdatedBy = userId
+
+	recurringId, err := s.repository.CreateRecurring(*recurring)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetRecurringById(groupId, recurringId)
+}
+
+// GetRecurringById returns the recurring movement with its stored occurrences
+// and the next dates on which a movement will be created.
+func (s *Service) GetRecurringById(groupId []uint8, recurringId []uint8) (*models.RecurringMovement, error) {
+
+	recurring, err := s.repository.GetRecurringById(groupId, recurringId)
+	if err != nil {
+		return nil, err
+	}
+
+	recurring.Occurrences, err = s.repository.GetOccurrences(recurring.RecurringMovementId)
+	if err != nil {
+		return nil, err
+	}
+
+	recurring.NextOccurrences = upcoming(recurring, recurring.Occurrences, time.Now().UTC())
+
+	return recurring, nil
+}
+
+func (s *Service) GetGroupRecurring(groupId []uint8) ([]*models.RecurringMovement, error) {
+
+	return s.repository.GetGroupRecurring(groupId)
+}
+
+// UpdateRecurring only changes the future: occurrences already created keep
+// their movements, and reactivating the template or changing its schedule
+// or start date resumes it from today instead of creating the missed dates.
+func (s *Service) UpdateRecurring(payload models.UpdateRecurringPayload, groupId []uint8, recurringId []uint8, userId []uint8) error {
+
+	current, err := s.repository.GetRecurringById(groupId, recurringId)
+	if err != nil {
+		return err
+	}
+
+	recurring, err := buildRecurring(payload.Schedule, payload.StartDate, payload.EndDate, payload.MaxCount, payload.Template)
+	if err != nil {
+		return err
+	}
+	recurring.RecurringMovementId = current.RecurringMovementId
+	recurring.GroupId = groupId
+	recurring.Active = current.Active
+	if payload.Active != nil {
+		recurring.Active = *payload.Active
+	}
+
+	recurring.ResumedAt = current.ResumedAt
+	reactivated := recurring.Active && !current.Active
+	rescheduled := recurring.Schedule != current.Schedule || !recurring.StartDate.Equal(toDate(current.StartDate))
+	if reactivated || rescheduled {
+		recurring.ResumedAt = toDate(time.Now().UTC())
+	}
+	recurring.UpdatedBy = userId
+
+	return s.repository.UpdateRecurring(*recurring)
+}
+
+func (s *Service) DeleteRecurring(groupId []uint8, recurringId []uint8) error {
+
+	return s.repository.DeleteRecurring(groupId, recurringId)
+}
+
+// SkipOccurrence prevents the movement of one date from being created
+func (s *Service) SkipOccurrence(groupId []uint8, recurringId []uint8, date string, userId []uint8) error {
+
+	return s.saveOccurrence(groupId, recurringId, date, userId, models.OccurrenceSkipped, nil)
+}
+
+// EditOccurrence replaces the template for one date. Editing a skipped
+// occurrence schedules it again.
+func (s *Service) EditOccurrence(payload models.CreateMovementPayload, groupId []uint8, recurringId []uint8, date string, userId []uint8) error {
+
+	if err := validateTemplate(&payload); err != nil {
+		return err
+	}
+
+	return s.saveOccurrence(groupId, recurringId, date, userId, models.OccurrenceScheduled, &payload)
+}
+
+// ProcessDue creates the movements of every occurrence of the active recurring
+// movements from their resume date up to today, including the ones missed
+// while the server was down. Each occurrence is claimed in the database
+// before its movement is created, so restarts and concurrent runs never
+// create it twice; a crash between both steps leaves the occurrence as
+// processing instead of retrying it. It returns the number of movements
+// created.
+func (s *Service) ProcessDue(today time.Time) (int, error) {
+
+	recurring, err := s.repository.GetActiveRecurring()
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, r := range recurring {
+		n, err := s.processRecurring(r, today)
+		created += n
+		if err != nil {
+			log.Printf("error processing recurring movement %s: %v", r.RecurringMovementId, err)
+		}
+	}
+
+	return created, nil
+}
+
+// Aux Functions
+
+func (s *Service) processRecurring(recurring *models.RecurringMovement, today time.Time) (int, error) {
+
+	schedule, err := ParseSchedule(recurring.Schedule)
+	if err != nil {
+		return 0, err
+	}
+
+	occurrences, err := s.repository.GetOccurrences(recurring.RecurringMovementId)
+	if err != nil {
+		return 0, err
+	}
+
+	// Edited occurrences are still scheduled, everything else is done
+	done := make(map[time.Time]bool)
+	for _, occurrence := range occurrences {
+		if occurrence.Status != models.OccurrenceScheduled {
+			done[toDate(occurrence.OccurrenceDate)] = true
+		}
+	}
+
+	// MaxCount is still counted from StartDate, only the window starts later
+	from := recurring.StartDate
+	if recurring.ResumedAt.After(from) {
+		from = recurring.ResumedAt
+	}
+
+	created := 0
+	for _, date := range schedule.Occurrences(recurring.StartDate, recurring.EndDate, recurring.MaxCount, from, today) {
+		if done[date] {
+			continue
+		}
+
+		claimed, override, err := s.repository.ClaimOccurrence(recurring.RecurringMovementId, date)
+		if !claimed {
+			if err != nil {
+				return created, err
+			}
+			continue
+		}
+
+		occurrence := models.RecurringOccurrence{
+			RecurringMovementId: recurring.RecurringMovementId,
+			OccurrenceDate:      date,
+			Status:              models.OccurrenceCreated,
+		}
+
+		if err == nil {
+			payload := recurring.Template
+			if override != nil {
+				payload = *override
+			}
+			payload.MovementDate = date.Format(time.DateOnly)
+
+			// Templates without payers are paid by their creator, not by
+			// whoever edited them last
+			var movement *models.Movement
+			movement, err = s.movementService.CreateMovement(payload, recurring.GroupId, recurring.CreatedBy)
+			if err == nil {
+				occurrence.MovementId = movement.MovementId
+				created++
+			}
+		}
+
+		if err != nil {
+			occurrence.Status = models.OccurrenceFailed
+			occurrence.Error = err.Error()
+		}
+
+		if err := s.repository.CompleteOccurrence(occurrence); err != nil {
+			return created, err
+		}
+	}
+
+	return created, nil
+}
+
+func (s *Service) saveOccurrence(groupId []uint8, recurringId []uint8, date string, userId []uint8, status string, override *models.CreateMovementPayload) error {
+
+	recurring, err := s.repository.GetRecurringById(groupId, recurringId)
+	if err != nil {
+		return err
+	}
+
+	occurrenceDate, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return errors.ErrInvalidaPayload("date must have the format YYYY-MM-DD")
+	}
+
+	schedule, err := ParseSchedule(recurring.Schedule)
+	if err != nil {
+		return err
+	}
+	if len(schedule.Occurrences(recurring.StartDate, recurring.EndDate, recurring.MaxCount, occurrenceDate, occurrenceDate)) == 0 {
+		return errors.ErrInvalidOccurrence(date)
+	}
+
+	saved, err := s.repository.SaveOccurrence(models.RecurringOccurrence{
+		RecurringMovementId: recurring.RecurringMovementId,
+		OccurrenceDate:      occurrenceDate,
+		Status:              status,
+		Override:            override,
+		UpdatedBy:           userId,
+	})
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return errors.ErrOccurrenceProcessed
+	}
+
+	return nil
+}
+
+func upcoming(recurring *models.RecurringMovement, occurrences []*models.RecurringOccurrence, today time.Time) []time.Time {
+
+	schedule, err := ParseSchedule(recurring.Schedule)
+	if err != nil || !recurring.Active {
+		return nil
+	}
+
+	done := make(map[time.Time]bool)
+	for _, occurrence := range occurrences {
+		if occurrence.Status != models.OccurrenceScheduled {
+			done[toDate(occurrence.OccurrenceDate)] = true
+		}
+	}
+
+	var dates []time.Time
+	for _, date := range schedule.Occurrences(recurring.StartDate, recurring.EndDate, recurring.MaxCount, today, today.AddDate(lookaheadYears, 0, 0)) {
+		if done[date] {
+			continue
+		}
+		dates = append(dates, date)
+		if len(dates) == nextOccurrences {
+			break
+		}
+	}
+
+	return dates
+}
+
+func buildRecurring(expr string, startDate string, endDate string, maxCount *int64, template models.CreateMovementPayload) (*models.RecurringMovement, error) {
+
+	if _, err := ParseSchedule(expr); err != nil {
+		return nil, err
+	}
+
+	start, err := time.Parse(time.DateOnly, startDate)
+	if err != nil {
+		return nil, errors.ErrInvalidaPayload("startDate must have the format YYYY-MM-DD")
+	}
+
+	recurring := &models.RecurringMovement{
+		Schedule:  expr,
+		StartDate: start,
+		MaxCount:  maxCount,
+	}
+
+	if endDate != "" {
+		end, err := time.Parse(time.DateOnly, endDate)
+		if err != nil {
+			return nil, errors.ErrInvalidaPayload("endDate must have the format YYYY-MM-DD")
+		}
+		if end.Before(start) {
+			return nil, errors.ErrInvalidaPayload("endDate can not be before startDate")
+		}
+		recurring.EndDate = &end
+	}
+
+	if err := validateTemplate(&template); err != nil {
+		return nil, err
+	}
+	recurring.Template = template
+
+	return recurring, nil
+}
+
+// The rest of the template is validated when each movement is created, since
+// members, categories and exchange rates can change in the meantime
+func validateTemplate(template *models.CreateMovementPayload) error {
+
+	if !template.Amount.IsPositive() || !template.Amount.Equal(template.Amount.Round(2)) {
+		return errors.ErrInvalidaPayload("template amount must be greater than zero and have at most 2 decimals")
+	}
+	template.MovementDate = ""
+
+	return nil
+}
@@ -0,0 +1,383 @@
+package recurring
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+)
+
+// Movements only have a date, so schedules are evaluated day by day. The
+// minute and hour fields of cron expressions are validated but not used.
+type Schedule struct {
+	matches func(day time.Time, start time.Time) bool
+	count   *int64
+	until   *time.Time
+}
+
+var cronMacros = map[string]string{
+	"@daily":    "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var cronDayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+var rruleDayNames = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseSchedule accepts a 5 field cron expression (or one of the @daily,
+// @weekly, @monthly and @yearly macros) or an RRULE with FREQ, INTERVAL,
+// BYDAY, BYMONTHDAY, BYMONTH, COUNT and UNTIL.
+func ParseSchedule(expr string) (*Schedule, error) {
+
+	expr = strings.TrimSpace(expr)
+	if strings.Contains(strings.ToUpper(expr), "FREQ=") {
+		return parseRRule(expr)
+	}
+	return parseCron(expr)
+}
+
+// Occurrences returns the dates of the schedule between from and to, both
+// included, for a series that starts on start. The series ends on end and
+// after maxCount dates when they are given; the count includes the dates
+// before from.
+func (s *Schedule) Occurrences(start time.Time, end *time.Time, maxCount *int64, from time.Time, to time.Time) []time.Time {
+
+	start = toDate(start)
+	from, to = toDate(from), toDate(to)
+
+	if end != nil && toDate(*end).Before(to) {
+		to = toDate(*end)
+	}
+	if s.until != nil && s.until.Before(to) {
+		to = *s.until
+	}
+
+	limit := int64(-1)
+	if maxCount != nil {
+		limit = *maxCount
+	}
+	if s.count != nil && (limit < 0 || *s.count < limit) {
+		limit = *s.count
+	}
+
+	var dates []time.Time
+	var count int64
+
+	for day := start; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if limit >= 0 && count >= limit {
+			break
+		}
+		if !s.matches(day, start) {
+			continue
+		}
+		count++
+		if !day.Before(from) {
+			dates = append(dates, day)
+		}
+	}
+
+	return dates
+}
+
+// Aux Functions
+
+func parseCron(expr string) (*Schedule, error) {
+
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.ErrInvalidaPayload("schedule must be a cron expression with 5 fields or an RRULE")
+	}
+
+	if _, _, err := parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if _, _, err := parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	days, anyDay, err := parseCronField(fields[2], 1, 31, nil)
+	if err != nil {
+		return nil, err
+	}
+	months, _, err := parseCronField(fields[3], 1, 12, monthNames)
+	if err != nil {
+		return nil, err
+	}
+	weekdays, anyWeekday, err := parseCronField(fields[4], 0, 7, cronDayNames)
+	if err != nil {
+		return nil, err
+	}
+	// 7 is also sunday
+	if weekdays[7] {
+		weekdays[0] = true
+	}
+
+	matches := func(day time.Time, _ time.Time) bool {
+		if !months[int(day.Month())] {
+			return false
+		}
+		dayMatch := days[day.Day()]
+		weekdayMatch := weekdays[int(day.Weekday())]
+		// Like cron, when both fields are restricted either one is enough
+		if !anyDay && !anyWeekday {
+			return dayMatch || weekdayMatch
+		}
+		return dayMatch && weekdayMatch
+	}
+
+	return &Schedule{matches: matches}, nil
+}
+
+// parseCronField returns the allowed values of a field and whether it is *
+func parseCronField(field string, min int, max int, names map[string]int) (map[int]bool, bool, error) {
+
+	values := make(map[int]bool)
+	invalid := errors.ErrInvalidaPayload(fmt.Sprintf("invalid cron field %q", field))
+
+	value := func(text string) (int, error) {
+		if n, ok := names[strings.ToUpper(text)]; ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(text)
+		if err != nil || n < min || n > max {
+			return 0, invalid
+		}
+		return n, nil
+	}
+
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return nil, false, invalid
+			}
+			step = n
+		}
+
+		low, high := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			lowText, highText, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = value(lowText); err != nil {
+				return nil, false, err
+			}
+			if high, err = value(highText); err != nil {
+				return nil, false, err
+			}
+			if high < low {
+				return nil, false, invalid
+			}
+		default:
+			n, err := value(rangePart)
+			if err != nil {
+				return nil, false, err
+			}
+			low = n
+			if !hasStep {
+				high = n
+			}
+		}
+
+		for n := low; n <= high; n += step {
+			values[n] = true
+		}
+	}
+
+	return values, field == "*", nil
+}
+
+func parseRRule(expr string) (*Schedule, error) {
+
+	expr = strings.TrimPrefix(strings.ToUpper(expr), "RRULE:")
+
+	var freq string
+	interval := 1
+	var byDay []time.Weekday
+	var byMonthDay []int
+	byMonth := make(map[int]bool)
+	schedule := &Schedule{}
+
+	for _, part := range strings.Split(expr, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, errors.ErrInvalidaPayload(fmt.Sprintf("invalid RRULE part %q", part))
+		}
+
+		switch key {
+		case "FREQ":
+			freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, errors.ErrInvalidaPayload("RRULE INTERVAL must be a positive number")
+			}
+			interval = n
+		case "BYDAY":
+			for _, name := range strings.Split(value, ",") {
+				weekday, ok := rruleDayNames[name]
+				if !ok {
+					return nil, errors.ErrInvalidaPayload(fmt.Sprintf("RRULE BYDAY %q is not supported", name))
+				}
+				byDay = append(byDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, text := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(text)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, errors.ErrInvalidaPayload(fmt.Sprintf("invalid RRULE BYMONTHDAY %q", text))
+				}
+				byMonthDay = append(byMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, text := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(text)
+				if err != nil || n < 1 || n > 12 {
+					return nil, errors.ErrInvalidaPayload(fmt.Sprintf("invalid RRULE BYMONTH %q", text))
+				}
+				byMonth[n] = true
+			}
+		case "COUNT":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n < 1 {
+				return nil, errors.ErrInvalidaPayload("RRULE COUNT must be a positive number")
+			}
+			schedule.count = &n
+		case "UNTIL":
+			until, err := time.Parse("20060102", value[:min(len(value), 8)])
+			if err != nil {
+				return nil, errors.ErrInvalidaPayload("RRULE UNTIL must have the format YYYYMMDD")
+			}
+			schedule.until = &until
+		case "WKST":
+			if value != "MO" {
+				return nil, errors.ErrInvalidaPayload("RRULE WKST only supports MO")
+			}
+		default:
+			return nil, errors.ErrInvalidaPayload(fmt.Sprintf("RRULE %s is not supported", key))
+		}
+	}
+
+	inMonth := func(day time.Time, start time.Time) bool {
+		if len(byMonth) > 0 {
+			return byMonth[int(day.Month())]
+		}
+		return true
+	}
+	onDay := func(day time.Time, start time.Time, defaultDay bool) bool {
+		if len(byMonthDay) > 0 {
+			return matchesMonthDay(day, byMonthDay)
+		}
+		if len(byDay) > 0 {
+			return matchesWeekday(day, byDay)
+		}
+		if defaultDay {
+			return day.Day() == start.Day()
+		}
+		return true
+	}
+
+	switch freq {
+	case "DAILY":
+		schedule.matches = func(day time.Time, start time.Time) bool {
+			return daysBetween(start, day)%interval == 0 && inMonth(day, start) && onDay(day, start, false)
+		}
+	case "WEEKLY":
+		schedule.matches = func(day time.Time, start time.Time) bool {
+			if (daysBetween(weekStart(start), weekStart(day))/7)%interval != 0 || !inMonth(day, start) {
+				return false
+			}
+			if len(byDay) > 0 {
+				return matchesWeekday(day, byDay)
+			}
+			return day.Weekday() == start.Weekday()
+		}
+	case "MONTHLY":
+		schedule.matches = func(day time.Time, start time.Time) bool {
+			return monthsBetween(start, day)%interval == 0 && inMonth(day, start) && onDay(day, start, true)
+		}
+	case "YEARLY":
+		schedule.matches = func(day time.Time, start time.Time) bool {
+			if (day.Year()-start.Year())%interval != 0 {
+				return false
+			}
+			if len(byMonth) == 0 && day.Month() != start.Month() {
+				return false
+			}
+			return inMonth(day, start) && onDay(day, start, true)
+		}
+	default:
+		return nil, errors.ErrInvalidaPayload("RRULE FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+	}
+
+	return schedule, nil
+}
+
+func matchesWeekday(day time.Time, weekdays []time.Weekday) bool {
+
+	for _, weekday := range weekdays {
+		if day.Weekday() == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// Negative days count from the end of the month, -1 is the last day
+func matchesMonthDay(day time.Time, monthDays []int) bool {
+
+	last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, n := range monthDays {
+		if n > 0 && day.Day() == n {
+			return true
+		}
+		if n < 0 && day.Day() == last+n+1 {
+			return true
+		}
+	}
+	return false
+}
+
+func toDate(t time.Time) time.Time {
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(from time.Time, to time.Time) int {
+
+	return int(toDate(to).Sub(toDate(from)).Hours() / 24)
+}
+
+func monthsBetween(from time.Time, to time.Time) int {
+
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
+
+// weekStart returns the monday of the week of day
+func weekStart(day time.Time) time.Time {
+
+	offset := (int(day.Weekday()) + 6) % 7
+	return toDate(day).AddDate(0, 0, -offset)
+}
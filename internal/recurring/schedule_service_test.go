@@ -0,0 +1,240 @@
+package recurring
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseScheduleErrors(t *testing.T) {
+
+	tests := []string{
+		"",
+		"0 0 * *",
+		"0 0 * * * *",
+		"60 0 * * *",
+		"0 24 * * *",
+		"0 0 0 * *",
+		"0 0 32 * *",
+		"0 0 * 13 *",
+		"0 0 * * 8",
+		"0 0 * FOO *",
+		"0 0 5-1 * *",
+		"0 0 */0 * *",
+		"@hourly",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;INTERVAL",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=-32",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;UNTIL=2024",
+		"FREQ=WEEKLY;WKST=SU",
+		"FREQ=MONTHLY;BYSETPOS=-1",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := ParseSchedule(expr); err == nil {
+				t.Errorf("ParseSchedule(%q) returned no error", expr)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		expr     string
+		start    string
+		end      string
+		maxCount int64
+		from     string
+		to       string
+		want     string
+	}{
+		{
+			name:  "monthly macro skips the days before the start",
+			expr:  "@monthly",
+			start: "2024-01-15", from: "2024-01-01", to: "2024-04-30",
+			want: "2024-02-01 2024-03-01 2024-04-01",
+		},
+		{
+			name:  "weekly macro runs on sundays",
+			expr:  "@weekly",
+			start: "2024-10-01", from: "2024-10-01", to: "2024-10-20",
+			want: "2024-10-06 2024-10-13 2024-10-20",
+		},
+		{
+			name:  "restricted day and weekday match either",
+			expr:  "0 9 13 * FRI",
+			start: "2024-10-01", from: "2024-10-01", to: "2024-10-31",
+			want: "2024-10-04 2024-10-11 2024-10-13 2024-10-18 2024-10-25",
+		},
+		{
+			name:  "any day and a weekday range match both",
+			expr:  "30 8 * * 1-5",
+			start: "2024-10-01", from: "2024-10-01", to: "2024-10-07",
+			want: "2024-10-01 2024-10-02 2024-10-03 2024-10-04 2024-10-07",
+		},
+		{
+			name:  "any weekday and a restricted day match both",
+			expr:  "0 0 15 * *",
+			start: "2024-01-01", from: "2024-01-01", to: "2024-03-31",
+			want: "2024-01-15 2024-02-15 2024-03-15",
+		},
+		{
+			name:  "weekday 7 is sunday",
+			expr:  "0 0 * * 7",
+			start: "2024-10-01", from: "2024-10-01", to: "2024-10-13",
+			want: "2024-10-06 2024-10-13",
+		},
+		{
+			name:  "month names",
+			expr:  "0 0 1 jan,JUL *",
+			start: "2024-01-01", from: "2024-01-01", to: "2025-01-01",
+			want: "2024-01-01 2024-07-01 2025-01-01",
+		},
+		{
+			name:  "steps",
+			expr:  "0 0 */10 * *",
+			start: "2024-10-01", from: "2024-10-01", to: "2024-10-31",
+			want: "2024-10-01 2024-10-11 2024-10-21 2024-10-31",
+		},
+		{
+			name:  "last day of the month",
+			expr:  "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: "2024-01-01", from: "2024-01-01", to: "2024-04-30",
+			want: "2024-01-31 2024-02-29 2024-03-31 2024-04-30",
+		},
+		{
+			name:  "first and last day of the month",
+			expr:  "FREQ=MONTHLY;BYMONTHDAY=1,-1",
+			start: "2024-02-01", from: "2024-02-01", to: "2024-03-31",
+			want: "2024-02-01 2024-02-29 2024-03-01 2024-03-31",
+		},
+		{
+			name:  "monthly skips months without the start day",
+			expr:  "freq=monthly",
+			start: "2024-01-31", from: "2024-01-01", to: "2024-05-31",
+			want: "2024-01-31 2024-03-31 2024-05-31",
+		},
+		{
+			name:  "weekly interval counts iso weeks from the start",
+			expr:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;WKST=MO",
+			start: "2024-10-02", from: "2024-10-01", to: "2024-10-31",
+			want: "2024-10-04 2024-10-14 2024-10-18 2024-10-28",
+		},
+		{
+			name:  "weekly without days repeats the start weekday",
+			expr:  "FREQ=WEEKLY;INTERVAL=3",
+			start: "2024-01-03", from: "2024-01-01", to: "2024-02-29",
+			want: "2024-01-03 2024-01-24 2024-02-14",
+		},
+		{
+			name:  "daily interval in some months",
+			expr:  "FREQ=DAILY;INTERVAL=3;BYMONTH=2",
+			start: "2024-01-30", from: "2024-01-01", to: "2024-02-10",
+			want: "2024-02-02 2024-02-05 2024-02-08",
+		},
+		{
+			name:  "yearly on a leap day",
+			expr:  "FREQ=YEARLY",
+			start: "2024-02-29", from: "2024-01-01", to: "2032-12-31",
+			want: "2024-02-29 2028-02-29 2032-02-29",
+		},
+		{
+			name:  "yearly interval with months and days",
+			expr:  "FREQ=YEARLY;INTERVAL=2;BYMONTH=6,12;BYMONTHDAY=15",
+			start: "2024-01-01", from: "2024-01-01", to: "2026-12-31",
+			want: "2024-06-15 2024-12-15 2026-06-15 2026-12-15",
+		},
+		{
+			name:  "count smaller than the max count",
+			expr:  "FREQ=DAILY;COUNT=2",
+			start: "2024-01-01", maxCount: 10, from: "2024-01-01", to: "2024-01-31",
+			want: "2024-01-01 2024-01-02",
+		},
+		{
+			name:  "max count smaller than the count",
+			expr:  "FREQ=DAILY;COUNT=5",
+			start: "2024-01-01", maxCount: 3, from: "2024-01-01", to: "2024-01-31",
+			want: "2024-01-01 2024-01-02 2024-01-03",
+		},
+		{
+			name:  "count includes the dates before from",
+			expr:  "FREQ=DAILY;COUNT=5",
+			start: "2024-01-01", from: "2024-01-04", to: "2024-01-31",
+			want: "2024-01-04 2024-01-05",
+		},
+		{
+			name:  "max count of a cron schedule",
+			expr:  "@daily",
+			start: "2024-01-01", maxCount: 2, from: "2024-01-02", to: "2024-01-31",
+			want: "2024-01-02",
+		},
+		{
+			name:  "until is included",
+			expr:  "FREQ=DAILY;UNTIL=20240103T235959Z",
+			start: "2024-01-01", from: "2024-01-01", to: "2024-01-31",
+			want: "2024-01-01 2024-01-02 2024-01-03",
+		},
+		{
+			name:  "end before until",
+			expr:  "FREQ=DAILY;UNTIL=20240110",
+			start: "2024-01-01", end: "2024-01-02", from: "2024-01-01", to: "2024-01-31",
+			want: "2024-01-01 2024-01-02",
+		},
+		{
+			name:  "nothing after the end",
+			expr:  "@daily",
+			start: "2024-01-01", end: "2024-01-05", from: "2024-01-06", to: "2024-01-31",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q) error = %v", tt.expr, err)
+			}
+
+			var end *time.Time
+			if tt.end != "" {
+				date := parseDate(t, tt.end)
+				end = &date
+			}
+			var maxCount *int64
+			if tt.maxCount > 0 {
+				maxCount = &tt.maxCount
+			}
+
+			dates := schedule.Occurrences(parseDate(t, tt.start), end, maxCount, parseDate(t, tt.from), parseDate(t, tt.to))
+
+			got := make([]string, len(dates))
+			for i, date := range dates {
+				got[i] = date.Format(time.DateOnly)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("Occurrences() = %q, want %q", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
+
+// Aux Functions
+
+func parseDate(t *testing.T, value string) time.Time {
+
+	t.Helper()
+
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return date
+}
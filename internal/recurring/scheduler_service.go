@@ -0,0 +1,28 @@
+package recurring
+
+import (
+	"log"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+// RunScheduler periodically creates the movements of the recurring movements
+// that are due. ProcessDue is idempotent, so the interval only controls how
+// soon a movement appears after its date starts.
+func RunScheduler(service models.RecurringService, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		created, err := service.ProcessDue(time.Now().UTC())
+		if err != nil {
+			log.Printf("Recurring scheduler error: %v", err)
+			continue
+		}
+		if created > 0 {
+			log.Printf("Recurring scheduler created %d movements", created)
+		}
+	}
+}
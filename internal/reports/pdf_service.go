@@ -0,0 +1,282 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	unicodeFamily = "statement"
+	coreFamily    = "Helvetica"
+	lineHeight    = 6.0
+	photoSize     = 25.0
+	pageMargin    = 10.0
+)
+
+// table has the widths in mm and the alignment of each column, every table
+// fills the 190mm between the margins of A4
+type table struct {
+	widths []float64
+	aligns []string
+}
+
+var (
+	movementTable = table{widths: []float64{22, 68, 35, 37, 28}, aligns: []string{"L", "L", "L", "L", "R"}}
+	balanceTable  = table{widths: []float64{50, 28, 28, 28, 28, 28}, aligns: []string{"L", "R", "R", "R", "R", "R"}}
+)
+
+type pdfWriter struct {
+	pdf    *gofpdf.Fpdf
+	locale locale
+	family string
+	// tr turns UTF-8 into the encoding of the font
+	tr func(string) string
+}
+
+// writePDF renders the statement with the TrueType font of fontFile, which
+// must cover every language, or with the core fonts when it is empty. The
+// core fonts only have cp1252 glyphs, so languages like zh are written in
+// the default language and unknown characters of user texts are lost.
+func writePDF(statement *models.Statement, language string, fontFile string, w io.Writer) error {
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.SetCreationDate(statement.GeneratedAt)
+	pdf.SetTitle(statement.Group.GroupName, true)
+	pdf.AliasNbPages("{nb}")
+
+	p := &pdfWriter{pdf: pdf}
+	if fontFile != "" {
+		pdf.AddUTF8Font(unicodeFamily, "", fontFile)
+		pdf.AddUTF8Font(unicodeFamily, "B", fontFile)
+		p.family = unicodeFamily
+		p.tr = func(text string) string { return text }
+	} else {
+		if unicodeOnly[language] {
+			language = models.DefaultLanguage
+		}
+		p.family = coreFamily
+		p.tr = pdf.UnicodeTranslatorFromDescriptor("")
+	}
+	p.locale = newLocale(language)
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		p.font("", 8)
+		pdf.CellFormat(0, 5, p.tr(fmt.Sprintf(p.locale.text("page"), pdf.PageNo())), "", 0, "C", false, 0, "")
+	})
+
+	pdf.AddPage()
+	p.header(statement)
+	p.movements(statement.Movements)
+	p.balances(statement.Balances, statement)
+	p.settleUp(statement.SettleUp)
+
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("error rendering statement: %w", err)
+	}
+	return nil
+}
+
+func (p *pdfWriter) font(style string, size float64) {
+
+	p.pdf.SetFont(p.family, style, size)
+}
+
+// header shows the photo of the group, when it can be read, next to the name
+// and the period
+func (p *pdfWriter) header(statement *models.Statement) {
+
+	textX := pageMargin
+	if len(statement.Photo) > 0 {
+		info := p.pdf.RegisterImageOptionsReader("photo", gofpdf.ImageOptions{ImageType: statement.PhotoType}, bytes.NewReader(statement.Photo))
+		if p.pdf.Ok() && info.Width() > 0 && info.Height() > 0 {
+			width, height := photoSize, photoSize
+			if info.Width() > info.Height() {
+				height = photoSize * info.Height() / info.Width()
+			} else {
+				width = photoSize * info.Width() / info.Height()
+			}
+			p.pdf.ImageOptions("photo", pageMargin, pageMargin, width, height, false, gofpdf.ImageOptions{ImageType: statement.PhotoType}, 0, "")
+			textX += photoSize + 5
+		} else {
+			log.Printf("statement of group %s without photo: %v", statement.Group.GroupId, p.pdf.Error())
+			p.pdf.ClearError()
+		}
+	}
+
+	layout := p.locale.dateLayout()
+
+	p.pdf.SetXY(textX, pageMargin)
+	p.font("B", 16)
+	p.pdf.CellFormat(0, 8, p.fit(statement.Group.GroupName, 190-(textX-pageMargin)), "", 1, "L", false, 0, "")
+	p.pdf.SetX(textX)
+	p.font("", 12)
+	p.pdf.CellFormat(0, lineHeight, p.tr(p.locale.text("title")), "", 1, "L", false, 0, "")
+	p.pdf.SetX(textX)
+	p.font("", 10)
+	p.pdf.CellFormat(0, lineHeight, p.tr(fmt.Sprintf(p.locale.text("period"), statement.From.Format(layout), statement.To.Format(layout))), "", 1, "L", false, 0, "")
+	p.pdf.SetX(textX)
+	p.pdf.CellFormat(0, lineHeight, p.tr(fmt.Sprintf(p.locale.text("generated"), statement.GeneratedAt.Format(layout))), "", 1, "L", false, 0, "")
+
+	if p.pdf.GetY() < pageMargin+photoSize {
+		p.pdf.SetY(pageMargin + photoSize)
+	}
+	p.pdf.Ln(4)
+}
+
+// movements lists the movements of the period with a total per currency
+func (p *pdfWriter) movements(movements []*models.StatementMovement) {
+
+	p.section(p.locale.text("movements"))
+
+	if len(movements) == 0 {
+		p.font("", 10)
+		p.pdf.CellFormat(0, lineHeight, p.tr(p.locale.text("noMovs")), "", 1, "L", false, 0, "")
+		p.pdf.Ln(4)
+		return
+	}
+
+	head := []string{p.locale.text("date"), p.locale.text("desc"), p.locale.text("category"), p.locale.text("paidBy"), p.locale.text("amount")}
+	p.tableHead(movementTable, head)
+
+	totals := map[string]decimal.Decimal{}
+	for _, movement := range movements {
+		p.newPageIfFull(movementTable, head)
+		p.font("", 9)
+		p.pdf.CellFormat(movementTable.widths[0], lineHeight, movement.Date.Format(p.locale.dateLayout()), "B", 0, "L", false, 0, "")
+		p.pdf.CellFormat(movementTable.widths[1], lineHeight, p.fit(movement.Description, movementTable.widths[1]), "B", 0, "L", false, 0, "")
+		p.pdf.CellFormat(movementTable.widths[2], lineHeight, p.fit(movement.Category, movementTable.widths[2]), "B", 0, "L", false, 0, "")
+		p.pdf.CellFormat(movementTable.widths[3], lineHeight, p.fit(movement.PaidBy, movementTable.widths[3]), "B", 0, "L", false, 0, "")
+		p.pdf.CellFormat(movementTable.widths[4], lineHeight, p.money(movement.Amount, movement.Currency), "B", 1, "R", false, 0, "")
+		totals[movement.Currency] = totals[movement.Currency].Add(movement.Amount)
+	}
+
+	currencies := make([]string, 0, len(totals))
+	for currency := range totals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	labelWidth := 190 - movementTable.widths[4]
+	for _, currency := range currencies {
+		p.newPageIfFull(table{}, nil)
+		p.font("B", 9)
+		p.pdf.CellFormat(labelWidth, lineHeight, p.tr(p.locale.text("total")), "", 0, "R", false, 0, "")
+		p.pdf.CellFormat(movementTable.widths[4], lineHeight, p.money(totals[currency], currency), "", 1, "R", false, 0, "")
+	}
+	p.pdf.Ln(4)
+}
+
+func (p *pdfWriter) balances(balances *models.GroupBalances, statement *models.Statement) {
+
+	p.section(fmt.Sprintf(p.locale.text("balances"), statement.GeneratedAt.Format(p.locale.dateLayout()), balances.Currency))
+
+	head := []string{p.locale.text("member"), p.locale.text("paid"), p.locale.text("owed"), p.locale.text("sent"), p.locale.text("received"), p.locale.text("net")}
+	p.tableHead(balanceTable, head)
+
+	for _, member := range balances.Members {
+		p.newPageIfFull(balanceTable, head)
+		p.font("", 9)
+		p.pdf.CellFormat(balanceTable.widths[0], lineHeight, p.fit(member.UserName, balanceTable.widths[0]), "B", 0, "L", false, 0, "")
+		for i, value := range []decimal.Decimal{member.Paid, member.Owed, member.Sent, member.Received} {
+			p.pdf.CellFormat(balanceTable.widths[i+1], lineHeight, p.locale.amount(value), "B", 0, "R", false, 0, "")
+		}
+		p.font("B", 9)
+		p.pdf.CellFormat(balanceTable.widths[5], lineHeight, p.locale.amount(member.Net), "B", 1, "R", false, 0, "")
+	}
+	p.pdf.Ln(4)
+}
+
+// settleUp lists the transfers that leave every balance at zero
+func (p *pdfWriter) settleUp(settleUp *models.SettleUp) {
+
+	p.section(p.locale.text("settleUp"))
+	p.font("", 10)
+
+	if len(settleUp.Transfers) == 0 {
+		p.pdf.CellFormat(0, lineHeight, p.tr(p.locale.text("settled")), "", 1, "L", false, 0, "")
+		return
+	}
+
+	for _, transfer := range settleUp.Transfers {
+		p.newPageIfFull(table{}, nil)
+		p.pdf.CellFormat(150, lineHeight, p.fit(fmt.Sprintf(p.locale.text("transfer"), transfer.FromName, transfer.ToName), 150), "", 0, "L", false, 0, "")
+		p.pdf.CellFormat(40, lineHeight, p.money(transfer.Amount, settleUp.Currency), "", 1, "R", false, 0, "")
+	}
+
+	if settleUp.Simplified {
+		p.pdf.Ln(2)
+		p.font("", 8)
+		p.pdf.CellFormat(0, lineHeight, p.tr(p.locale.text("simplified")), "", 1, "L", false, 0, "")
+	}
+}
+
+// Aux Functions
+
+func (p *pdfWriter) section(title string) {
+
+	p.newPageIfFull(table{}, nil)
+	p.font("B", 13)
+	p.pdf.CellFormat(0, 8, p.tr(title), "", 1, "L", false, 0, "")
+}
+
+func (p *pdfWriter) tableHead(columns table, head []string) {
+
+	p.font("B", 9)
+	p.pdf.SetFillColor(230, 230, 230)
+	for i, title := range head {
+		ln := 0
+		if i == len(head)-1 {
+			ln = 1
+		}
+		p.pdf.CellFormat(columns.widths[i], lineHeight, p.fit(title, columns.widths[i]), "B", ln, columns.aligns[i], true, 0, "")
+	}
+}
+
+// newPageIfFull starts a new page, repeating the head of the table, before a
+// row that would not fit in the current one
+func (p *pdfWriter) newPageIfFull(columns table, head []string) {
+
+	_, pageHeight := p.pdf.GetPageSize()
+	_, _, _, bottom := p.pdf.GetMargins()
+	if p.pdf.GetY()+lineHeight <= pageHeight-bottom {
+		return
+	}
+
+	p.pdf.AddPage()
+	if head != nil {
+		p.tableHead(columns, head)
+	}
+}
+
+func (p *pdfWriter) money(value decimal.Decimal, currency string) string {
+
+	return p.tr(p.locale.amount(value) + " " + currency)
+}
+
+// fit cuts text to the width of a cell, leaving room for its padding
+func (p *pdfWriter) fit(text string, width float64) string {
+
+	width -= 2
+	if fitted := p.tr(text); p.pdf.GetStringWidth(fitted) <= width {
+		return fitted
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if fitted := p.tr(string(runes) + "..."); p.pdf.GetStringWidth(fitted) <= width {
+			return fitted
+		}
+	}
+	return ""
+}
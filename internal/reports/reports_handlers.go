@@ -0,0 +1,213 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/auth"
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/middlewares"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+// Longest periods a report and a statement cover
+const (
+	maxReportMonths    = 120
+	maxStatementMonths = 12
+)
+
+type Handler struct {
+	service        models.ReportService
+	authRepository models.AuthRepository
+}
+
+func NewHandler(service models.ReportService, authRepository models.AuthRepository) *Handler {
+	return &Handler{service: service, authRepository: authRepository}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+
+	// User routes, the share of the user in all its groups
+	router.HandleFunc("/report", middlewares.WithJWTAuth(h.handleGetUserReport)).Methods("GET")
+
+	// Group routes
+	router.HandleFunc("/group/{groupId}/report", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetGroupReport, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}/statement.pdf", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetStatement, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+}
+
+func (h *Handler) handleGetGroupReport(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	from, to, format, err := parseReportQuery(r.URL.Query())
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	report, err := h.service.GetGroupReport(groupId, from, to, userId)
+	if err == errors.ErrGroupNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeReport(w, report, format, fmt.Sprintf("group-%s-report", groupId))
+}
+
+// handleGetUserReport converts to ?currency=, USD when not given
+func (h *Handler) handleGetUserReport(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	from, to, format, err := parseReportQuery(r.URL.Query())
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	currency := strings.ToUpper(r.URL.Query().Get("currency"))
+	if currency == "" {
+		currency = models.DefaultBaseCurrency
+	}
+	if err := utils.Validate.Var(currency, "iso4217"); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload("currency must be an ISO-4217 code"))
+		return
+	}
+
+	report, err := h.service.GetUserReport(userId, from, to, currency)
+	if err == errors.ErrUserNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeReport(w, report, format, "report")
+}
+
+// handleGetStatement renders the whole PDF before answering, so errors are
+// still sent as JSON. The period defaults to the previous month, the one a
+// monthly statement is sent for.
+func (h *Handler) handleGetStatement(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	now := time.Now().UTC()
+	from, to, err := parsePeriod(r.URL.Query(), time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC), maxStatementMonths)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var statement bytes.Buffer
+	err = h.service.WriteGroupStatement(groupId, from, to, userId, &statement)
+	if err == errors.ErrGroupNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", fmt.Sprintf("statement-%s-%s.pdf", from.Format(time.DateOnly), to.Format(time.DateOnly))))
+	w.WriteHeader(http.StatusOK)
+	w.Write(statement.Bytes())
+}
+
+// Aux Functions
+
+func (h *Handler) writeReport(w http.ResponseWriter, report *models.Report, format string, filename string) {
+
+	if format == models.ReportFormatJSON {
+		utils.WriteJSON(w, http.StatusOK, report)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".csv"))
+	w.WriteHeader(http.StatusOK)
+	if err := h.service.WriteCSV(report, w); err != nil {
+		log.Printf("writing report %s: %v", filename, err)
+	}
+}
+
+// parseReportQuery reads from, to and format. The period defaults to the
+// current month.
+func parseReportQuery(query url.Values) (time.Time, time.Time, string, error) {
+
+	format := query.Get("format")
+	if format == "" {
+		format = models.ReportFormatJSON
+	}
+	if format != models.ReportFormatJSON && format != models.ReportFormatCSV {
+		return time.Time{}, time.Time{}, "", errors.ErrInvalidReportFormat(format)
+	}
+
+	now := time.Now().UTC()
+	from, to, err := parsePeriod(query, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), maxReportMonths)
+	if err != nil {
+		return time.Time{}, time.Time{}, "", err
+	}
+
+	return from, to, format, nil
+}
+
+// parsePeriod reads from and to. A missing to is the end of the month of
+// from.
+func parsePeriod(query url.Values, defaultFrom time.Time, maxMonths int) (time.Time, time.Time, error) {
+
+	from := defaultFrom
+	if value := query.Get("from"); value != "" {
+		date, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.ErrInvalidaPayload("from must be a YYYY-MM-DD date")
+		}
+		from = date
+	}
+
+	to := time.Date(from.Year(), from.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+	if value := query.Get("to"); value != "" {
+		date, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.ErrInvalidaPayload("to must be a YYYY-MM-DD date")
+		}
+		to = date
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.ErrInvalidaPayload("from must not be after to")
+	}
+	if from.AddDate(0, maxMonths, 0).Before(to) {
+		return time.Time{}, time.Time{}, errors.ErrInvalidaPayload(fmt.Sprintf("the period must not be longer than %d months", maxMonths))
+	}
+
+	return from, to, nil
+}
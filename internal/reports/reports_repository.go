@@ -0,0 +1,288 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+// Postgres SQL Repository
+type SQLRepository struct {
+	db *sql.DB
+}
+
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+// Every query of a report starts with base, the expenses of the period with
+// $2 and $3 the first and last day, and adds the rate to $4 of every
+// currency and date. $5 is the language of the category names, typed in its
+// own CTE because Postgres rejects parameters a query never uses.
+const spendingCTE = `
+	WITH lang AS (SELECT $5::varchar AS code),
+	base AS (%s),
+	rates AS (
+		SELECT d.currency, d.movement_date, %s AS rate
+		FROM (SELECT DISTINCT currency, movement_date FROM base WHERE currency <> $4) d
+	),
+	spending AS (
+		SELECT b.*, COALESCE(r.rate, 1) AS rate, CASE WHEN b.currency = $4 THEN b.amount ELSE ROUND(b.amount * r.rate, 2) END AS value
+		FROM base b
+		LEFT JOIN rates r ON r.currency = b.currency AND r.movement_date = b.movement_date
+	)`
+
+// Name of the category in $5, then in the default language, then the first
+// one, like categories.Localize
+const categoryNameJoin = `
+	LEFT JOIN LATERAL (
+		SELECT cn.name FROM public.category_name cn
+		WHERE cn.category_id = s.category_id
+		ORDER BY (cn.language_code = (SELECT code FROM lang)) DESC, (cn.language_code = '` + models.DefaultLanguage + `') DESC, cn.language_code
+		LIMIT 1
+	) cn ON TRUE`
+
+// Suma los gastos del grupo en el periodo
+func (s *SQLRepository) GetGroupReport(groupId []uint8, from time.Time, to time.Time, currency string, language string) (*models.Report, error) {
+
+	cte := fmt.Sprintf(spendingCTE, `
+		SELECT m.movement_id, m.group_id, m.movement_date, m.description, m.category_id, m.amount, m.currency
+		FROM public.movement m
+		WHERE m.group_id = $1 AND m.movement_date BETWEEN $2 AND $3`, rateSQL("d.currency", "$4", "d.movement_date"))
+
+	report := &models.Report{GroupId: groupId}
+	if err := s.fillReport(report, cte, []any{groupId, from, to, currency, language}); err != nil {
+		return nil, err
+	}
+
+	// What each member paid and owes of the expenses
+	rows, err := s.db.Query(cte+`
+		SELECT p.user_id, u.user_name, SUM(ROUND(p.owed_amount * s.rate, 2)), SUM(ROUND(p.paid_amount * s.rate, 2)), COUNT(*) FILTER (WHERE p.owed_amount > 0)
+		FROM spending s
+		INNER JOIN public.movement_participant p ON p.movement_id = s.movement_id
+		INNER JOIN auth."user" u ON u.user_id = p.user_id
+		GROUP BY p.user_id, u.user_name
+		ORDER BY 3 DESC, u.user_name`, groupId, from, to, currency, language)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el reporte por miembro: %w", err)
+	}
+	defer rows.Close()
+
+	report.ByMember = []*models.ReportTotal{}
+	for rows.Next() {
+		total := new(models.ReportTotal)
+		var paid decimal.Decimal
+		if err := rows.Scan(&total.Id, &total.Name, &total.Total, &paid, &total.Count); err != nil {
+			return nil, errors.ErrReportScan(err.Error())
+		}
+		total.Paid = &paid
+		report.ByMember = append(report.ByMember, total)
+	}
+
+	return report, rows.Err()
+}
+
+// Suma la parte que le toca al usuario de los gastos de todos sus grupos
+func (s *SQLRepository) GetUserReport(userId []uint8, from time.Time, to time.Time, currency string, language string) (*models.Report, error) {
+
+	cte := fmt.Sprintf(spendingCTE, `
+		SELECT m.movement_id, m.group_id, m.movement_date, m.description, m.category_id, p.owed_amount AS amount, m.currency
+		FROM public.movement m
+		INNER JOIN public.movement_participant p ON p.movement_id = m.movement_id
+		WHERE p.user_id = $1 AND p.owed_amount > 0 AND m.movement_date BETWEEN $2 AND $3`, rateSQL("d.currency", "$4", "d.movement_date"))
+
+	report := &models.Report{UserId: userId}
+	if err := s.fillReport(report, cte, []any{userId, from, to, currency, language}); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(cte+`
+		SELECT s.group_id, COALESCE(g.group_name, ''), SUM(s.value), COUNT(*)
+		FROM spending s
+		INNER JOIN public."group" g ON g.group_id = s.group_id
+		GROUP BY s.group_id, g.group_name
+		ORDER BY 3 DESC, g.group_name`, userId, from, to, currency, language)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el reporte por grupo: %w", err)
+	}
+	defer rows.Close()
+
+	report.ByGroup = []*models.ReportTotal{}
+	for rows.Next() {
+		total := new(models.ReportTotal)
+		if err := rows.Scan(&total.Id, &total.Name, &total.Total, &total.Count); err != nil {
+			return nil, errors.ErrReportScan(err.Error())
+		}
+		report.ByGroup = append(report.ByGroup, total)
+	}
+
+	return report, rows.Err()
+}
+
+// Aux Functions
+
+// fillReport runs the parts shared by group and user reports
+func (s *SQLRepository) fillReport(report *models.Report, cte string, args []any) error {
+
+	var missingCurrency string
+	var missingDate time.Time
+	err := s.db.QueryRow(cte+`
+		SELECT currency, movement_date FROM rates WHERE rate IS NULL ORDER BY movement_date LIMIT 1`, args...,
+	).Scan(&missingCurrency, &missingDate)
+	if err == nil {
+		return errors.ErrMissingExchangeRate(missingCurrency, args[3].(string), missingDate.Format(time.DateOnly))
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("error al obtener las cotizaciones del reporte: %w", err)
+	}
+
+	err = s.db.QueryRow(cte+`
+		SELECT COALESCE(SUM(value), 0), COUNT(*) FROM spending`, args...,
+	).Scan(&report.Total, &report.Count)
+	if err != nil {
+		return fmt.Errorf("error al obtener el total del reporte: %w", err)
+	}
+
+	if report.ByCategory, err = s.queryTotals(cte+`
+		SELECT s.category_id, COALESCE(cn.name, ''), SUM(s.value), COUNT(*)
+		FROM spending s`+categoryNameJoin+`
+		GROUP BY s.category_id, cn.name
+		ORDER BY 3 DESC, 2`, args); err != nil {
+		return err
+	}
+
+	rows, err := s.db.Query(cte+`
+		SELECT f.name, v.value, SUM(s.value), COUNT(*)
+		FROM spending s
+		INNER JOIN public.movement_field_value v ON v.movement_id = s.movement_id
+		INNER JOIN public.movement_field f ON f.movement_field_id = v.movement_field_id
+		GROUP BY f.name, v.value
+		ORDER BY f.name, 3 DESC, v.value`, args...)
+	if err != nil {
+		return fmt.Errorf("error al obtener el reporte por campo: %w", err)
+	}
+	defer rows.Close()
+
+	report.ByField = []*models.ReportFieldTotal{}
+	for rows.Next() {
+		total := new(models.ReportFieldTotal)
+		if err := rows.Scan(&total.Field, &total.Value, &total.Total, &total.Count); err != nil {
+			return errors.ErrReportScan(err.Error())
+		}
+		report.ByField = append(report.ByField, total)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Every month of the period, with the change against the previous one
+	months, err := s.db.Query(cte+`
+		SELECT to_char(t.month, 'YYYY-MM'), t.total, t.count,
+			ROUND(100 * (t.total - LAG(t.total) OVER (ORDER BY t.month)) / NULLIF(LAG(t.total) OVER (ORDER BY t.month), 0), 2)
+		FROM (
+			SELECT mo.month, COALESCE(SUM(s.value), 0) AS total, COUNT(s.movement_id) AS count
+			FROM generate_series(date_trunc('month', $2::date), $3::date, interval '1 month') AS mo(month)
+			LEFT JOIN spending s ON date_trunc('month', s.movement_date) = mo.month
+			GROUP BY mo.month
+		) t
+		ORDER BY t.month`, args...)
+	if err != nil {
+		return fmt.Errorf("error al obtener el reporte mensual: %w", err)
+	}
+	defer months.Close()
+
+	report.Months = []*models.ReportMonth{}
+	for months.Next() {
+		month := new(models.ReportMonth)
+		var change decimal.NullDecimal
+		if err := months.Scan(&month.Month, &month.Total, &month.Count, &change); err != nil {
+			return errors.ErrReportScan(err.Error())
+		}
+		if change.Valid {
+			month.Change = &change.Decimal
+		}
+		report.Months = append(report.Months, month)
+	}
+	if err := months.Err(); err != nil {
+		return err
+	}
+
+	largest, err := s.db.Query(cte+`
+		SELECT s.movement_id, s.group_id, s.movement_date, COALESCE(s.description, ''), COALESCE(cn.name, ''), s.amount, s.currency, s.value
+		FROM spending s`+categoryNameJoin+`
+		ORDER BY s.value DESC, s.movement_date DESC
+		LIMIT `+fmt.Sprint(models.ReportLargestCount), args...)
+	if err != nil {
+		return fmt.Errorf("error al obtener los mayores gastos: %w", err)
+	}
+	defer largest.Close()
+
+	report.Largest = []*models.ReportExpense{}
+	for largest.Next() {
+		expense := new(models.ReportExpense)
+		err := largest.Scan(
+			&expense.MovementId,
+			&expense.GroupId,
+			&expense.Date,
+			&expense.Description,
+			&expense.Category,
+			&expense.Amount,
+			&expense.Currency,
+			&expense.Total,
+		)
+		if err != nil {
+			return errors.ErrReportScan(err.Error())
+		}
+		report.Largest = append(report.Largest, expense)
+	}
+
+	return largest.Err()
+}
+
+func (s *SQLRepository) queryTotals(query string, args []any) ([]*models.ReportTotal, error) {
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el reporte: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []*models.ReportTotal{}
+	for rows.Next() {
+		total := new(models.ReportTotal)
+		if err := rows.Scan(&total.Id, &total.Name, &total.Total, &total.Count); err != nil {
+			return nil, errors.ErrReportScan(err.Error())
+		}
+		totals = append(totals, total)
+	}
+
+	return totals, rows.Err()
+}
+
+// rateSQL looks for the rate from one currency to another in the same order
+// as currencies.Service.GetRate: the last rate of the pair up to date, then
+// the inverse pair, then a cross rate through the pivot currency, each leg
+// also trying its inverse. It is NULL when GetRate returns
+// ErrMissingExchangeRate. GetRate skips the cross rate when a currency is the
+// pivot, here the pivot to pivot leg is NULL and so is the product. Unlike
+// GetRate it does not return 1 for the same currency, so callers leave those
+// rows out.
+func rateSQL(from string, to string, date string) string {
+
+	pivot := pq.QuoteLiteral(models.PivotCurrency)
+	return fmt.Sprintf("COALESCE(%s, %s * %s)", pairRateSQL(from, to, date), pairRateSQL(from, pivot, date), pairRateSQL(pivot, to, date))
+}
+
+func pairRateSQL(from string, to string, date string) string {
+
+	return fmt.Sprintf("COALESCE(%s, 1 / %s)", directRateSQL(from, to, date), directRateSQL(to, from, date))
+}
+
+func directRateSQL(from string, to string, date string) string {
+
+	return fmt.Sprintf(`(SELECT r.rate FROM conf.exchange_rate r WHERE r.base_currency = %s AND r.quote_currency = %s AND r.rate_date <= %s ORDER BY r.rate_date DESC LIMIT 1)`, from, to, date)
+}
@@ -0,0 +1,156 @@
+package reports
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/categories"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+type Service struct {
+	repository         models.ReportRepository
+	groupRepository    models.GroupRepository
+	userRepository     models.UserRepository
+	movementRepository models.MovementRepository
+	categoryRepository models.CategoryRepository
+	balanceService     models.BalanceService
+	storage            models.Storage
+}
+
+func NewService(repository models.ReportRepository, groupRepository models.GroupRepository, userRepository models.UserRepository, movementRepository models.MovementRepository, categoryRepository models.CategoryRepository, balanceService models.BalanceService, storage models.Storage) *Service {
+	return &Service{
+		repository:         repository,
+		groupRepository:    groupRepository,
+		userRepository:     userRepository,
+		movementRepository: movementRepository,
+		categoryRepository: categoryRepository,
+		balanceService:     balanceService,
+		storage:            storage,
+	}
+}
+
+// GetGroupReport sums the expenses of the group in its base currency, with
+// the category names in the language of the user asking
+func (s *Service) GetGroupReport(groupId []uint8, from time.Time, to time.Time, userId []uint8) (*models.Report, error) {
+
+	group, err := s.groupRepository.GetGroupById(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	language, err := categories.UserLanguage(s.userRepository, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.repository.GetGroupReport(groupId, from, to, group.BaseCurrency, language)
+	if err != nil {
+		return nil, err
+	}
+
+	complete(report, from, to, group.BaseCurrency)
+	shares(report.ByMember, report.Total)
+
+	return report, nil
+}
+
+// GetUserReport sums what the user owes of the expenses of all its groups
+func (s *Service) GetUserReport(userId []uint8, from time.Time, to time.Time, currency string) (*models.Report, error) {
+
+	language, err := categories.UserLanguage(s.userRepository, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.repository.GetUserReport(userId, from, to, currency, language)
+	if err != nil {
+		return nil, err
+	}
+
+	complete(report, from, to, currency)
+	shares(report.ByGroup, report.Total)
+
+	return report, nil
+}
+
+// WriteCSV flattens the report into section,name,detail,total,paid,count,share
+// rows, one section per chart
+func (s *Service) WriteCSV(report *models.Report, w io.Writer) error {
+
+	writer := csv.NewWriter(w)
+
+	write := func(section string, name string, detail string, total decimal.Decimal, paid string, count int, share string) {
+		writer.Write([]string{section, name, detail, total.StringFixed(2), paid, strconv.Itoa(count), share})
+	}
+
+	writer.Write([]string{"section", "name", "detail", "total", "paid", "count", "share"})
+
+	write("total", report.Currency, report.From.Format(time.DateOnly)+"/"+report.To.Format(time.DateOnly), report.Total, "", report.Count, "")
+	write("average", "expense", "", report.Average, "", report.Count, "")
+	write("average", "month", "", report.MonthlyAverage, "", len(report.Months), "")
+
+	totals := func(section string, items []*models.ReportTotal) {
+		for _, item := range items {
+			paid := ""
+			if item.Paid != nil {
+				paid = item.Paid.StringFixed(2)
+			}
+			write(section, item.Name, "", item.Total, paid, item.Count, item.Share.StringFixed(2))
+		}
+	}
+	totals("category", report.ByCategory)
+	totals("member", report.ByMember)
+	totals("group", report.ByGroup)
+
+	for _, field := range report.ByField {
+		write("field", field.Field, field.Value, field.Total, "", field.Count, "")
+	}
+
+	for _, month := range report.Months {
+		change := ""
+		if month.Change != nil {
+			change = month.Change.StringFixed(2)
+		}
+		write("month", month.Month, change, month.Total, "", month.Count, "")
+	}
+
+	for _, expense := range report.Largest {
+		write("largest", expense.Description, expense.Date.Format(time.DateOnly)+" "+expense.Category, expense.Total, "", 1, "")
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// Aux Functions
+
+// complete fills the averages and the shares of the categories
+func complete(report *models.Report, from time.Time, to time.Time, currency string) {
+
+	report.Currency = currency
+	report.From = from
+	report.To = to
+
+	if report.Count > 0 {
+		report.Average = report.Total.Div(decimal.NewFromInt(int64(report.Count))).Round(2)
+	}
+	if len(report.Months) > 0 {
+		report.MonthlyAverage = report.Total.Div(decimal.NewFromInt(int64(len(report.Months)))).Round(2)
+	}
+
+	shares(report.ByCategory, report.Total)
+}
+
+func shares(items []*models.ReportTotal, total decimal.Decimal) {
+
+	if total.IsZero() {
+		return
+	}
+	for _, item := range items {
+		item.Share = item.Total.Mul(decimal.NewFromInt(100)).Div(total).Round(2)
+	}
+}
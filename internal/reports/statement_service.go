@@ -0,0 +1,174 @@
+package reports
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/conf"
+	"github.com/PabloPei/SmartSpend-backend/internal/categories"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+// Largest group photo downloaded for a statement
+const maxPhotoSize = 5 << 20
+
+// Image types gofpdf can embed, by the media type of their content
+var photoTypes = map[string]string{
+	"image/jpeg": "JPG",
+	"image/png":  "PNG",
+	"image/gif":  "GIF",
+}
+
+var photoClient = &http.Client{Timeout: 5 * time.Second}
+
+// WriteGroupStatement collects the movements of the period and the current
+// balances of the group and renders them as a PDF
+func (s *Service) WriteGroupStatement(groupId []uint8, from time.Time, to time.Time, userId []uint8, w io.Writer) error {
+
+	language, err := categories.UserLanguage(s.userRepository, userId)
+	if err != nil {
+		return err
+	}
+
+	group, err := s.groupRepository.GetGroupById(groupId)
+	if err != nil {
+		return err
+	}
+
+	balances, err := s.balanceService.GetGroupBalances(groupId)
+	if err != nil {
+		return err
+	}
+
+	settleUp, err := s.balanceService.GetSettleUp(groupId)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]string, len(balances.Members))
+	for _, member := range balances.Members {
+		names[string(member.UserId)] = member.UserName
+	}
+
+	movements, err := s.periodMovements(groupId, from, to, language, names)
+	if err != nil {
+		return err
+	}
+
+	statement := &models.Statement{
+		Group:       group,
+		From:        from,
+		To:          to,
+		GeneratedAt: time.Now().UTC(),
+		Movements:   movements,
+		Balances:    balances,
+		SettleUp:    settleUp,
+	}
+	statement.Photo, statement.PhotoType = s.groupPhoto(group)
+
+	return writePDF(statement, language, conf.ServerConfig.StatementFontFile, w)
+}
+
+// periodMovements reads every page of the movements of the period, oldest
+// first, with the category and the payers by name
+func (s *Service) periodMovements(groupId []uint8, from time.Time, to time.Time, language string, names map[string]string) ([]*models.StatementMovement, error) {
+
+	groupCategories, err := s.categoryRepository.GetGroupCategories(groupId)
+	if err != nil {
+		return nil, err
+	}
+	categoryNames := make(map[string]string, len(groupCategories))
+	for _, category := range groupCategories {
+		categoryNames[string(category.CategoryId)], _ = categories.Localize(category.Names, language)
+	}
+
+	var movements []*models.StatementMovement
+	filter := models.MovementFilter{From: &from, To: &to}
+	page := models.PageRequest{Limit: models.MaxPageLimit, Sort: "date"}
+	for {
+		result, err := s.movementRepository.GetGroupMovements(groupId, filter, page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, movement := range result.Items {
+			var payers []string
+			for _, participant := range movement.Participants {
+				if participant.Paid.IsPositive() {
+					payers = append(payers, names[string(participant.UserId)])
+				}
+			}
+			movements = append(movements, &models.StatementMovement{
+				Date:        movement.MovementDate,
+				Description: movement.Description,
+				Category:    categoryNames[string(movement.CategoryId)],
+				PaidBy:      strings.Join(payers, ", "),
+				Amount:      movement.Amount,
+				Currency:    movement.Currency,
+			})
+		}
+
+		if result.NextCursor == "" {
+			return movements, nil
+		}
+		page.Cursor = result.NextCursor
+	}
+}
+
+// groupPhoto reads the uploaded photo of the group from the storage, or
+// downloads it when the photo is only a link. A statement is still sent
+// without it when the photo can not be read or is not an image gofpdf knows.
+func (s *Service) groupPhoto(group *models.Group) ([]byte, string) {
+
+	if group.PhotoKey == "" {
+		return fetchPhoto(group.PhotoUrl)
+	}
+
+	file, err := s.storage.Get(group.PhotoKey)
+	if err != nil {
+		log.Printf("statement photo %s: %v", group.PhotoKey, err)
+		return nil, ""
+	}
+	defer file.Close()
+
+	return readPhoto(group.PhotoKey, file)
+}
+
+func fetchPhoto(url string) ([]byte, string) {
+
+	if url == "" {
+		return nil, ""
+	}
+
+	response, err := photoClient.Get(url)
+	if err != nil {
+		log.Printf("statement photo %s: %v", url, err)
+		return nil, ""
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		log.Printf("statement photo %s: status %d", url, response.StatusCode)
+		return nil, ""
+	}
+
+	return readPhoto(url, response.Body)
+}
+
+func readPhoto(name string, r io.Reader) ([]byte, string) {
+
+	photo, err := io.ReadAll(io.LimitReader(r, maxPhotoSize+1))
+	if err != nil || len(photo) > maxPhotoSize {
+		log.Printf("statement photo %s: unreadable or larger than %d bytes", name, maxPhotoSize)
+		return nil, ""
+	}
+
+	photoType, ok := photoTypes[http.DetectContentType(photo)]
+	if !ok {
+		return nil, ""
+	}
+	return photo, photoType
+}
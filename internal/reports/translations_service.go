@@ -0,0 +1,154 @@
+package reports
+
+import (
+	"strings"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Texts of the statement in every language of conf.language
+var translations = map[string]map[string]string{
+	"en": {
+		"title":      "Statement",
+		"period":     "Period: %s - %s",
+		"generated":  "Generated on %s",
+		"movements":  "Movements",
+		"date":       "Date",
+		"desc":       "Description",
+		"category":   "Category",
+		"paidBy":     "Paid by",
+		"amount":     "Amount",
+		"total":      "Total",
+		"noMovs":     "There are no movements in this period.",
+		"balances":   "Balances on %s (%s)",
+		"member":     "Member",
+		"paid":       "Paid",
+		"owed":       "Owed",
+		"sent":       "Sent",
+		"received":   "Received",
+		"net":        "Net",
+		"settleUp":   "Settle up",
+		"transfer":   "%s pays %s",
+		"settled":    "Everyone is settled up.",
+		"page":       "Page %d of {nb}",
+		"simplified": "Debts are simplified to the fewest transfers.",
+	},
+	"es": {
+		"title":      "Resumen",
+		"period":     "Período: %s - %s",
+		"generated":  "Generado el %s",
+		"movements":  "Movimientos",
+		"date":       "Fecha",
+		"desc":       "Descripción",
+		"category":   "Categoría",
+		"paidBy":     "Pagado por",
+		"amount":     "Monto",
+		"total":      "Total",
+		"noMovs":     "No hay movimientos en este período.",
+		"balances":   "Saldos al %s (%s)",
+		"member":     "Miembro",
+		"paid":       "Pagó",
+		"owed":       "Debe",
+		"sent":       "Envió",
+		"received":   "Recibió",
+		"net":        "Neto",
+		"settleUp":   "Cómo saldar",
+		"transfer":   "%s le paga a %s",
+		"settled":    "No hay deudas pendientes.",
+		"page":       "Página %d de {nb}",
+		"simplified": "Las deudas se simplifican a la menor cantidad de transferencias.",
+	},
+	"zh": {
+		"title":      "账单",
+		"period":     "期间：%s - %s",
+		"generated":  "生成于 %s",
+		"movements":  "账目",
+		"date":       "日期",
+		"desc":       "说明",
+		"category":   "类别",
+		"paidBy":     "付款人",
+		"amount":     "金额",
+		"total":      "合计",
+		"noMovs":     "此期间没有账目。",
+		"balances":   "截至 %s 的余额（%s）",
+		"member":     "成员",
+		"paid":       "已付",
+		"owed":       "应付",
+		"sent":       "已转出",
+		"received":   "已收到",
+		"net":        "净额",
+		"settleUp":   "结算建议",
+		"transfer":   "%s 付给 %s",
+		"settled":    "所有人都已结清。",
+		"page":       "第 %d 页，共 {nb} 页",
+		"simplified": "债务已简化为最少的转账次数。",
+	},
+}
+
+// Date layout of every language
+var dateLayouts = map[string]string{
+	"en": "01/02/2006",
+	"es": "02/01/2006",
+	"zh": "2006-01-02",
+}
+
+// Languages that write 1.234,56 instead of 1,234.56
+var decimalComma = map[string]bool{
+	"es": true,
+}
+
+// Languages the core PDF fonts can not write, which need STATEMENT_FONT_FILE
+var unicodeOnly = map[string]bool{
+	"zh": true,
+}
+
+// locale gives the texts and formats of a language, falling back to the
+// default language for unknown ones
+type locale struct {
+	language string
+}
+
+func newLocale(language string) locale {
+
+	if _, ok := translations[language]; !ok {
+		language = models.DefaultLanguage
+	}
+	return locale{language: language}
+}
+
+func (l locale) text(key string) string {
+
+	return translations[l.language][key]
+}
+
+func (l locale) dateLayout() string {
+
+	return dateLayouts[l.language]
+}
+
+// amount formats with two decimals and thousands separators
+func (l locale) amount(value decimal.Decimal) string {
+
+	value = value.Round(2)
+	number := value.Abs().StringFixed(2)
+	integer, fraction, _ := strings.Cut(number, ".")
+
+	thousands, point := ",", "."
+	if decimalComma[l.language] {
+		thousands, point = ".", ","
+	}
+
+	var grouped strings.Builder
+	if value.IsNegative() {
+		grouped.WriteString("-")
+	}
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteString(thousands)
+		}
+		grouped.WriteRune(digit)
+	}
+
+	return grouped.String() + point + fraction
+}
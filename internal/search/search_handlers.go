@@ -0,0 +1,67 @@
+package search
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/auth"
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/middlewares"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/pagination"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+// Longest text a search accepts
+const maxSearchLength = 200
+
+type Handler struct {
+	service models.SearchService
+}
+
+func NewHandler(service models.SearchService) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+
+	// User routes, results are limited to the groups of the user
+	router.HandleFunc("/search", middlewares.WithJWTAuth(h.handleSearch)).Methods("GET")
+}
+
+// handleSearch answers ?q= with the best ranked matches first, a page at a
+// time like the other lists
+func (h *Handler) handleSearch(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	page, err := pagination.ParseRequest(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	text := strings.TrimSpace(r.URL.Query().Get("q"))
+	if text == "" || utf8.RuneCountInString(text) > maxSearchLength {
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(fmt.Sprintf("q must have between 1 and %d characters", maxSearchLength)))
+		return
+	}
+
+	results, err := h.service.Search(userId, text, page)
+	if err == errors.ErrInvalidCursor || err == errors.ErrInvalidSort {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, results)
+}
@@ -0,0 +1,163 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/pagination"
+	"github.com/lib/pq"
+)
+
+// ts_headline marks the matches with these control characters, which the
+// snippet escaping leaves alone, so they can become <mark> tags afterwards
+const (
+	startMark = "\x01"
+	stopMark  = "\x02"
+)
+
+var headlineOptions = "StartSel=" + startMark + ", StopSel=" + stopMark + `, MaxWords=20, MinWords=5, MaxFragments=2, FragmentDelimiter=" … "`
+
+// Results are always ranked, the key only breaks ties
+var searchSorts = pagination.Fields{
+	"rank": {{Expr: "r.rank", Type: "real"}, {Expr: "r.key", Type: "text"}},
+}
+
+// Postgres SQL Repository
+type SQLRepository struct {
+	db *sql.DB
+}
+
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+// Busca en los movimientos, valores de campos, comentarios, notas de
+// liquidaciones y nombres de los grupos en los que el usuario tiene un rol
+// vigente. La configuracion va como literal para que se usen los indices GIN.
+func (s *SQLRepository) Search(userId []uint8, text string, config string, page models.PageRequest) (*models.Page[*models.SearchResult], error) {
+
+	query, err := pagination.Build(searchSorts, page, "-rank", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	condition := ""
+	if query.Condition != "" {
+		condition = "WHERE " + query.Condition
+	}
+
+	vector := func(column string) string {
+		return fmt.Sprintf("to_tsvector(%s, %s)", pq.QuoteLiteral(config), column)
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+		WITH q AS (
+			SELECT websearch_to_tsquery(%[1]s, $2) AS query
+		), member_groups AS (
+			SELECT ur.group_id FROM auth.user_role ur
+			WHERE ur.user_id = $1 AND (ur.valid_until IS NULL OR ur.valid_until > CURRENT_TIMESTAMP)
+		)
+		SELECT r.type, r.id, r.group_id, r.group_name, r.field, r.snippet, r.rank, %[2]s
+		FROM (
+			SELECT 'movement' AS type, m.movement_id::text AS id, g.group_id, COALESCE(g.group_name, '') AS group_name, '' AS field,
+				ts_headline(%[1]s, m.description, q.query, $3) AS snippet,
+				ts_rank(%[3]s, q.query) AS rank, 'movement:' || m.movement_id AS key
+			FROM public.movement m
+			INNER JOIN public."group" g ON g.group_id = m.group_id
+			CROSS JOIN q
+			WHERE m.group_id IN (SELECT group_id FROM member_groups) AND %[3]s @@ q.query
+			UNION ALL
+			SELECT 'field', m.movement_id::text, g.group_id, COALESCE(g.group_name, ''), f.name,
+				ts_headline(%[1]s, v.value, q.query, $3),
+				ts_rank(%[4]s, q.query), 'field:' || v.movement_id || ':' || v.movement_field_id
+			FROM public.movement_field_value v
+			INNER JOIN public.movement_field f ON f.movement_field_id = v.movement_field_id
+			INNER JOIN public.movement m ON m.movement_id = v.movement_id
+			INNER JOIN public."group" g ON g.group_id = m.group_id
+			CROSS JOIN q
+			WHERE m.group_id IN (SELECT group_id FROM member_groups) AND %[4]s @@ q.query
+			UNION ALL
+			SELECT 'comment', m.movement_id::text, g.group_id, COALESCE(g.group_name, ''), '',
+				ts_headline(%[1]s, c.text, q.query, $3),
+				ts_rank(%[10]s, q.query), 'comment:' || c.comment_id
+			FROM public.movement_comment c
+			INNER JOIN public.movement m ON m.movement_id = c.movement_id
+			INNER JOIN public."group" g ON g.group_id = m.group_id
+			CROSS JOIN q
+			WHERE m.group_id IN (SELECT group_id FROM member_groups) AND %[10]s @@ q.query
+			UNION ALL
+			SELECT 'settlement', st.settlement_id::text, g.group_id, COALESCE(g.group_name, ''), '',
+				ts_headline(%[1]s, st.note, q.query, $3),
+				ts_rank(%[5]s, q.query), 'settlement:' || st.settlement_id
+			FROM public.settlement st
+			INNER JOIN public."group" g ON g.group_id = st.group_id
+			CROSS JOIN q
+			WHERE st.group_id IN (SELECT group_id FROM member_groups) AND %[5]s @@ q.query
+			UNION ALL
+			SELECT 'group', g.group_id::text, g.group_id, COALESCE(g.group_name, ''), '',
+				ts_headline(%[1]s, g.group_name, q.query, $3),
+				ts_rank(%[6]s, q.query), 'group:' || g.group_id
+			FROM public."group" g
+			CROSS JOIN q
+			WHERE g.group_id IN (SELECT group_id FROM member_groups) AND %[6]s @@ q.query
+		) r
+		%[7]s
+		ORDER BY %[8]s
+		LIMIT %[9]d`,
+		pq.QuoteLiteral(config),
+		query.Keys,
+		vector("COALESCE(m.description, '')"),
+		vector("v.value"),
+		vector("COALESCE(st.note, '')"),
+		vector("COALESCE(g.group_name, '')"),
+		condition,
+		query.Order,
+		query.Limit+1,
+		vector("c.text"),
+	), append([]any{userId, text, headlineOptions}, query.Args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.SearchResult
+	var keys [][]string
+
+	scanner := query.Scanner(rows)
+	for rows.Next() {
+		result := new(models.SearchResult)
+		err := scanner.Scan(
+			&result.Type,
+			&result.Id,
+			&result.GroupId,
+			&result.GroupName,
+			&result.Field,
+			&result.Snippet,
+			&result.Rank,
+		)
+		if err != nil {
+			return nil, errors.ErrSearchScan(err.Error())
+		}
+		result.Snippet = highlight(result.Snippet)
+		results = append(results, result)
+		keys = append(keys, scanner.Values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return pagination.Page(query, results, keys), nil
+}
+
+// Aux Functions
+
+// highlight escapes the snippet and turns the marks of ts_headline into tags
+func highlight(snippet string) string {
+
+	snippet = html.EscapeString(snippet)
+	return strings.NewReplacer(startMark, "<mark>", stopMark, "</mark>").Replace(snippet)
+}
@@ -0,0 +1,40 @@
+package search
+
+import (
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+// Postgres text search configuration of each language. Languages without
+// one, like zh, are searched word by word without stemming.
+var textSearchConfigs = map[string]string{
+	"en": "english",
+	"es": "spanish",
+}
+
+const defaultTextSearchConfig = "simple"
+
+type Service struct {
+	repository     models.SearchRepository
+	userRepository models.UserRepository
+}
+
+func NewService(repository models.SearchRepository, userRepository models.UserRepository) *Service {
+	return &Service{repository: repository, userRepository: userRepository}
+}
+
+// Search looks for text in the groups of the user, stemming the words with
+// the language of the user.
+func (s *Service) Search(userId []uint8, text string, page models.PageRequest) (*models.Page[*models.SearchResult], error) {
+
+	user, err := s.userRepository.GetUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	config, ok := textSearchConfigs[user.LanguageCode]
+	if !ok {
+		config = defaultTextSearchConfig
+	}
+
+	return s.repository.Search(userId, text, config, page)
+}
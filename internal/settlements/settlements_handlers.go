@@ -0,0 +1,181 @@
+package settlements
+
+import (
+	"net/http"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/auth"
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/middlewares"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service        models.SettlementService
+	authRepository models.AuthRepository
+}
+
+func NewHandler(service models.SettlementService, authRepository models.AuthRepository) *Handler {
+	return &Handler{service: service, authRepository: authRepository}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+
+	router.HandleFunc("/group/{groupId}/settlement", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleSettlementCreate, models.PermissionEditMovements, h.authRepository))).Methods("POST")
+	router.HandleFunc("/group/{groupId}/settlement", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetSettlements, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}/settlement/{settlementId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetSettlement, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+	router.HandleFunc("/group/{groupId}/settlement/{settlementId}", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleSettlementDelete, models.PermissionEditMovements, h.authRepository))).Methods("DELETE")
+	router.HandleFunc("/group/{groupId}/settlement/{settlementId}/confirm", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleSettlementConfirm, models.PermissionViewGroup, h.authRepository))).Methods("PUT")
+	router.HandleFunc("/group/{groupId}/settlement/{settlementId}/dispute", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleSettlementDispute, models.PermissionViewGroup, h.authRepository))).Methods("PUT")
+	router.HandleFunc("/group/{groupId}/history", middlewares.WithJWTAuth(middlewares.RequirePermission(h.handleGetHistory, models.PermissionViewGroup, h.authRepository))).Methods("GET")
+}
+
+func (h *Handler) handleSettlementCreate(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	var payload models.CreateSettlementPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		validationErrors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(validationErrors.Error()))
+		return
+	}
+
+	settlement, err := h.service.CreateSettlement(payload, groupId, userId)
+	if err == errors.ErrNotSettlementPayer {
+		utils.WriteError(w, http.StatusForbidden, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, settlement)
+}
+
+func (h *Handler) handleGetSettlements(w http.ResponseWriter, r *http.Request) {
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	settlements, err := h.service.GetGroupSettlements(groupId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, settlements)
+}
+
+func (h *Handler) handleGetSettlement(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	settlementId := []uint8(vars["settlementId"])
+
+	settlement, err := h.service.GetSettlementById(groupId, settlementId)
+	if err == errors.ErrSettlementNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, settlement)
+}
+
+func (h *Handler) handleSettlementConfirm(w http.ResponseWriter, r *http.Request) {
+
+	h.handleStatusUpdate(w, r, h.service.ConfirmSettlement, "Settlement confirmed successfully")
+}
+
+func (h *Handler) handleSettlementDispute(w http.ResponseWriter, r *http.Request) {
+
+	h.handleStatusUpdate(w, r, h.service.DisputeSettlement, "Settlement disputed successfully")
+}
+
+func (h *Handler) handleSettlementDelete(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	settlementId := []uint8(vars["settlementId"])
+
+	err = h.service.DeleteSettlement(groupId, settlementId, userId)
+	if err == errors.ErrSettlementNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err == errors.ErrSettlementConfirmed {
+		utils.WriteError(w, http.StatusForbidden, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Settlement deleted successfully",
+	})
+}
+
+func (h *Handler) handleGetHistory(w http.ResponseWriter, r *http.Request) {
+
+	groupId := []uint8(mux.Vars(r)["groupId"])
+
+	history, err := h.service.GetGroupHistory(groupId)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, history)
+}
+
+// Aux Functions
+
+func (h *Handler) handleStatusUpdate(w http.ResponseWriter, r *http.Request, update func(groupId []uint8, settlementId []uint8, userId []uint8) error, message string) {
+
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	vars := mux.Vars(r)
+	groupId := []uint8(vars["groupId"])
+	settlementId := []uint8(vars["settlementId"])
+
+	err = update(groupId, settlementId, userId)
+	if err == errors.ErrSettlementNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err == errors.ErrNotSettlementPayee {
+		utils.WriteError(w, http.StatusForbidden, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": message,
+	})
+}
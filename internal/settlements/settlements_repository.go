@@ -0,0 +1,194 @@
+package settlements
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+// Postgres SQL Repository
+type SQLRepository struct {
+	db *sql.DB
+}
+
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+const settlementColumns = `settlement_id, group_id, from_user_id, to_user_id, amount, settlement_date, note, status, created_at, created_by, updated_at, updated_by`
+
+func (s *SQLRepository) CreateSettlement(settlement models.Settlement) ([]uint8, error) {
+
+	var settlementId []uint8
+	err := s.db.QueryRow(
+		`INSERT INTO public.settlement (group_id, from_user_id, to_user_id, amount, settlement_date, note, status, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING settlement_id`,
+		settlement.GroupId, settlement.FromUserId, settlement.ToUserId, settlement.Amount, settlement.SettlementDate, settlement.Note, settlement.Status, settlement.CreatedBy, settlement.UpdatedBy,
+	).Scan(&settlementId)
+
+	if err != nil {
+		return nil, fmt.Errorf("error al crear el pago: %w", err)
+	}
+
+	return settlementId, nil
+}
+
+func (s *SQLRepository) GetSettlementById(groupId []uint8, settlementId []uint8) (*models.Settlement, error) {
+
+	row := s.db.QueryRow("SELECT "+settlementColumns+" FROM public.settlement WHERE group_id = $1 AND settlement_id = $2", groupId, settlementId)
+	return scanRowIntoSettlement(row)
+}
+
+func (s *SQLRepository) GetGroupSettlements(groupId []uint8) ([]*models.Settlement, error) {
+
+	rows, err := s.db.Query(`
+		SELECT `+settlementColumns+`
+		FROM public.settlement
+		WHERE group_id = $1
+		ORDER BY settlement_date DESC, created_at DESC`, groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los pagos del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var settlements []*models.Settlement
+	for rows.Next() {
+		settlement, err := scanRowIntoSettlement(rows)
+		if err != nil {
+			return nil, err
+		}
+		settlements = append(settlements, settlement)
+	}
+
+	return settlements, rows.Err()
+}
+
+func (s *SQLRepository) UpdateSettlementStatus(settlement models.Settlement) error {
+
+	res, err := s.db.Exec(
+		`UPDATE public.settlement
+		SET status = $1, updated_by = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE group_id = $3 AND settlement_id = $4`,
+		settlement.Status, settlement.UpdatedBy, settlement.GroupId, settlement.SettlementId,
+	)
+	if err != nil {
+		return fmt.Errorf("error al actualizar el estado del pago: %w", err)
+	}
+
+	return checkAffected(res)
+}
+
+func (s *SQLRepository) DeleteSettlement(groupId []uint8, settlementId []uint8) error {
+
+	res, err := s.db.Exec("DELETE FROM public.settlement WHERE group_id = $1 AND settlement_id = $2", groupId, settlementId)
+	if err != nil {
+		return fmt.Errorf("error al eliminar el pago: %w", err)
+	}
+
+	return checkAffected(res)
+}
+
+// Indica si el usuario tiene acceso vigente al grupo
+func (s *SQLRepository) IsActiveMember(groupId []uint8, userId []uint8) (bool, error) {
+
+	var exists bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM auth.user_role
+			WHERE group_id = $1 AND user_id = $2
+			AND (valid_until IS NULL OR valid_until > CURRENT_TIMESTAMP)
+		)`, groupId, userId).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error al verificar el miembro del grupo: %w", err)
+	}
+
+	return exists, nil
+}
+
+// Devuelve movimientos y pagos del grupo ordenados del mas reciente al mas antiguo
+func (s *SQLRepository) GetGroupHistory(groupId []uint8) ([]*models.HistoryItem, error) {
+
+	rows, err := s.db.Query(`
+		SELECT 'movement', movement_id, movement_date, amount, COALESCE(description, ''), NULL, NULL, '', created_at, created_by
+		FROM public.movement
+		WHERE group_id = $1
+		UNION ALL
+		SELECT 'settlement', settlement_id, settlement_date, amount, COALESCE(note, ''), from_user_id, to_user_id, status, created_at, created_by
+		FROM public.settlement
+		WHERE group_id = $1
+		ORDER BY 3 DESC, 9 DESC`, groupId)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el historial del grupo: %w", err)
+	}
+	defer rows.Close()
+
+	var history []*models.HistoryItem
+	for rows.Next() {
+		item := new(models.HistoryItem)
+		err := rows.Scan(
+			&item.Type,
+			&item.Id,
+			&item.Date,
+			&item.Amount,
+			&item.Description,
+			&item.FromUserId,
+			&item.ToUserId,
+			&item.Status,
+			&item.CreatedAt,
+			&item.CreatedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, item)
+	}
+
+	return history, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRowIntoSettlement(row rowScanner) (*models.Settlement, error) {
+
+	settlement := new(models.Settlement)
+	var note sql.NullString
+	err := row.Scan(
+		&settlement.SettlementId,
+		&settlement.GroupId,
+		&settlement.FromUserId,
+		&settlement.ToUserId,
+		&settlement.Amount,
+		&settlement.SettlementDate,
+		&note,
+		&settlement.Status,
+		&settlement.CreatedAt,
+		&settlement.CreatedBy,
+		&settlement.UpdatedAt,
+		&settlement.UpdatedBy,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrSettlementNotFound
+		}
+		return nil, errors.ErrSettlementScan(err.Error())
+	}
+	settlement.Note = note.String
+	return settlement, nil
+}
+
+func checkAffected(res sql.Result) error {
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.ErrSettlementNotFound
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package settlements
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+)
+
+type Service struct {
+	repository     models.SettlementRepository
+	authRepository models.AuthRepository
+}
+
+func NewService(repository models.SettlementRepository, authRepository models.AuthRepository) *Service {
+	return &Service{repository: repository, authRepository: authRepository}
+}
+
+// CreateSettlement registers a payment of the user. Only admins can register
+// payments made by other members.
+func (s *Service) CreateSettlement(payload models.CreateSettlementPayload, groupId []uint8, userId []uint8) (*models.Settlement, error) {
+
+	if err := utils.ValidateAmount(payload.Amount); err != nil {
+		return nil, err
+	}
+
+	settlementDate, err := utils.ParseDate("settlementDate", payload.SettlementDate)
+	if err != nil {
+		return nil, err
+	}
+
+	fromUserId := userId
+	if payload.FromUserId != "" {
+		fromUserId = []uint8(strings.ToLower(payload.FromUserId))
+	}
+	toUserId := []uint8(strings.ToLower(payload.ToUserId))
+
+	if string(fromUserId) != string(userId) {
+		admin, err := s.isAdmin(groupId, userId)
+		if err != nil {
+			return nil, err
+		}
+		if !admin {
+			return nil, errors.ErrNotSettlementPayer
+		}
+	}
+
+	if string(fromUserId) == string(toUserId) {
+		return nil, errors.ErrInvalidaPayload("a member can not pay to itself")
+	}
+
+	for _, id := range [][]uint8{fromUserId, toUserId} {
+		member, err := s.repository.IsActiveMember(groupId, id)
+		if err != nil {
+			return nil, err
+		}
+		if !member {
+			return nil, errors.ErrInvalidaPayload(fmt.Sprintf("user %s is not a member of the group", id))
+		}
+	}
+
+	settlement := models.Settlement{
+		GroupId:        groupId,
+		FromUserId:     fromUserId,
+		ToUserId:       toUserId,
+		Amount:         payload.Amount,
+		SettlementDate: settlementDate,
+		Note:           payload.Note,
+		Status:         models.SettlementPending,
+		CreatedBy:      userId,
+		UpdatedBy:      userId,
+	}
+
+	settlementId, err := s.repository.CreateSettlement(settlement)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.GetSettlementById(groupId, settlementId)
+}
+
+func (s *Service) GetSettlementById(groupId []uint8, settlementId []uint8) (*models.Settlement, error) {
+
+	return s.repository.GetSettlementById(groupId, settlementId)
+}
+
+func (s *Service) GetGroupSettlements(groupId []uint8) ([]*models.Settlement, error) {
+
+	return s.repository.GetGroupSettlements(groupId)
+}
+
+func (s *Service) ConfirmSettlement(groupId []uint8, settlementId []uint8, userId []uint8) error {
+
+	return s.updateStatus(groupId, settlementId, userId, models.SettlementConfirmed)
+}
+
+func (s *Service) DisputeSettlement(groupId []uint8, settlementId []uint8, userId []uint8) error {
+
+	return s.updateStatus(groupId, settlementId, userId, models.SettlementDisputed)
+}
+
+// DeleteSettlement removes a settlement. Confirmed settlements are part of
+// the balances both members agreed on, so only admins can delete them.
+func (s *Service) DeleteSettlement(groupId []uint8, settlementId []uint8, userId []uint8) error {
+
+	settlement, err := s.repository.GetSettlementById(groupId, settlementId)
+	if err != nil {
+		return err
+	}
+
+	if settlement.Status == models.SettlementConfirmed {
+		admin, err := s.isAdmin(groupId, userId)
+		if err != nil {
+			return err
+		}
+		if !admin {
+			return errors.ErrSettlementConfirmed
+		}
+	}
+
+	return s.repository.DeleteSettlement(groupId, settlementId)
+}
+
+func (s *Service) GetGroupHistory(groupId []uint8) ([]*models.HistoryItem, error) {
+
+	return s.repository.GetGroupHistory(groupId)
+}
+
+// Aux Functions
+
+// updateStatus lets the receiver of the payment confirm that the money
+// arrived or dispute it. A disputed settlement stops counting in the balances
+// until it is confirmed.
+func (s *Service) updateStatus(groupId []uint8, settlementId []uint8, userId []uint8, status string) error {
+
+	settlement, err := s.repository.GetSettlementById(groupId, settlementId)
+	if err != nil {
+		return err
+	}
+
+	if string(settlement.ToUserId) != string(userId) {
+		return errors.ErrNotSettlementPayee
+	}
+
+	settlement.Status = status
+	settlement.UpdatedBy = userId
+
+	return s.repository.UpdateSettlementStatus(*settlement)
+}
+
+// isAdmin checks a permission that only the admin role has
+func (s *Service) isAdmin(groupId []uint8, userId []uint8) (bool, error) {
+
+	return s.authRepository.UserHasPermission(userId, groupId, models.PermissionEditGroup)
+}
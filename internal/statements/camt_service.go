@@ -0,0 +1,161 @@
+package statements
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtEntry struct {
+	Reference string `xml:"NtryRef"`
+	Amount    struct {
+		Value    string `xml:",chardata"`
+		Currency string `xml:"Ccy,attr"`
+	} `xml:"Amt"`
+	Indicator string `xml:"CdtDbtInd"`
+	// Sts is a code up to version 8 and a <Cd> element afterwards
+	Status struct {
+		Value string `xml:",chardata"`
+		Code  string `xml:"Cd"`
+	} `xml:"Sts"`
+	BookingDate       camtDate `xml:"BookgDt"`
+	ValueDate         camtDate `xml:"ValDt"`
+	ServicerReference string   `xml:"AcctSvcrRef"`
+	AdditionalInfo    string   `xml:"AddtlNtryInf"`
+	Details           []struct {
+		References struct {
+			ServicerReference string `xml:"AcctSvcrRef"`
+			EndToEndId        string `xml:"EndToEndId"`
+			TransactionId     string `xml:"TxId"`
+		} `xml:"Refs"`
+		Creditor     string   `xml:"RltdPties>Cdtr>Nm"`
+		Debtor       string   `xml:"RltdPties>Dbtr>Nm"`
+		Unstructured []string `xml:"RmtInf>Ustrd"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+// ParseCAMT053 reads ISO 20022 bank to customer statements (camt.053) of any
+// version and in the charset declared by the XML. Entries that are not booked
+// yet are ignored.
+func ParseCAMT053(data []byte) ([]models.BankTransaction, error) {
+
+	var document struct {
+		Statements []struct {
+			Iban    string      `xml:"Acct>Id>IBAN"`
+			Other   string      `xml:"Acct>Id>Othr>Id"`
+			Entries []camtEntry `xml:"Ntry"`
+		} `xml:"BkToCstmrStmt>Stmt"`
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+	decoder.CharsetReader = charsetReader
+	if err := decoder.Decode(&document); err != nil {
+		return nil, errors.ErrStatementFile(err.Error())
+	}
+
+	var transactions []models.BankTransaction
+	for _, statement := range document.Statements {
+		account := statement.Iban
+		if account == "" {
+			account = statement.Other
+		}
+
+		for _, entry := range statement.Entries {
+			if status := firstNonEmpty(entry.Status.Code, entry.Status.Value); status != "" && status != "BOOK" {
+				continue
+			}
+
+			transaction, err := camtTransaction(entry)
+			if err != nil {
+				return nil, err
+			}
+			transaction.Account = account
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	return transactions, nil
+}
+
+// Aux Functions
+
+func camtTransaction(entry camtEntry) (models.BankTransaction, error) {
+
+	amount, err := decimal.NewFromString(strings.TrimSpace(entry.Amount.Value))
+	if err != nil {
+		return models.BankTransaction{}, errors.ErrStatementFile("invalid Amt " + entry.Amount.Value)
+	}
+	if entry.Indicator == "DBIT" {
+		amount = amount.Neg()
+	}
+
+	date, err := parseCAMTDate(entry.BookingDate)
+	if err != nil {
+		date, err = parseCAMTDate(entry.ValueDate)
+		if err != nil {
+			return models.BankTransaction{}, err
+		}
+	}
+
+	id := firstNonEmpty(entry.ServicerReference)
+	var description []string
+	for _, details := range entry.Details {
+		if id == "" {
+			id = firstNonEmpty(details.References.ServicerReference, details.References.TransactionId, details.References.EndToEndId)
+		}
+		if amount.IsNegative() {
+			description = append(description, details.Creditor)
+		} else {
+			description = append(description, details.Debtor)
+		}
+		description = append(description, details.Unstructured...)
+	}
+	if id == "" {
+		id = firstNonEmpty(entry.Reference)
+	}
+	description = append(description, entry.AdditionalInfo)
+
+	return models.BankTransaction{
+		Id:          id,
+		Date:        date,
+		Amount:      amount,
+		Currency:    strings.ToUpper(entry.Amount.Currency),
+		Description: joinText(description...),
+	}, nil
+}
+
+func parseCAMTDate(date camtDate) (time.Time, error) {
+
+	if date.Date != "" {
+		if t, err := time.Parse(time.DateOnly, strings.TrimSpace(date.Date)); err == nil {
+			return t, nil
+		}
+	}
+	if len(date.DateTime) >= 10 {
+		if t, err := time.Parse(time.DateOnly, date.DateTime[:10]); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, errors.ErrStatementFile("entry without booking or value date")
+}
+
+func firstNonEmpty(values ...string) string {
+
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" && value != "NOTPROVIDED" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package statements
+
+import (
+	"testing"
+)
+
+func TestParseCAMT053(t *testing.T) {
+
+	tests := []struct {
+		file string
+		want []want
+	}{
+		{
+			// Version 8: status codes, references in the details and a
+			// pending entry that is skipped
+			file: "camt053_v8.xml",
+			want: []want{
+				{id: "SVC-001", account: "DE89370400440532013000", date: "2024-02-27", amount: "-120.5", currency: "EUR", description: "Stadtwerke Köln Strom Februar"},
+				{id: "TX-2", account: "DE89370400440532013000", date: "2024-02-29", amount: "2500", currency: "EUR", description: "Arbeitgeber GmbH Gehalt Februar SEPA Gutschrift"},
+			},
+		},
+		{
+			// Version 2 in ISO-8859-1: text status and an account without IBAN
+			file: "camt053_v2_latin1.xml",
+			want: []want{
+				{id: "N-77", account: "0012345678", date: "2024-01-10", amount: "-80", currency: "CHF", description: "Bäckerei Zürich"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			transactions, err := ParseCAMT053(readTestdata(t, tt.file))
+			if err != nil {
+				t.Fatalf("ParseCAMT053() error = %v", err)
+			}
+			checkTransactions(t, transactions, tt.want)
+		})
+	}
+}
+
+func TestParseCAMT053Errors(t *testing.T) {
+
+	entry := func(content string) string {
+		return `<Document><BkToCstmrStmt><Stmt><Ntry>` + content + `</Ntry></Stmt></BkToCstmrStmt></Document>`
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "truncated xml", data: `<Document><BkToCstmrStmt><Stmt><Ntry>`},
+		{name: "not xml", data: `date;amount`},
+		{name: "unknown encoding", data: `<?xml version="1.0" encoding="x-unknown"?><Document></Document>`},
+		{name: "invalid amount", data: entry(`<Amt Ccy="EUR">1,5</Amt><CdtDbtInd>DBIT</CdtDbtInd><BookgDt><Dt>2024-01-01</Dt></BookgDt>`)},
+		{name: "missing dates", data: entry(`<Amt Ccy="EUR">1.50</Amt><CdtDbtInd>DBIT</CdtDbtInd>`)},
+		{name: "invalid dates", data: entry(`<Amt Ccy="EUR">1.50</Amt><BookgDt><Dt>01/01/2024</Dt></BookgDt><ValDt><DtTm>soon</DtTm></ValDt>`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCAMT053([]byte(tt.data)); err == nil {
+				t.Errorf("ParseCAMT053() returned no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package statements
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// :61: value date, optional entry date, debit/credit mark, optional funds
+// code, amount, transaction type, customer reference, optional bank reference
+// after // and optional supplementary details on the next line
+var mt940Line = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d*)([NSF][A-Z0-9]{3})([^/\n]*)(?://([^\n]*))?`)
+
+// :60F: / :60M: mark, date, currency and amount of the opening balance
+var mt940Balance = regexp.MustCompile(`^[CD]\d{6}([A-Z]{3})`)
+
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+// ParseMT940 reads SWIFT MT940 customer statements, with or without the
+// SWIFT block envelope. The currency comes from the opening balance.
+func ParseMT940(data []byte) ([]models.BankTransaction, error) {
+
+	fields, err := mt940Fields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions []models.BankTransaction
+	var account, currency string
+	var current *models.BankTransaction
+
+	flush := func() {
+		if current != nil {
+			transactions = append(transactions, *current)
+			current = nil
+		}
+	}
+
+	for _, field := range fields {
+		switch field.tag {
+		case "20":
+			flush()
+		case "25":
+			account = strings.TrimSpace(field.value)
+		case "60F", "60M":
+			if match := mt940Balance.FindStringSubmatch(field.value); match != nil {
+				currency = match[1]
+			}
+		case "61":
+			flush()
+			transaction, err := mt940Transaction(field.value)
+			if err != nil {
+				return nil, err
+			}
+			transaction.Account = account
+			transaction.Currency = currency
+			current = &transaction
+		case "86":
+			if current != nil {
+				current.Description = joinText(current.Description, mt940Information(field.value))
+			}
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return transactions, nil
+}
+
+// Aux Functions
+
+// mt940Fields splits the statement into its :tag: fields. Lines that do not
+// start with a tag continue the previous field.
+func mt940Fields(data []byte) ([]mt940Field, error) {
+
+	data, err := decodeText(data, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []mt940Field
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r ")
+
+		// SWIFT envelope: {1:...}{2:...}{4: opens the text block and -} closes it
+		if index := strings.Index(line, "{4:"); index >= 0 {
+			line = line[index+3:]
+		}
+		if line == "" || line == "-" || strings.HasPrefix(line, "-}") || strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			end := strings.Index(line[1:], ":")
+			if end > 0 {
+				fields = append(fields, mt940Field{tag: line[1 : end+1], value: line[end+2:]})
+				continue
+			}
+		}
+
+		if len(fields) == 0 {
+			return nil, errors.ErrStatementFile("text before the first MT940 field")
+		}
+		fields[len(fields)-1].value += "\n" + line
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.ErrStatementFile(err.Error())
+	}
+
+	return fields, nil
+}
+
+func mt940Transaction(value string) (models.BankTransaction, error) {
+
+	match := mt940Line.FindStringSubmatch(value)
+	if match == nil {
+		return models.BankTransaction{}, errors.ErrStatementFile("invalid :61: line " + value)
+	}
+
+	date, err := time.Parse("060102", match[1])
+	if err != nil {
+		return models.BankTransaction{}, errors.ErrStatementFile("invalid :61: date " + match[1])
+	}
+
+	amount, err := decimal.NewFromString(strings.ReplaceAll(match[5], ",", "."))
+	if err != nil {
+		return models.BankTransaction{}, errors.ErrStatementFile("invalid :61: amount " + match[5])
+	}
+	// RC reverses a credit, so it is a debit, and RD reverses a debit
+	if match[3] == "D" || match[3] == "RC" {
+		amount = amount.Neg()
+	}
+
+	id := strings.TrimSpace(match[8])
+	if customer := strings.TrimSpace(match[7]); id == "" && customer != "" && customer != "NONREF" {
+		id = customer
+	}
+
+	transaction := models.BankTransaction{
+		Id:     id,
+		Date:   date,
+		Amount: amount,
+	}
+	if _, details, ok := strings.Cut(value, "\n"); ok {
+		transaction.Description = details
+	}
+
+	return transaction, nil
+}
+
+// mt940Information returns the text of a :86: field. Structured fields use
+// ?NN subfields, where 20 to 29 and 60 to 63 are the remittance information
+// and 32 and 33 the name of the counterparty.
+func mt940Information(value string) string {
+
+	value = strings.ReplaceAll(value, "\n", "")
+	if !strings.Contains(value, "?") {
+		return value
+	}
+
+	var name, remittance []string
+	for _, part := range strings.Split(value, "?")[1:] {
+		if len(part) < 2 {
+			continue
+		}
+		code, text := part[:2], part[2:]
+		switch {
+		case code == "32" || code == "33":
+			name = append(name, text)
+		case code >= "20" && code <= "29", code >= "60" && code <= "63":
+			remittance = append(remittance, text)
+		}
+	}
+
+	return joinText(strings.Join(name, ""), strings.Join(remittance, " "))
+}
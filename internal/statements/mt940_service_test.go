@@ -0,0 +1,57 @@
+package statements
+
+import (
+	"testing"
+)
+
+func TestParseMT940(t *testing.T) {
+
+	// SWIFT envelope, ISO-8859-1, structured and free :86: text, an amount
+	// without decimals and a reversed debit
+	transactions, err := ParseMT940(readTestdata(t, "statement.sta"))
+	if err != nil {
+		t.Fatalf("ParseMT940() error = %v", err)
+	}
+
+	checkTransactions(t, transactions, []want{
+		{id: "BANKREF1", account: "10020030/1234567890", date: "2024-03-01", amount: "-25.3", currency: "EUR", description: "Kartenzahlung Bäcker Müller Einkauf Bäckerei Filiale 3"},
+		{id: "987654", account: "10020030/1234567890", date: "2024-03-02", amount: "1200", currency: "EUR", description: "Gehalt März"},
+		{account: "10020030/1234567890", date: "2024-03-03", amount: "5", currency: "EUR", description: "Storno Gebühr"},
+	})
+}
+
+func TestParseMT940Statements(t *testing.T) {
+
+	// Each :20: starts a statement with its own account and currency
+	data := []byte(":20:A\n:25:ACC1\n:60F:C240101USD0,00\n:61:240102C10,00NMSCREF1\n:20:B\n:25:ACC2\n:60M:D240101GBP0,00\n:61:240103RC2,50NMSCREF2\n:86:Fee\n")
+
+	transactions, err := ParseMT940(data)
+	if err != nil {
+		t.Fatalf("ParseMT940() error = %v", err)
+	}
+
+	checkTransactions(t, transactions, []want{
+		{id: "REF1", account: "ACC1", date: "2024-01-02", amount: "10", currency: "USD"},
+		{id: "REF2", account: "ACC2", date: "2024-01-03", amount: "-2.5", currency: "GBP", description: "Fee"},
+	})
+}
+
+func TestParseMT940Errors(t *testing.T) {
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "text before the first field", data: "statement\n:20:A\n"},
+		{name: "invalid transaction line", data: ":20:A\n:61:yesterday 10 EUR\n"},
+		{name: "invalid date", data: ":20:A\n:61:241340C10,00NMSCREF\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseMT940([]byte(tt.data)); err == nil {
+				t.Errorf("ParseMT940() returned no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package statements
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Aggregates like STMTTRN are closed in both versions
+var ofxTransaction = regexp.MustCompile(`(?s)<STMTTRN>(.*?)</STMTTRN>`)
+
+// Version 1 declares its charset in the ENCODING and CHARSET header lines,
+// version 2 in the XML declaration
+var (
+	ofxHeader   = regexp.MustCompile(`(?m)^\s*(ENCODING|CHARSET):\s*(\S+)`)
+	xmlEncoding = regexp.MustCompile(`^\s*<\?xml[^>]*encoding=["']([^"']+)["']`)
+)
+
+// ParseOFX reads OFX and QFX files, both the SGML based version 1, where
+// leaf elements are not closed, and the XML based version 2.
+func ParseOFX(data []byte) ([]models.BankTransaction, error) {
+
+	data, err := decodeText(data, ofxCharset(data))
+	if err != nil {
+		return nil, err
+	}
+
+	content := string(data)
+	currency := strings.ToUpper(ofxValue(content, "CURDEF"))
+	account := ofxValue(content, "ACCTID")
+
+	var transactions []models.BankTransaction
+	for _, match := range ofxTransaction.FindAllStringSubmatch(content, -1) {
+		block := match[1]
+
+		date, err := parseOFXDate(ofxValue(block, "DTPOSTED"))
+		if err != nil {
+			return nil, err
+		}
+
+		amount, err := decimal.NewFromString(strings.ReplaceAll(ofxValue(block, "TRNAMT"), ",", "."))
+		if err != nil {
+			return nil, errors.ErrStatementFile("invalid TRNAMT " + ofxValue(block, "TRNAMT"))
+		}
+
+		transactionCurrency := currency
+		if original := ofxValue(block, "CURSYM"); original != "" {
+			transactionCurrency = strings.ToUpper(original)
+		}
+
+		transactions = append(transactions, models.BankTransaction{
+			Id:          ofxValue(block, "FITID"),
+			Account:     account,
+			Date:        date,
+			Amount:      amount,
+			Currency:    transactionCurrency,
+			Description: joinText(ofxValue(block, "NAME"), ofxValue(block, "MEMO")),
+		})
+	}
+
+	return transactions, nil
+}
+
+// Aux Functions
+
+// ofxValue returns the text of the first element with the tag. In SGML files
+// the text ends at the next tag instead of at the closing one.
+func ofxValue(content string, tag string) string {
+
+	start := strings.Index(content, "<"+tag+">")
+	if start < 0 {
+		return ""
+	}
+	start += len(tag) + 2
+
+	end := strings.Index(content[start:], "<")
+	if end < 0 {
+		end = len(content) - start
+	}
+
+	return strings.TrimSpace(html.UnescapeString(content[start : start+end]))
+}
+
+// ofxCharset returns the charset declared by the file, or an empty string.
+// Version 1 charsets are Windows code page numbers like 1252.
+func ofxCharset(data []byte) string {
+
+	head := bytes.TrimPrefix(data[:min(len(data), 1024)], utf8BOM)
+	if match := xmlEncoding.FindSubmatch(head); match != nil {
+		return string(match[1])
+	}
+
+	var encoding, charset string
+	for _, match := range ofxHeader.FindAllSubmatch(head, -1) {
+		if string(match[1]) == "ENCODING" {
+			encoding = strings.ToUpper(string(match[2]))
+		} else {
+			charset = strings.ToUpper(string(match[2]))
+		}
+	}
+
+	switch {
+	case encoding == "UTF-8":
+		return "utf-8"
+	case charset == "" || charset == "NONE":
+		return ""
+	case strings.Trim(charset, "0123456789") == "":
+		return "windows-" + charset
+	}
+	return charset
+}
+
+// OFX dates are YYYYMMDD optionally followed by the time and the time zone
+func parseOFXDate(value string) (time.Time, error) {
+
+	if len(value) < 8 {
+		return time.Time{}, errors.ErrStatementFile("invalid DTPOSTED " + value)
+	}
+
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, errors.ErrStatementFile("invalid DTPOSTED " + value)
+	}
+
+	return date, nil
+}
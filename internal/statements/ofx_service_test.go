@@ -0,0 +1,99 @@
+package statements
+
+import (
+	"testing"
+)
+
+func TestParseOFX(t *testing.T) {
+
+	tests := []struct {
+		file string
+		want []want
+	}{
+		{
+			// SGML, Windows-1252, comma decimals and a foreign currency
+			file: "statement_v1.ofx",
+			want: []want{
+				{id: "202402050001", account: "ES7620770024003102575766", date: "2024-02-05", amount: "-42.5", currency: "EUR", description: "Café Müller Desayuno & café"},
+				{id: "202402150002", account: "ES7620770024003102575766", date: "2024-02-15", amount: "1500", currency: "USD", description: "Nómina"},
+			},
+		},
+		{
+			// XML, UTF-8 with a byte order mark and dates with time zone
+			file: "statement_v2.qfx",
+			want: []want{
+				{id: "A1", account: "987654321", date: "2023-12-30", amount: "-7.25", currency: "USD", description: "Crème Brûlée Café"},
+				{id: "A2", account: "987654321", date: "2024-01-02", amount: "2000", currency: "USD", description: "Payroll January"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			transactions, err := ParseOFX(readTestdata(t, tt.file))
+			if err != nil {
+				t.Fatalf("ParseOFX() error = %v", err)
+			}
+			checkTransactions(t, transactions, tt.want)
+		})
+	}
+}
+
+func TestParseOFXCharset(t *testing.T) {
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{
+			name: "xml declaration",
+			data: []byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<OFX><CURDEF>EUR</CURDEF><STMTTRN><DTPOSTED>20240101</DTPOSTED><TRNAMT>-1</TRNAMT><NAME>Pe\xf1a</NAME></STMTTRN></OFX>"),
+			want: "Peña",
+		},
+		{
+			name: "utf-8 header",
+			data: []byte("OFXHEADER:100\nENCODING:UTF-8\nCHARSET:NONE\n\n<OFX><CURDEF>EUR<STMTTRN><DTPOSTED>20240101<TRNAMT>-1<NAME>Peña</STMTTRN></OFX>"),
+			want: "Peña",
+		},
+		{
+			name: "no charset and not utf-8",
+			data: []byte("<OFX><CURDEF>EUR<STMTTRN><DTPOSTED>20240101<TRNAMT>-1<NAME>Pe\xf1a \x80</STMTTRN></OFX>"),
+			want: "Peña €",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactions, err := ParseOFX(tt.data)
+			if err != nil {
+				t.Fatalf("ParseOFX() error = %v", err)
+			}
+			if len(transactions) != 1 || transactions[0].Description != tt.want {
+				t.Errorf("ParseOFX() = %+v, want the description %q", transactions, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOFXErrors(t *testing.T) {
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "short date", data: "<OFX><STMTTRN><DTPOSTED>2024<TRNAMT>1<FITID>1</STMTTRN></OFX>"},
+		{name: "invalid date", data: "<OFX><STMTTRN><DTPOSTED>20241340<TRNAMT>1<FITID>1</STMTTRN></OFX>"},
+		{name: "missing date", data: "<OFX><STMTTRN><TRNAMT>1<FITID>1</STMTTRN></OFX>"},
+		{name: "invalid amount", data: "<OFX><STMTTRN><DTPOSTED>20240101<TRNAMT>ten<FITID>1</STMTTRN></OFX>"},
+		{name: "unknown charset", data: "OFXHEADER:100\nENCODING:USASCII\nCHARSET:9999\n\n<OFX></OFX>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseOFX([]byte(tt.data)); err == nil {
+				t.Errorf("ParseOFX() returned no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package statements
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// QIF dates are month first. Some programs use an apostrophe before the year
+// and some omit the leading zeros.
+var qifDateLayouts = []string{"01/02/2006", "1/2/2006", "01/02/06", "1/2/06", "01-02-2006", "1-2-06", "2006-01-02"}
+
+// ParseQIF reads the bank, cash and credit card sections of a QIF file. QIF
+// has no transaction ids or currencies, so both are filled by the importer.
+func ParseQIF(data []byte) ([]models.BankTransaction, error) {
+
+	data, err := decodeText(data, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions []models.BankTransaction
+	var current models.BankTransaction
+	var hasAmount bool
+	inTransactions := false
+	var payee, memo string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r ")
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "!") {
+			header := strings.ToLower(line)
+			inTransactions = strings.HasPrefix(header, "!type:bank") || strings.HasPrefix(header, "!type:cash") ||
+				strings.HasPrefix(header, "!type:ccard") || strings.HasPrefix(header, "!type:oth")
+			continue
+		}
+		if !inTransactions {
+			continue
+		}
+
+		code, value := line[0], strings.TrimSpace(line[1:])
+		switch code {
+		case 'D':
+			date, err := parseQIFDate(value)
+			if err != nil {
+				return nil, err
+			}
+			current.Date = date
+		case 'T', 'U':
+			amount, err := decimal.NewFromString(strings.ReplaceAll(value, ",", ""))
+			if err != nil {
+				return nil, errors.ErrStatementFile("invalid amount " + value)
+			}
+			current.Amount = amount
+			hasAmount = true
+		case 'P':
+			payee = value
+		case 'M':
+			memo = value
+		case '^':
+			if hasAmount && !current.Date.IsZero() {
+				current.Description = joinText(payee, memo)
+				transactions = append(transactions, current)
+			}
+			current, hasAmount, payee, memo = models.BankTransaction{}, false, "", ""
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.ErrStatementFile(err.Error())
+	}
+
+	return transactions, nil
+}
+
+// Aux Functions
+
+func parseQIFDate(value string) (time.Time, error) {
+
+	value = strings.ReplaceAll(strings.ReplaceAll(value, "'", "/"), " ", "")
+	for _, layout := range qifDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, errors.ErrStatementFile("invalid date " + value)
+}
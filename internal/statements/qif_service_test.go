@@ -0,0 +1,56 @@
+package statements
+
+import (
+	"testing"
+)
+
+func TestParseQIF(t *testing.T) {
+
+	// Windows-1252, thousands separators, U lines, short years and a category
+	// list that is not a transaction section
+	transactions, err := ParseQIF(readTestdata(t, "statement.qif"))
+	if err != nil {
+		t.Fatalf("ParseQIF() error = %v", err)
+	}
+
+	checkTransactions(t, transactions, []want{
+		{date: "2024-01-15", amount: "-1234.56", description: "Alquiler año Enero"},
+		{date: "2024-02-03", amount: "250", description: "Devolución"},
+		{date: "2024-03-01", amount: "-10"},
+		{date: "2024-03-05", amount: "-99.99", description: "Tarjeta"},
+	})
+}
+
+func TestParseQIFUTF8(t *testing.T) {
+
+	data := []byte("\xef\xbb\xbf!Type:Cash\nD12/31/2023\nT-3.50\nPCafé\n^\n")
+
+	transactions, err := ParseQIF(data)
+	if err != nil {
+		t.Fatalf("ParseQIF() error = %v", err)
+	}
+
+	checkTransactions(t, transactions, []want{
+		{date: "2023-12-31", amount: "-3.5", description: "Café"},
+	})
+}
+
+func TestParseQIFErrors(t *testing.T) {
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "day first date", data: "!Type:Bank\nD31/12/2024\nT1\n^\n"},
+		{name: "invalid date", data: "!Type:Bank\nDyesterday\nT1\n^\n"},
+		{name: "invalid amount", data: "!Type:Bank\nD01/01/2024\nTten\n^\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseQIF([]byte(tt.data)); err == nil {
+				t.Errorf("ParseQIF() returned no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package statements
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Some banks start UTF-8 files with a byte order mark
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// Parse reads a bank statement in any of the supported formats. The format
+// is taken from the file extension and, when it is not conclusive, from the
+// content.
+func Parse(filename string, data []byte) ([]models.BankTransaction, error) {
+
+	format := DetectFormat(filename, data)
+
+	var transactions []models.BankTransaction
+	var err error
+
+	switch format {
+	case models.StatementFormatOFX:
+		transactions, err = ParseOFX(data)
+	case models.StatementFormatQIF:
+		transactions, err = ParseQIF(data)
+	case models.StatementFormatCAMT:
+		transactions, err = ParseCAMT053(data)
+	case models.StatementFormatMT940:
+		transactions, err = ParseMT940(data)
+	default:
+		return nil, errors.ErrStatementFile("the format is not OFX, QFX, QIF, CAMT.053 or MT940")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(transactions) == 0 {
+		return nil, errors.ErrStatementFile("no transactions found")
+	}
+
+	assignIds(transactions)
+
+	return transactions, nil
+}
+
+// DetectFormat returns the statement format of the file, or an empty string
+func DetectFormat(filename string, data []byte) string {
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ofx", ".qfx":
+		return models.StatementFormatOFX
+	case ".qif":
+		return models.StatementFormatQIF
+	case ".sta", ".mt940", ".940":
+		return models.StatementFormatMT940
+	}
+
+	head := data[:min(len(data), 4096)]
+	switch {
+	case bytes.Contains(head, []byte("OFXHEADER")) || bytes.Contains(bytes.ToUpper(head), []byte("<OFX>")):
+		return models.StatementFormatOFX
+	case bytes.HasPrefix(bytes.TrimSpace(head), []byte("!Type:")) || bytes.HasPrefix(bytes.TrimSpace(head), []byte("!type:")):
+		return models.StatementFormatQIF
+	case bytes.Contains(head, []byte("BkToCstmrStmt")):
+		return models.StatementFormatCAMT
+	case bytes.Contains(head, []byte(":20:")) && bytes.Contains(data, []byte(":61:")):
+		return models.StatementFormatMT940
+	}
+
+	return ""
+}
+
+// Aux Functions
+
+// assignIds gives the transactions without a bank id a hash of the account,
+// date, amount and description. Identical entries of the same statement are
+// told apart by their position among them, so importing the file again
+// produces the same ids.
+func assignIds(transactions []models.BankTransaction) {
+
+	seen := make(map[string]int)
+	for i := range transactions {
+		t := &transactions[i]
+		if t.Id != "" {
+			if t.Account != "" {
+				t.Id = t.Account + ":" + t.Id
+			}
+			continue
+		}
+
+		key := strings.Join([]string{t.Account, t.Date.Format("2006-01-02"), t.Amount.String(), t.Description}, "|")
+		seen[key]++
+		sum := sha1.Sum([]byte(key + "|" + strconv.Itoa(seen[key])))
+		t.Id = "hash:" + hex.EncodeToString(sum[:12])
+	}
+}
+
+// decodeText converts the statement to UTF-8 from the charset it declares.
+// Files that declare none and are not valid UTF-8 are read as Windows-1252,
+// the usual encoding of bank exports.
+func decodeText(data []byte, charset string) ([]byte, error) {
+
+	data = bytes.TrimPrefix(data, utf8BOM)
+
+	var enc encoding.Encoding = charmap.Windows1252
+	if charset != "" {
+		var err error
+		if enc, err = htmlindex.Get(charset); err != nil {
+			return nil, errors.ErrStatementFile("unknown charset " + charset)
+		}
+	} else if utf8.Valid(data) {
+		return data, nil
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return nil, errors.ErrStatementFile(err.Error())
+	}
+	return decoded, nil
+}
+
+// charsetReader lets the XML decoder read files declaring a charset other
+// than UTF-8
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, errors.ErrStatementFile("unknown charset " + charset)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// joinText joins the non empty parts with a space
+func joinText(parts ...string) string {
+
+	var text []string
+	for _, part := range parts {
+		if part = strings.Join(strings.Fields(part), " "); part != "" {
+			text = append(text, part)
+		}
+	}
+	return strings.Join(text, " ")
+}
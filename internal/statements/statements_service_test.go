@@ -0,0 +1,166 @@
+package statements
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+)
+
+// want is the expected transaction, with the amount as text to compare the
+// sign and the decimals
+type want struct {
+	id          string
+	account     string
+	date        string
+	amount      string
+	currency    string
+	description string
+}
+
+func TestParse(t *testing.T) {
+
+	tests := []struct {
+		file   string
+		format string
+		count  int
+		ids    []string
+	}{
+		{file: "statement_v1.ofx", format: models.StatementFormatOFX, count: 2, ids: []string{"ES7620770024003102575766:202402050001", "ES7620770024003102575766:202402150002"}},
+		{file: "statement_v2.qfx", format: models.StatementFormatOFX, count: 2, ids: []string{"987654321:A1", "987654321:A2"}},
+		{file: "statement.qif", format: models.StatementFormatQIF, count: 4},
+		{file: "camt053_v8.xml", format: models.StatementFormatCAMT, count: 2, ids: []string{"DE89370400440532013000:SVC-001", "DE89370400440532013000:TX-2"}},
+		{file: "statement.sta", format: models.StatementFormatMT940, count: 3, ids: []string{"10020030/1234567890:BANKREF1", "10020030/1234567890:987654"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data := readTestdata(t, tt.file)
+
+			if format := DetectFormat(tt.file, data); format != tt.format {
+				t.Errorf("DetectFormat() = %q, want %q", format, tt.format)
+			}
+			// Without a known extension the content decides
+			if format := DetectFormat("statement.txt", data); format != tt.format {
+				t.Errorf("DetectFormat() by content = %q, want %q", format, tt.format)
+			}
+
+			transactions, err := Parse(tt.file, data)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(transactions) != tt.count {
+				t.Fatalf("Parse() returned %d transactions, want %d", len(transactions), tt.count)
+			}
+			for i, id := range tt.ids {
+				if transactions[i].Id != id {
+					t.Errorf("transaction %d id = %q, want %q", i, transactions[i].Id, id)
+				}
+			}
+			for i, transaction := range transactions {
+				if transaction.Id == "" {
+					t.Errorf("transaction %d has no id", i)
+				}
+			}
+		})
+	}
+}
+
+func TestParseGivesStableIds(t *testing.T) {
+
+	data := []byte("!Type:Bank\nD01/15/2024\nT-10.00\nPCoffee\n^\nD01/15/2024\nT-10.00\nPCoffee\n^\n")
+
+	first, err := Parse("statement.qif", data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	second, err := Parse("statement.qif", data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if !strings.HasPrefix(first[0].Id, "hash:") {
+		t.Errorf("id %q is not a hash", first[0].Id)
+	}
+	if first[0].Id == first[1].Id {
+		t.Errorf("identical entries share the id %q", first[0].Id)
+	}
+	for i := range first {
+		if first[i].Id != second[i].Id {
+			t.Errorf("transaction %d changed its id from %q to %q", i, first[i].Id, second[i].Id)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		filename string
+		data     string
+	}{
+		{name: "unknown format", filename: "statement.csv", data: "date,amount\n2024-01-01,10\n"},
+		{name: "no transactions", filename: "statement.qif", data: "!Type:Bank\n"},
+		{name: "empty file", filename: "statement.ofx", data: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.filename, []byte(tt.data)); err == nil {
+				t.Errorf("Parse() returned no error")
+			}
+		})
+	}
+}
+
+// Aux Functions
+
+func readTestdata(t *testing.T, name string) []byte {
+
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func checkTransactions(t *testing.T, got []models.BankTransaction, wants []want) {
+
+	t.Helper()
+
+	if len(got) != len(wants) {
+		t.Fatalf("got %d transactions, want %d", len(got), len(wants))
+	}
+
+	for i, w := range wants {
+		g := got[i]
+		date, err := time.Parse(time.DateOnly, w.date)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if g.Id != w.id {
+			t.Errorf("transaction %d id = %q, want %q", i, g.Id, w.id)
+		}
+		if g.Account != w.account {
+			t.Errorf("transaction %d account = %q, want %q", i, g.Account, w.account)
+		}
+		if !g.Date.Equal(date) {
+			t.Errorf("transaction %d date = %s, want %s", i, g.Date.Format(time.DateOnly), w.date)
+		}
+		if g.Amount.String() != w.amount {
+			t.Errorf("transaction %d amount = %s, want %s", i, g.Amount, w.amount)
+		}
+		if g.Currency != w.currency {
+			t.Errorf("transaction %d currency = %q, want %q", i, g.Currency, w.currency)
+		}
+		if g.Description != w.description {
+			t.Errorf("transaction %d description = %q, want %q", i, g.Description, w.description)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+)
+
+// LocalStorage keeps the files in a directory of the server. Its signed URLs
+// point to the /files route of this API, see Handler.
+type LocalStorage struct {
+	dir       string
+	publicURL string
+	secret    []byte
+}
+
+// NewLocalStorage fails without a secret, anyone could sign URLs otherwise
+func NewLocalStorage(dir string, publicURL string, secret string) (*LocalStorage, error) {
+
+	if secret == "" {
+		return nil, fmt.Errorf("the local storage needs a signing secret, set STORAGE_SIGNING_SECRET")
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	return &LocalStorage{dir: dir, publicURL: strings.TrimSuffix(publicURL, "/"), secret: []byte(secret)}, nil
+}
+
+// Put writes to a temporary file first, so a failed upload never leaves half
+// a file under the key
+func (s *LocalStorage) Put(key string, content io.Reader, size int64, contentType string) error {
+
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+		return errors.ErrStorage(err.Error())
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return errors.ErrStorage(err.Error())
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return errors.ErrStorage(err.Error())
+	}
+	if err := file.Close(); err != nil {
+		return errors.ErrStorage(err.Error())
+	}
+
+	if err := os.Rename(file.Name(), name); err != nil {
+		return errors.ErrStorage(err.Error())
+	}
+
+	return nil
+}
+
+func (s *LocalStorage) Get(key string) (io.ReadCloser, error) {
+
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, errors.ErrFileNotFound
+	} else if err != nil {
+		return nil, errors.ErrStorage(err.Error())
+	}
+
+	return file, nil
+}
+
+// Delete does nothing for files that do not exist
+func (s *LocalStorage) Delete(key string) error {
+
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return errors.ErrStorage(err.Error())
+	}
+
+	return nil
+}
+
+// SignedURL returns <publicURL>/<key>?expires=<unix time>&signature=<hmac>
+func (s *LocalStorage) SignedURL(key string, expiry time.Duration) (string, error) {
+
+	if !validKey(key) {
+		return "", errors.ErrFileNotFound
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.sign(key, expires))
+
+	return s.publicURL + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+// Verify checks the signature of a URL given by SignedURL and that it has not
+// expired
+func (s *LocalStorage) Verify(key string, expires string, signature string) error {
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return errors.ErrInvalidFileSignature
+	}
+
+	if !hmac.Equal([]byte(s.sign(key, expires)), []byte(signature)) {
+		return errors.ErrInvalidFileSignature
+	}
+
+	return nil
+}
+
+// Aux Functions
+
+func (s *LocalStorage) sign(key string, expires string) string {
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+
+	if !validKey(key) {
+		return "", errors.ErrFileNotFound
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
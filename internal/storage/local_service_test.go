@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/gorilla/mux"
+)
+
+func TestLocalStorage(t *testing.T) {
+
+	storage := newTestLocalStorage(t)
+	key := "users/1/photo.txt"
+
+	if err := storage.Put(key, strings.NewReader("first"), 5, "text/plain"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	// A second upload replaces the file
+	if err := storage.Put(key, strings.NewReader("second"), 6, "text/plain"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if got := readFile(t, storage, key); got != "second" {
+		t.Errorf("Get() = %q, want %q", got, "second")
+	}
+
+	if err := storage.Delete(key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := storage.Get(key); err != errors.ErrFileNotFound {
+		t.Errorf("Get() after Delete() error = %v, want %v", err, errors.ErrFileNotFound)
+	}
+	// Deleting twice is not an error
+	if err := storage.Delete(key); err != nil {
+		t.Errorf("second Delete() error = %v", err)
+	}
+
+	for _, invalid := range []string{"../outside.txt", "/etc/passwd"} {
+		if err := storage.Put(invalid, strings.NewReader("x"), 1, "text/plain"); err != errors.ErrFileNotFound {
+			t.Errorf("Put(%q) error = %v, want %v", invalid, err, errors.ErrFileNotFound)
+		}
+	}
+}
+
+func TestLocalStorageSignedURL(t *testing.T) {
+
+	storage := newTestLocalStorage(t)
+	key := "movements/1/receipt 1.pdf"
+
+	signed, err := storage.SignedURL(key, time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL() error = %v", err)
+	}
+	if !strings.HasPrefix(signed, "http://localhost:8080/api/v1/files/movements/1/receipt%201.pdf?") {
+		t.Fatalf("SignedURL() = %q, want it under the public URL with the key escaped", signed)
+	}
+
+	gotKey, expires, signature := parseSignedURL(t, signed)
+	if gotKey != key {
+		t.Errorf("SignedURL() key = %q, want %q", gotKey, key)
+	}
+	if err := storage.Verify(key, expires, signature); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		key       string
+		expires   string
+		signature string
+	}{
+		{name: "other key", key: "movements/1/other.pdf", expires: expires, signature: signature},
+		{name: "later expiry", key: key, expires: later(t, expires), signature: signature},
+		{name: "invalid expiry", key: key, expires: "tomorrow", signature: signature},
+		{name: "no signature", key: key, expires: expires},
+		{name: "other secret", key: key, expires: expires, signature: (&LocalStorage{secret: []byte("other")}).sign(key, expires)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := storage.Verify(tt.key, tt.expires, tt.signature); err != errors.ErrInvalidFileSignature {
+				t.Errorf("Verify() error = %v, want %v", err, errors.ErrInvalidFileSignature)
+			}
+		})
+	}
+
+	if _, err := storage.SignedURL("../outside.txt", time.Minute); err == nil {
+		t.Errorf("SignedURL() signed a key outside the storage")
+	}
+}
+
+func TestNewLocalStorageNeedsSecret(t *testing.T) {
+
+	if _, err := NewLocalStorage(t.TempDir(), "http://localhost:8080/api/v1/files", ""); err == nil {
+		t.Errorf("NewLocalStorage() accepted an empty signing secret")
+	}
+}
+
+func TestLocalStorageSignedURLExpires(t *testing.T) {
+
+	storage := newTestLocalStorage(t)
+	key := "users/1/photo.png"
+
+	signed, err := storage.SignedURL(key, -time.Second)
+	if err != nil {
+		t.Fatalf("SignedURL() error = %v", err)
+	}
+
+	_, expires, signature := parseSignedURL(t, signed)
+	if err := storage.Verify(key, expires, signature); err != errors.ErrInvalidFileSignature {
+		t.Errorf("Verify() of an expired URL error = %v, want %v", err, errors.ErrInvalidFileSignature)
+	}
+}
+
+func TestHandlerServesSignedFiles(t *testing.T) {
+
+	storage := newTestLocalStorage(t)
+	key := "users/1/note.txt"
+	if err := storage.Put(key, strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	router := mux.NewRouter()
+	NewHandler(storage).RegisterRoutes(router.PathPrefix("/api/v1").Subrouter())
+
+	signed, err := storage.SignedURL(key, time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL() error = %v", err)
+	}
+	target := strings.TrimPrefix(signed, "http://localhost:8080")
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		body   string
+	}{
+		{name: "signed", target: target, status: http.StatusOK, body: "hello"},
+		{name: "unsigned", target: "/api/v1/files/" + key, status: http.StatusForbidden},
+		{name: "signature of another key", target: strings.Replace(target, "note.txt", "other.txt", 1), status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.status)
+			}
+			if tt.body != "" && recorder.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", recorder.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+// Aux Functions
+
+func newTestLocalStorage(t *testing.T) *LocalStorage {
+
+	t.Helper()
+
+	storage, err := NewLocalStorage(t.TempDir(), "http://localhost:8080/api/v1/files/", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage
+}
+
+func readFile(t *testing.T, storage *LocalStorage, key string) string {
+
+	t.Helper()
+
+	file, err := storage.Get(key)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// parseSignedURL returns the key, expiry and signature of a local signed URL
+func parseSignedURL(t *testing.T, signed string) (string, string, string) {
+
+	t.Helper()
+
+	parsed, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := strings.TrimPrefix(parsed.Path, "/api/v1/files/")
+	return key, parsed.Query().Get("expires"), parsed.Query().Get("signature")
+}
+
+// later moves the expiry of a signed URL an hour forward
+func later(t *testing.T, expires string) string {
+
+	t.Helper()
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strconv.FormatInt(unix+3600, 10)
+}
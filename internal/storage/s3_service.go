@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Longest time a call to the S3 service may take
+const s3Timeout = 30 * time.Second
+
+// Region used to sign URLs when none is configured, the default of MinIO and
+// AWS S3
+const defaultS3Region = "us-east-1"
+
+// S3Storage keeps the files in a bucket of any S3 compatible service, such as
+// AWS S3 or a local MinIO. Signed URLs are presigned GET requests served by
+// the service itself.
+type S3Storage struct {
+	client *minio.Client
+	// signer presigns URLs for the endpoint clients reach, which is not the
+	// one the server uses when both run in the same private network
+	signer *minio.Client
+	bucket string
+}
+
+// NewS3Storage creates the bucket when it does not exist yet. Signed URLs
+// point to publicEndpoint.
+func NewS3Storage(endpoint string, publicEndpoint string, accessKey string, secretKey string, bucket string, region string, useSSL bool, publicUseSSL bool) (*S3Storage, error) {
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+		Region: region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Presigning is done locally, but without a region the client asks the
+	// public endpoint for it, which the server may not reach
+	signingRegion := region
+	if signingRegion == "" {
+		signingRegion = defaultS3Region
+	}
+	signer, err := minio.New(publicEndpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: publicUseSSL,
+		Region: signingRegion,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{Region: region}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &S3Storage{client: client, signer: signer, bucket: bucket}, nil
+}
+
+func (s *S3Storage) Put(key string, content io.Reader, size int64, contentType string) error {
+
+	if !validKey(key) {
+		return errors.ErrFileNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+
+	_, err := s.client.PutObject(ctx, s.bucket, key, content, size, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return errors.ErrStorage(err.Error())
+	}
+
+	return nil
+}
+
+// Get checks the object exists before returning it, GetObject alone only
+// fails on the first read
+func (s *S3Storage) Get(key string) (io.ReadCloser, error) {
+
+	object, err := s.client.GetObject(context.Background(), s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, errors.ErrStorage(err.Error())
+	}
+
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, errors.ErrFileNotFound
+		}
+		return nil, errors.ErrStorage(err.Error())
+	}
+
+	return object, nil
+}
+
+func (s *S3Storage) Delete(key string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+
+	if err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return errors.ErrStorage(err.Error())
+	}
+
+	return nil
+}
+
+func (s *S3Storage) SignedURL(key string, expiry time.Duration) (string, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+
+	signed, err := s.signer.PresignedGetObject(ctx, s.bucket, key, expiry, nil)
+	if err != nil {
+		return "", errors.ErrStorage(err.Error())
+	}
+
+	return signed.String(), nil
+}
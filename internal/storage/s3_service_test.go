@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+)
+
+func TestS3Storage(t *testing.T) {
+
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	storage := newTestS3Storage(t, server, "files.example.com")
+
+	if !fake.hasBucket("smartspend") {
+		t.Fatalf("NewS3Storage() did not create the bucket")
+	}
+
+	key := "users/1/photo.txt"
+	if err := storage.Put(key, strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if got, contentType := fake.object("smartspend", key); got != "hello" || contentType != "text/plain" {
+		t.Errorf("stored object = %q of type %q, want %q of type text/plain", got, contentType, "hello")
+	}
+
+	file, err := storage.Get(key)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil || string(data) != "hello" {
+		t.Errorf("Get() = %q, %v, want %q", data, err, "hello")
+	}
+
+	if _, err := storage.Get("users/1/missing.txt"); err != errors.ErrFileNotFound {
+		t.Errorf("Get() of a missing object error = %v, want %v", err, errors.ErrFileNotFound)
+	}
+
+	if err := storage.Delete(key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := storage.Get(key); err != errors.ErrFileNotFound {
+		t.Errorf("Get() after Delete() error = %v, want %v", err, errors.ErrFileNotFound)
+	}
+
+	if err := storage.Put("../outside.txt", strings.NewReader("x"), 1, "text/plain"); err != errors.ErrFileNotFound {
+		t.Errorf("Put() of an invalid key error = %v, want %v", err, errors.ErrFileNotFound)
+	}
+}
+
+func TestS3StorageKeepsExistingBucket(t *testing.T) {
+
+	fake := newFakeS3()
+	fake.buckets["smartspend"] = true
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	newTestS3Storage(t, server, "files.example.com")
+
+	if created, _ := fake.counts(); created != 0 {
+		t.Errorf("NewS3Storage() created an existing bucket")
+	}
+}
+
+func TestS3StorageSignedURL(t *testing.T) {
+
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	storage := newTestS3Storage(t, server, "files.example.com")
+	_, requests := fake.counts()
+
+	signed, err := storage.SignedURL("users/1/photo.png", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL() error = %v", err)
+	}
+
+	parsed, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Signed for the public endpoint, without asking the service anything
+	if parsed.Scheme != "https" || parsed.Host != "files.example.com" || parsed.Path != "/smartspend/users/1/photo.png" {
+		t.Errorf("SignedURL() = %q, want https://files.example.com/smartspend/users/1/photo.png", signed)
+	}
+	query := parsed.Query()
+	if query.Get("X-Amz-Expires") != "300" || query.Get("X-Amz-Signature") == "" || !strings.Contains(query.Get("X-Amz-Credential"), "/us-east-1/s3/") {
+		t.Errorf("SignedURL() query = %v, want a 300 second signature for us-east-1", query)
+	}
+	if _, after := fake.counts(); after != requests {
+		t.Errorf("SignedURL() made %d requests to the service", after-requests)
+	}
+}
+
+// Aux Functions
+
+func newTestS3Storage(t *testing.T, server *httptest.Server, publicEndpoint string) *S3Storage {
+
+	t.Helper()
+
+	endpoint := strings.TrimPrefix(server.URL, "http://")
+	storage, err := NewS3Storage(endpoint, publicEndpoint, "access", "secret", "smartspend", "", false, true)
+	if err != nil {
+		t.Fatalf("NewS3Storage() error = %v", err)
+	}
+	return storage
+}
+
+// fakeS3 is an in memory stand-in for the S3 calls the storage makes, with
+// path style buckets like MinIO. Signatures are not checked.
+type fakeS3 struct {
+	mu             sync.Mutex
+	buckets        map[string]bool
+	objects        map[string]string
+	types          map[string]string
+	createdBuckets int
+	requests       int
+}
+
+func newFakeS3() *fakeS3 {
+
+	return &fakeS3{buckets: map[string]bool{}, objects: map[string]string{}, types: map[string]string{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	name := bucket + "/" + key
+
+	switch {
+	case r.URL.Query().Has("location"):
+		w.Write([]byte(`<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/"></LocationConstraint>`))
+
+	case key == "" && r.Method == http.MethodHead:
+		if !f.buckets[bucket] {
+			w.WriteHeader(http.StatusNotFound)
+		}
+
+	case key == "" && r.Method == http.MethodPut:
+		f.buckets[bucket] = true
+		f.createdBuckets++
+
+	case !f.buckets[bucket]:
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchBucket")
+
+	case r.Method == http.MethodPut:
+		data, err := readS3Body(r)
+		if err != nil {
+			writeS3Error(w, r, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[name] = data
+		f.types[name] = r.Header.Get("Content-Type")
+		w.Header().Set("ETag", `"etag"`)
+
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := f.objects[name]
+		if !ok {
+			writeS3Error(w, r, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Content-Type", f.types[name])
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write([]byte(data))
+		}
+
+	case r.Method == http.MethodDelete:
+		delete(f.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeS3Error(w, r, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (f *fakeS3) hasBucket(bucket string) bool {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.buckets[bucket]
+}
+
+func (f *fakeS3) object(bucket string, key string) (string, string) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.objects[bucket+"/"+key], f.types[bucket+"/"+key]
+}
+
+// counts returns the buckets created and the requests received
+func (f *fakeS3) counts() (int, int) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.createdBuckets, f.requests
+}
+
+func writeS3Error(w http.ResponseWriter, r *http.Request, status int, code string) {
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		w.Write([]byte("<Error><Code>" + code + "</Code></Error>"))
+	}
+}
+
+// readS3Body decodes the aws-chunked body of streaming signed uploads, which
+// the client uses over plain HTTP
+func readS3Body(r *http.Request) (string, error) {
+
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		data, err := io.ReadAll(r.Body)
+		return string(data), err
+	}
+
+	reader := bufio.NewReader(r.Body)
+	var data strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		sizeText, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeText, 16, 64)
+		if err != nil {
+			return "", err
+		}
+		if size == 0 {
+			return data.String(), nil
+		}
+		if _, err := io.CopyN(&data, reader, size); err != nil {
+			return "", err
+		}
+		if _, err := reader.Discard(2); err != nil {
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/utils"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	storage models.Storage
+}
+
+func NewHandler(storage models.Storage) *Handler {
+	return &Handler{storage: storage}
+}
+
+// RegisterRoutes only adds the route for the local storage, S3 serves its own
+// signed URLs
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+
+	if _, ok := h.storage.(*LocalStorage); !ok {
+		return
+	}
+
+	// Public route, the signature is the authorization
+	router.HandleFunc("/files/{key:.+}", h.handleGetFile).Methods("GET", "HEAD")
+}
+
+func (h *Handler) handleGetFile(w http.ResponseWriter, r *http.Request) {
+
+	local := h.storage.(*LocalStorage)
+	key := mux.Vars(r)["key"]
+
+	query := r.URL.Query()
+	if err := local.Verify(key, query.Get("expires"), query.Get("signature")); err != nil {
+		utils.WriteError(w, http.StatusForbidden, err)
+		return
+	}
+
+	name, err := local.path(key)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, errors.ErrFileNotFound)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		utils.WriteError(w, http.StatusNotFound, errors.ErrFileNotFound)
+		return
+	}
+
+	contentType, err := mimetype.DetectReader(file)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType.String())
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/conf"
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// Content types accepted for each kind of upload
+var (
+	PhotoTypes      = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+	AttachmentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "image/heic", "application/pdf"}
+)
+
+// New opens the storage chosen by STORAGE_BACKEND
+func New(cfg conf.ApiServerConfig) (models.Storage, error) {
+
+	switch cfg.StorageBackend {
+	case BackendLocal:
+		return NewLocalStorage(cfg.StorageLocalDir, cfg.StoragePublicURL, cfg.StorageSigningSecret)
+	case BackendS3:
+		return NewS3Storage(cfg.S3Endpoint, cfg.S3PublicEndpoint, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3Bucket, cfg.S3Region, cfg.S3UseSSL, cfg.S3PublicUseSSL)
+	}
+
+	return nil, fmt.Errorf("unknown storage backend %q, expected %s or %s", cfg.StorageBackend, BackendLocal, BackendS3)
+}
+
+// Detect returns the content type of the file, read from its content and not
+// from the name or headers given by the client, and the extension for it
+func Detect(data []byte, allowed []string) (string, string, error) {
+
+	detected := mimetype.Detect(data)
+	for _, contentType := range allowed {
+		if detected.Is(contentType) {
+			return contentType, detected.Extension(), nil
+		}
+	}
+
+	return "", "", errors.ErrUnsupportedFileType(detected.String())
+}
+
+// NewKey returns a key under prefix nobody can guess
+func NewKey(prefix string, extension string) (string, error) {
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	return path.Join(prefix, hex.EncodeToString(random)+extension), nil
+}
+
+// SignURL signs the key for STORAGE_URL_EXPIRATION_IN_SECONDS
+func SignURL(storage models.Storage, key string) (string, error) {
+
+	return storage.SignedURL(key, time.Duration(conf.ServerConfig.StorageURLExpirationInSeconds)*time.Second)
+}
+
+// PhotoURL returns a signed URL for a stored photo, or url for photos that
+// are only a link
+func PhotoURL(storage models.Storage, key string, url string) string {
+
+	if key == "" {
+		return url
+	}
+
+	signed, err := SignURL(storage, key)
+	if err != nil {
+		log.Printf("signing photo %s: %v", key, err)
+		return url
+	}
+	return signed
+}
+
+// Aux Functions
+
+// validKey rejects keys that would leave the storage directory
+func validKey(key string) bool {
+
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	return path.Clean(key) == key && !strings.HasPrefix(key, "../") && key != ".."
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidKey(t *testing.T) {
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "users/1/photo.png", want: true},
+		{key: "photo.png", want: true},
+		{key: "movements/1/..receipt.pdf", want: true},
+		{key: "", want: false},
+		{key: "/etc/passwd", want: false},
+		{key: "..", want: false},
+		{key: "../photo.png", want: false},
+		{key: "users/../../photo.png", want: false},
+		{key: "users/./photo.png", want: false},
+		{key: "users//photo.png", want: false},
+		{key: "users/1/", want: false},
+		{key: "users\\1\\photo.png", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := validKey(tt.key); got != tt.want {
+				t.Errorf("validKey(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewKey(t *testing.T) {
+
+	first, err := NewKey("users/1", ".png")
+	if err != nil {
+		t.Fatalf("NewKey() error = %v", err)
+	}
+	second, err := NewKey("users/1", ".png")
+	if err != nil {
+		t.Fatalf("NewKey() error = %v", err)
+	}
+
+	if !strings.HasPrefix(first, "users/1/") || !strings.HasSuffix(first, ".png") || !validKey(first) {
+		t.Errorf("NewKey() = %q, want a valid key under users/1 ending in .png", first)
+	}
+	if first == second {
+		t.Errorf("NewKey() returned %q twice", first)
+	}
+}
+
+func TestDetect(t *testing.T) {
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x02\x00\x00\x00")
+
+	contentType, extension, err := Detect(png, PhotoTypes)
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	if contentType != "image/png" || extension != ".png" {
+		t.Errorf("Detect() = %q, %q, want image/png, .png", contentType, extension)
+	}
+
+	// The content decides, so an html page is not a photo whatever its name
+	if _, _, err := Detect([]byte("<html><body>photo</body></html>"), PhotoTypes); err == nil {
+		t.Errorf("Detect() accepted html as a photo")
+	}
+}
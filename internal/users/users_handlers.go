@@ -3,6 +3,7 @@ package users
 import (
 	"net/http"
 
+	"github.com/PabloPei/SmartSpend-backend/internal/auth"
 	"github.com/PabloPei/SmartSpend-backend/internal/errors"
 	"github.com/PabloPei/SmartSpend-backend/internal/middlewares"
 	"github.com/PabloPei/SmartSpend-backend/internal/models"
@@ -11,6 +12,9 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// Largest photo that can be uploaded
+const maxPhotoSize = 5 << 20
+
 type Handler struct {
 	service models.UserService
 }
@@ -116,6 +120,8 @@ func (h *Handler) handleGetUser(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, userPublic)
 }
 
+// handleUserPhoto takes a JSON body with a link to the photo or a multipart
+// form with the photo itself in "file"
 func (h *Handler) handleUserPhoto(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
@@ -126,6 +132,31 @@ func (h *Handler) handleUserPhoto(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userId, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, errors.ErrJWTInvalidToken)
+		return
+	}
+
+	// Only the user can change its own photo
+	user, err := h.service.GetUserPublicByEmail(email)
+	if err == errors.ErrUserNotFound {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if string(user.UserId) != string(userId) {
+		utils.WriteError(w, http.StatusForbidden, errors.ErrNotPhotoOwner)
+		return
+	}
+
+	if utils.IsMultipart(r) {
+		h.handleUserPhotoFile(w, r, email, userId)
+		return
+	}
+
 	var payload models.UploadPhotoPayload
 	if err := utils.ParseJSON(r, &payload); err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
@@ -138,8 +169,11 @@ func (h *Handler) handleUserPhoto(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.service.UploadPhoto(payload, email)
-	if err != nil {
+	err = h.service.UploadPhoto(payload, email, userId)
+	if err == errors.ErrNotPhotoOwner {
+		utils.WriteError(w, http.StatusForbidden, err)
+		return
+	} else if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
@@ -148,3 +182,28 @@ func (h *Handler) handleUserPhoto(w http.ResponseWriter, r *http.Request) {
 		"message": "Photo uploaded successfully",
 	})
 }
+
+// handleUserPhotoFile stores the photo sent in the "file" field of a
+// multipart form
+func (h *Handler) handleUserPhotoFile(w http.ResponseWriter, r *http.Request, email string, userId []uint8) {
+
+	data, _, err := utils.ParseFile(w, r, "file", maxPhotoSize)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, errors.ErrInvalidaPayload(err.Error()))
+		return
+	}
+
+	photoUrl, err := h.service.StorePhoto(data, email, userId)
+	if err == errors.ErrNotPhotoOwner {
+		utils.WriteError(w, http.StatusForbidden, err)
+		return
+	} else if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{
+		"message":  "Photo uploaded successfully",
+		"photoUrl": photoUrl,
+	})
+}
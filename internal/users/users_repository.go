@@ -17,6 +17,8 @@ func NewSQLRepository(db *sql.DB) *SQLRepository {
 	return &SQLRepository{db: db}
 }
 
+const userColumns = `user_id, user_name, email, password, COALESCE(photo_url, ''), COALESCE(photo_key, ''), language_code, created_at, updated_at`
+
 func (s *SQLRepository) CreateUser(user models.User) error {
 	_, err := s.db.Exec(
 		"INSERT INTO auth.\"user\" (user_name, email, password) VALUES ($1, $2, $3)",
@@ -29,10 +31,11 @@ func (s *SQLRepository) CreateUser(user models.User) error {
 	return nil
 }
 
+// Cambia la foto por un link, la foto subida deja de usarse
 func (s *SQLRepository) UploadPhoto(photoUrl string, email string) error {
 
 	_, err := s.db.Exec(
-		"UPDATE auth.\"user\" SET photo_url = $1 WHERE email = $2",
+		"UPDATE auth.\"user\" SET photo_url = $1, photo_key = NULL, updated_at = CURRENT_TIMESTAMP WHERE email = $2",
 		photoUrl, email,
 	)
 
@@ -43,13 +46,28 @@ func (s *SQLRepository) UploadPhoto(photoUrl string, email string) error {
 	return nil
 }
 
+// Guarda la clave de la foto subida al storage
+func (s *SQLRepository) UpdatePhotoKey(photoKey string, email string) error {
+
+	_, err := s.db.Exec(
+		"UPDATE auth.\"user\" SET photo_key = $1, updated_at = CURRENT_TIMESTAMP WHERE email = $2",
+		photoKey, email,
+	)
+
+	if err != nil {
+		return fmt.Errorf("error al actualizar la foto: %w", err)
+	}
+
+	return nil
+}
+
 func (s *SQLRepository) GetUserByEmail(email string) (*models.User, error) {
-	row := s.db.QueryRow("SELECT * FROM auth.\"user\" WHERE email = $1", email)
+	row := s.db.QueryRow("SELECT "+userColumns+" FROM auth.\"user\" WHERE email = $1", email)
 	return scanRowIntoUser(row)
 }
 
 func (s *SQLRepository) GetUserById(id []uint8) (*models.User, error) {
-	row := s.db.QueryRow("SELECT * FROM auth.\"user\" WHERE user_id = $1", id)
+	row := s.db.QueryRow("SELECT "+userColumns+" FROM auth.\"user\" WHERE user_id = $1", id)
 	return scanRowIntoUser(row)
 }
 
@@ -62,6 +80,7 @@ func scanRowIntoUser(row *sql.Row) (*models.User, error) {
 		&user.Email,
 		&user.Password,
 		&user.PhotoUrl,
+		&user.PhotoKey,
 		&user.LanguageCode,
 		&user.CreatedAt,
 		&user.UpdatedAt,
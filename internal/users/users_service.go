@@ -1,17 +1,22 @@
 package users
 
 import (
+	"log"
+
 	"github.com/PabloPei/SmartSpend-backend/internal/auth"
 	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/PabloPei/SmartSpend-backend/internal/images"
 	"github.com/PabloPei/SmartSpend-backend/internal/models"
+	"github.com/PabloPei/SmartSpend-backend/internal/storage"
 )
 
 type Service struct {
 	repository models.UserRepository
+	storage    models.Storage
 }
 
-func NewService(repository models.UserRepository) *Service {
-	return &Service{repository: repository}
+func NewService(repository models.UserRepository, storage models.Storage) *Service {
+	return &Service{repository: repository, storage: storage}
 }
 
 func (s *Service) RegisterUser(payload models.RegisterUserPayload) error {
@@ -32,7 +37,16 @@ func (s *Service) RegisterUser(payload models.RegisterUserPayload) error {
 		Password: hashedPassword,
 	}
 
-	return s.repository.CreateUser(user)
+	if err := s.repository.CreateUser(user); err != nil {
+		return err
+	}
+
+	if payload.PhotoUrl != "" {
+		return s.repository.UploadPhoto(payload.PhotoUrl, payload.Email)
+	}
+
+	s.storeIdenticon(payload.Email)
+	return nil
 }
 
 func (s *Service) LogInUser(user models.LogInUserPayload) (string, string, error) {
@@ -69,11 +83,14 @@ func (s *Service) GetUserPublicByEmail(email string) (*models.UserPublicPayload,
 		return nil, err
 	}
 
-	return &models.UserPublicPayload{
+	user := &models.UserPublicPayload{
 		UserId:   u.UserId,
 		Email:    u.Email,
 		UserName: u.UserName,
-	}, nil
+	}
+	user.PhotoUrl, user.Thumbnails = images.URLs(s.storage, u.PhotoKey, u.PhotoUrl)
+
+	return user, nil
 }
 
 func (s *Service) RefreshToken(userId []uint8) (string, error) {
@@ -95,14 +112,44 @@ func (s *Service) RefreshToken(userId []uint8) (string, error) {
 	return accessToken, nil
 }
 
-func (s *Service) UploadPhoto(payload models.UploadPhotoPayload, email string) error {
+// UploadPhoto changes the photo of the user by a link. Only the user itself,
+// userId, can change it, as the stored photo is deleted.
+func (s *Service) UploadPhoto(payload models.UploadPhotoPayload, email string, userId []uint8) error {
+
+	user, err := s.photoOwner(email, userId)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repository.UploadPhoto(payload.PhotoUrl, email); err != nil {
+		return err
+	}
+
+	images.Delete(s.storage, user.PhotoKey)
+	return nil
+}
+
+// StorePhoto saves an uploaded photo, without its metadata and with its
+// thumbnails, and returns a signed URL for it. The previous uploaded photo is
+// deleted.
+func (s *Service) StorePhoto(data []byte, email string, userId []uint8) (string, error) {
 
-	_, err := s.repository.GetUserByEmail(email)
+	user, err := s.photoOwner(email, userId)
 	if err != nil {
-		return errors.ErrUploadPhoto
+		return "", err
+	}
+
+	photo, err := images.Process(data)
+	if err != nil {
+		return "", err
 	}
 
-	return s.repository.UploadPhoto(payload.PhotoUrl, email)
+	key, err := s.savePhoto(user, photo)
+	if err != nil {
+		return "", err
+	}
+
+	return storage.SignURL(s.storage, key)
 }
 
 // Aux Functions
@@ -118,3 +165,57 @@ func createJWTPayload(user models.User) auth.UserJWT {
 	return userJWT
 
 }
+
+// photoOwner returns the user of email when it is the user changing the
+// photo
+func (s *Service) photoOwner(email string, userId []uint8) (*models.User, error) {
+
+	user, err := s.repository.GetUserByEmail(email)
+	if err != nil {
+		return nil, errors.ErrUploadPhoto
+	}
+	if string(user.UserId) != string(userId) {
+		return nil, errors.ErrNotPhotoOwner
+	}
+
+	return user, nil
+}
+
+// storeIdenticon gives a new user a generated photo. The user is already
+// created, so a failure only leaves it without photo.
+func (s *Service) storeIdenticon(email string) {
+
+	user, err := s.repository.GetUserByEmail(email)
+	if err != nil {
+		log.Printf("identicon for %s: %v", email, err)
+		return
+	}
+
+	photo, err := images.Identicon(string(user.UserId))
+	if err != nil {
+		log.Printf("identicon for %s: %v", email, err)
+		return
+	}
+
+	if _, err := s.savePhoto(user, photo); err != nil {
+		log.Printf("identicon for %s: %v", email, err)
+	}
+}
+
+// savePhoto stores the photo as the photo of the user and deletes the
+// previous one
+func (s *Service) savePhoto(user *models.User, photo *images.Photo) (string, error) {
+
+	key, err := images.Store(s.storage, "users/"+string(user.UserId), photo)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.repository.UpdatePhotoKey(key, user.Email); err != nil {
+		images.Delete(s.storage, key)
+		return "", err
+	}
+
+	images.Delete(s.storage, user.PhotoKey)
+	return key, nil
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/PabloPei/SmartSpend-backend/internal/errors"
+	"github.com/shopspring/decimal"
+)
+
+// MaxAmount is the first amount that does not fit the DECIMAL(10, 2) columns
+var MaxAmount = decimal.New(1, 8)
+
+// ValidateAmount checks that the amount is positive, has at most 2 decimals
+// and fits the amount columns
+func ValidateAmount(amount decimal.Decimal) error {
+	if !amount.IsPositive() {
+		return errors.ErrInvalidaPayload("amount must be greater than zero")
+	}
+	if !amount.Equal(amount.Round(2)) {
+		return errors.ErrInvalidaPayload("amount can not have more than 2 decimals")
+	}
+	if amount.GreaterThanOrEqual(MaxAmount) {
+		return errors.ErrInvalidaPayload("amount is too large")
+	}
+
+	return nil
+}
+
+// ParseDate reads a YYYY-MM-DD date of the payload field, today in UTC when
+// it is empty
+func ParseDate(field string, date string) (time.Time, error) {
+	if date == "" {
+		return time.Now().UTC().Truncate(24 * time.Hour), nil
+	}
+
+	parsed, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return time.Time{}, errors.ErrInvalidaPayload(field + " must have the format YYYY-MM-DD")
+	}
+
+	return parsed, nil
+}
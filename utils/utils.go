@@ -3,6 +3,8 @@ package utils
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strings"
 
@@ -29,6 +31,44 @@ func ParseJSON(r *http.Request, v any) error {
 	return json.NewDecoder(r.Body).Decode(v)
 }
 
+// IsMultipart tells a file upload from a JSON body
+func IsMultipart(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "multipart/form-data"
+}
+
+// ParseFile reads the file sent in the field of a multipart form, up to
+// maxSize bytes, and returns it with its file name
+func ParseFile(w http.ResponseWriter, r *http.Request, field string, maxSize int64) ([]byte, string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+	if err := r.ParseMultipartForm(maxSize); err != nil {
+		return nil, "", fmt.Errorf("invalid multipart form: %v", err)
+	}
+
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		return nil, "", fmt.Errorf("missing file %q", field)
+	}
+	defer file.Close()
+
+	if header.Size > maxSize {
+		return nil, "", fmt.Errorf("the file is larger than %d bytes", maxSize)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, "", fmt.Errorf("the file is larger than %d bytes", maxSize)
+	}
+	if len(data) == 0 {
+		return nil, "", fmt.Errorf("the file is empty")
+	}
+
+	return data, header.Filename, nil
+}
+
 func GetTokenFromRequest(r *http.Request) string {
 	tokenAuth := r.Header.Get("Authorization")
 